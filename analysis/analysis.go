@@ -0,0 +1,264 @@
+// Package analysis checks a PEG grammar, as parsed by peg.Peg, for
+// mistakes that would otherwise only show up when compiling or running
+// the generated parser.
+package analysis
+
+import (
+	"fmt"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"sort"
+	"strings"
+)
+
+// Severity tells whether a Diagnostic prevents a usable parser
+// from being generated.
+type Severity int
+
+const (
+	// The grammar can be generated, but probably doesn't do what
+	// was intended.
+	Warning Severity = iota
+	// The generated parser wouldn't compile or wouldn't terminate.
+	Fatal
+)
+
+func (s Severity) String() string {
+	if s == Fatal {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a problem found in the grammar.
+type Diagnostic struct {
+	Severity     Severity
+	Line, Column int
+	// The definition the problem was found in
+	Rule        string
+	Description string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d,%d: %s: %s", d.Line, d.Column, d.Severity, d.Description)
+}
+
+// HasFatal returns whether any of "diags" is Fatal.
+func HasFatal(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+type analyzer struct {
+	p        *peg.Peg
+	defs     map[string]*Node
+	nullable map[string]bool
+	diags    []Diagnostic
+}
+
+func (a *analyzer) report(s Severity, node *Node, rule, format string, args ...interface{}) {
+	if node.P != a.p {
+		// The node was imported from another grammar
+		return
+	}
+	line, column := a.p.ParserData.LineCol(node.Range.A)
+	a.diags = append(a.diags, Diagnostic{s, line, column, rule, fmt.Sprintf(format, args...)})
+}
+
+// Analyze checks the grammar parsed by "p" for
+//
+//   - undefined, unused and duplicate definitions
+//   - left recursion
+//   - repetition of expressions that can match without consuming any input
+//   - alternatives shadowed by a preceding literal alternative
+//   - Unicode properties that don't exist
+//   - cuts with nothing following them
+//   - operators that can match without consuming any input
+//
+// The diagnostics are returned in the order they appear in the grammar.
+func Analyze(p *peg.Peg) []Diagnostic {
+	return AnalyzeResolved(p, p.RootNode())
+}
+
+// AnalyzeResolved checks the grammar parsed by "p" like Analyze, along with
+// the grammars it imports, as combined into "root" by ResolveImports. Only
+// the problems in the grammar parsed by "p" are reported.
+func AnalyzeResolved(p *peg.Peg, root *Node) []Diagnostic {
+	a := analyzer{
+		p:    p,
+		defs: make(map[string]*Node),
+	}
+	// The parameterized definitions are checked where they're called
+	root, err := ExpandTemplates(root)
+	if err != nil {
+		te := err.(*TemplateError)
+		a.report(Fatal, te.Node, te.Rule, "%s", te.Description)
+		return a.diags
+	}
+	a.nullable = NullableRules(root)
+	var first string
+	for _, node := range root.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		name := node.Children[0].Data()
+		if _, ok := a.defs[name]; ok {
+			a.report(Fatal, node, name, "%s is already defined", name)
+			continue
+		}
+		if first == "" {
+			first = name
+		}
+		a.defs[name] = node
+	}
+
+	heads, _ := LeftRecursion(root)
+	isHead := make(map[string]bool)
+	for _, name := range heads {
+		isHead[name] = true
+	}
+
+	calls := make(map[string]map[string]bool)
+	for _, node := range root.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		name := node.Children[0].Data()
+		if isHead[name] && a.defs[name] == node {
+			a.report(Warning, node, name, "%s is left recursive", name)
+		}
+		if calls[name] == nil {
+			calls[name] = make(map[string]bool)
+		}
+		a.check(name, DefinitionExpression(node), calls[name])
+		if recovery := DefinitionRecovery(node); recovery != nil {
+			a.check(name, recovery, calls[name])
+		}
+		for _, level := range DefinitionPrecedence(node) {
+			op := level.Children[1]
+			if Nullable(op, a.nullable) {
+				a.report(Fatal, op, name, "%s has an operator that can match without consuming any input", name)
+			}
+			a.check(name, op, calls[name])
+		}
+	}
+
+	// Parsing starts from the first definition, anything
+	// it can't reach is unused
+	used := map[string]bool{first: true}
+	for stack := []string{first}; len(stack) > 0; {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for call := range calls[name] {
+			if !used[call] {
+				used[call] = true
+				stack = append(stack, call)
+			}
+		}
+	}
+	for _, node := range root.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		if name := node.Children[0].Data(); !used[name] && a.defs[name] == node {
+			a.report(Warning, node, name, "%s is never used", name)
+		}
+	}
+	sort.SliceStable(a.diags, func(i, j int) bool {
+		if a.diags[i].Line != a.diags[j].Line {
+			return a.diags[i].Line < a.diags[j].Line
+		}
+		return a.diags[i].Column < a.diags[j].Column
+	})
+	return a.diags
+}
+
+// check reports the problems found in the expression "node" of the
+// definition "rule", and adds the definitions it calls to "calls".
+func (a *analyzer) check(rule string, node *Node, calls map[string]bool) {
+	switch node.Name {
+	case "Primary":
+		if front := node.Children[0]; front.Name == "Identifier" {
+			name := front.Data()
+			if _, ok := a.defs[name]; !ok {
+				a.report(Fatal, front, rule, "%s isn't defined", name)
+			} else {
+				calls[name] = true
+			}
+			return
+		}
+	case "Suffix":
+		back := node.Children[len(node.Children)-1]
+		if (back.Name == "STAR" || back.Name == "PLUS") && Nullable(node.Children[0], a.nullable) {
+			a.report(Fatal, node, rule, "%s repeats an expression that can match without consuming any input", rule)
+		}
+	case "Sequence":
+		children := node.Children
+		if SequenceAction(node) != nil {
+			children = children[:len(children)-1]
+		}
+		if back := children[len(children)-1]; back.Name == "CUT" {
+			a.report(Warning, back, rule, "cut has no effect, as nothing follows it")
+		}
+	case "Class":
+		if _, err := NewCharClass(node); err != nil {
+			a.report(Fatal, node, rule, "%s", err)
+		}
+	case "Expression":
+		var literals [][]string
+		for _, alt := range node.Children {
+			lit, alone := leadingLiteral(alt)
+			for _, prev := range literals {
+				if hasPrefix(lit, prev) {
+					a.report(Warning, alt, rule, "alternative is never tried, as \"%s\" matches before it", strings.Join(prev, ""))
+					break
+				}
+			}
+			if alone {
+				literals = append(literals, lit)
+			}
+		}
+	}
+	for _, child := range node.Children {
+		a.check(rule, child, calls)
+	}
+}
+
+// leadingLiteral returns the Chars of the literal the Sequence "node"
+// starts with, if any, and whether that literal is all the Sequence
+// matches.
+func leadingLiteral(node *Node) (lit []string, alone bool) {
+	if node.Name != "Sequence" {
+		return nil, false
+	}
+	n := node.Children[0]
+	for _, name := range []string{"Prefix", "Suffix", "Primary"} {
+		if n.Name != name || len(n.Children) != 1 {
+			return nil, false
+		}
+		n = n.Children[0]
+	}
+	if n.Name != "Literal" || LiteralIgnoresCase(n) {
+		return nil, false
+	}
+	for _, c := range n.Children {
+		lit = append(lit, c.Data())
+	}
+	return lit, len(node.Children) == 1 || len(node.Children) == 2 && SequenceAction(node) != nil
+}
+
+func hasPrefix(lit, prefix []string) bool {
+	if len(lit) == 0 || len(lit) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if lit[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
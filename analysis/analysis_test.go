@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func analyze(t *testing.T, grammar string) []Diagnostic {
+	var p peg.Peg
+	if !p.Parse(grammar) {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	return Analyze(&p)
+}
+
+func TestAnalyze(t *testing.T) {
+	diags := analyze(t, `File    <- Sum? Keyword* (Spacing*)+ Missing
+Sum     <- Sum '+' Value / Value
+Keyword <- "in" / "int" / 'i' / "if" / "i" Value
+Value   <- [0-9]+
+Spacing <- [ \t]*
+Value   <- 'x'
+Dead    <- '(' Dead2? ')'
+Dead2   <- Dead
+`)
+	want := []string{
+		"1,26: error: File repeats an expression that can match without consuming any input",
+		"1,27: error: File repeats an expression that can match without consuming any input",
+		"1,38: error: Missing isn't defined",
+		"2,1: warning: Sum is left recursive",
+		"3,19: warning: alternative is never tried, as \"in\" matches before it",
+		"3,33: warning: alternative is never tried, as \"i\" matches before it",
+		"3,40: warning: alternative is never tried, as \"i\" matches before it",
+		"6,1: error: Value is already defined",
+		"7,1: warning: Dead is never used",
+		"8,1: warning: Dead2 is never used",
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	if a, b := strings.Join(got, "\n"), strings.Join(want, "\n"); a != b {
+		t.Errorf("Expected\n%s\ngot\n%s", b, a)
+	}
+	if !HasFatal(diags) {
+		t.Error("Expected errors to be fatal")
+	}
+}
+
+func TestAnalyzeTemplates(t *testing.T) {
+	diags := analyze(t, `File <- List(Value) !.
+Value <- [0-9]+
+List(x, sep) <- x (sep x)*
+`)
+	if len(diags) != 1 || diags[0].String() != "1,9: error: List takes 2 arguments, but is called with 1" {
+		t.Errorf("Unexpected diagnostics %v", diags)
+	}
+}
+
+func TestAnalyzeUnicode(t *testing.T) {
+	diags := analyze(t, `Keyword <- "in"i / "int" / 'i' Name
+Name    <- [^\p{Lx}0-9]+
+`)
+	if len(diags) != 1 || diags[0].String() != `2,12: error: \p{Lx} isn't a Unicode general category or script` {
+		t.Errorf("Unexpected diagnostics %v", diags)
+	}
+}
+
+func TestAnalyzeCut(t *testing.T) {
+	diags := analyze(t, `Statement <- "while" ~ Value ~ / "print" ~ Value ~ { return nil }
+Value     <- ~ [0-9]+
+`)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	want := "1,30: warning: cut has no effect, as nothing follows it\n1,50: warning: cut has no effect, as nothing follows it"
+	if a := strings.Join(got, "\n"); a != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, a)
+	}
+}
+
+func TestAnalyzePrecedence(t *testing.T) {
+	diags := analyze(t, `Expr  <- Value
+         %left '+' / '-'?
+         %prefix Minus
+Value <- [0-9]+
+`)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	want := "2,16: error: Expr has an operator that can match without consuming any input\n3,18: error: Minus isn't defined"
+	if a := strings.Join(got, "\n"); a != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, a)
+	}
+}
+
+func TestAnalyzePeg(t *testing.T) {
+	data, err := ioutil.ReadFile("../peg/peg.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range analyze(t, string(data)) {
+		t.Errorf("Unexpected diagnostic: %s", d)
+	}
+}
+
+func TestAnalyzeResolved(t *testing.T) {
+	var p, lib peg.Peg
+	if !p.Parse("%import \"lib.peg\"\nFile <- Value Missing\n") || !lib.Parse("Value <- [0-9]+ Spacing\nUnused <- 'x'\n") {
+		t.Fatal("Didn't parse correctly")
+	}
+	root, err := ResolveImports(p.RootNode(), "file.peg", func(string) (*Node, error) {
+		return lib.RootNode(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the problems of the importing grammar are reported
+	var got []string
+	for _, d := range AnalyzeResolved(&p, root) {
+		got = append(got, d.String())
+	}
+	if a, b := strings.Join(got, "\n"), "2,15: error: Missing isn't defined"; a != b {
+		t.Errorf("Expected\n%s\ngot\n%s", b, a)
+	}
+}
@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The maximum number of Nodes of an astField that's a slice
+const astMany = 2
+
+type (
+	// astField is a field of the type generated for a Definition,
+	// holding the child Nodes created by a rule or labeled expression.
+	astField struct {
+		// The rule or label
+		name  string
+		label bool
+		// The rule of the only Nodes a labeled expression creates
+		rule string
+		// Whether the field holds the Node created by the operator
+		// a precedence block applied last
+		op bool
+		// The minimum and maximum number of Nodes, the maximum
+		// being "astMany" for slices
+		min, max int
+	}
+
+	astFields []*astField
+
+	// ast infers the fields of the types generated for the
+	// Definitions of a grammar.
+	ast struct {
+		exps map[string]*Node
+		// The definitions not creating Nodes
+		nodeless map[string]bool
+		// The fields of the nodeless definitions, with the
+		// ones being inferred mapped to nil to catch recursion
+		inlined map[string]astFields
+		// The nodeless definitions found to be recursive
+		recursive map[string]bool
+	}
+)
+
+func (fs astFields) find(f *astField) *astField {
+	for _, f2 := range fs {
+		if f2.name == f.name && f2.label == f.label && f2.op == f.op {
+			return f2
+		}
+	}
+	return nil
+}
+
+func (fs astFields) copy() (ret astFields) {
+	for _, f := range fs {
+		f2 := *f
+		ret = append(ret, &f2)
+	}
+	return
+}
+
+// scale multiplies the number of Nodes of the fields by
+// the number of times "min" and "max" their expression matches.
+func (fs astFields) scale(min, max int) astFields {
+	for _, f := range fs {
+		f.min *= min
+		if f.min > 1 {
+			f.min = 1
+		}
+		if f.max *= max; f.max > astMany {
+			f.max = astMany
+		}
+	}
+	return fs
+}
+
+// then returns the fields of "fs" followed by "b".
+func (fs astFields) then(b astFields) astFields {
+	ret := fs.copy()
+	for _, f := range b {
+		if f2 := ret.find(f); f2 != nil {
+			f2.min = 1
+			f2.max = astMany
+		} else {
+			f2 := *f
+			ret = append(ret, &f2)
+		}
+	}
+	return ret
+}
+
+// or returns the fields of either "fs" or "b".
+func (fs astFields) or(b astFields) astFields {
+	ret := fs.copy()
+	for _, f := range ret {
+		if b.find(f) == nil {
+			f.min = 0
+		}
+	}
+	for _, f := range b {
+		if f2 := ret.find(f); f2 != nil {
+			if f.min < f2.min {
+				f2.min = f.min
+			}
+			if f.max > f2.max {
+				f2.max = f.max
+			}
+		} else {
+			f2 := *f
+			f2.min = 0
+			ret = append(ret, &f2)
+		}
+	}
+	return ret
+}
+
+// fields returns the fields of the expression "node".
+func (a *ast) fields(node *Node) astFields {
+	switch node.Name {
+	case "Expression":
+		var ret astFields
+		for i, child := range node.Children {
+			if i == 0 {
+				ret = a.fields(child)
+			} else {
+				ret = ret.or(a.fields(child))
+			}
+		}
+		return ret
+	case "Sequence":
+		var ret astFields
+		for _, child := range node.Children {
+			ret = ret.then(a.fields(child))
+		}
+		return ret
+	case "Prefix":
+		if PrefixPredicate(node) != nil {
+			// The Nodes are discarded
+			return nil
+		}
+		ret := a.fields(node.Children[len(node.Children)-1])
+		if label := PrefixLabel(node); label != "" {
+			f := &astField{name: label, label: true, min: 1, max: 1}
+			if len(ret) == 1 && !ret[0].label && ret[0].max == 1 {
+				f.rule = ret[0].name
+			}
+			ret = astFields{f}
+		}
+		return ret
+	case "Suffix":
+		ret := a.fields(node.Children[0])
+		if len(node.Children) > 1 {
+			switch node.Children[len(node.Children)-1].Name {
+			case "QUESTION":
+				ret = ret.scale(0, 1)
+			case "STAR":
+				ret = ret.scale(0, astMany)
+			case "PLUS":
+				ret = ret.scale(1, astMany)
+			}
+		}
+		return ret
+	case "Primary":
+		front := node.Children[0]
+		if front.Name != "Identifier" {
+			return a.fields(front)
+		}
+		name := front.Data()
+		if !a.nodeless[name] {
+			return astFields{{name: name, min: 1, max: 1}}
+		}
+		return a.inline(name)
+	}
+	return nil
+}
+
+// inline returns the fields of the nodeless definition "name", whose
+// Nodes end up as children of the Node of the definition calling it.
+func (a *ast) inline(name string) astFields {
+	if fs, ok := a.inlined[name]; ok {
+		if fs == nil {
+			a.recursive[name] = true
+		}
+		return fs.copy()
+	}
+	exp := a.exps[name]
+	if exp == nil {
+		return nil
+	}
+	a.inlined[name] = nil
+	fs := a.fields(exp)
+	if a.recursive[name] {
+		fs = fs.scale(0, astMany)
+	} else if fs == nil {
+		fs = astFields{}
+	}
+	a.inlined[name] = fs
+	return fs.copy()
+}
+
+// exported returns "name" with its first letter in upper case.
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// astType returns the name of the type generated for the definition "name".
+func astType(name string) string {
+	return name + "Node"
+}
+
+// astConverter returns the name of the function converting a Node created by
+// the definition "name" to its type, exported if the definition's name is.
+func astConverter(name string) string {
+	if exported(name) == name {
+		return "New" + astType(name)
+	}
+	return "new" + exported(astType(name))
+}
+
+// GenerateAST returns Go code declaring a type for each Definition in the
+// Definition nodes "defs" that creates a Node, with a field for the Nodes
+// each rule and labeled expression in it creates, along with functions
+// converting Nodes to those types.
+//
+// The definitions in "nodeless" don't create Nodes, so the Nodes created by
+// the rules they refer to are given fields of the definitions referring to
+// them instead.
+func GenerateAST(defs []*Node, nodeless map[string]bool) string {
+	a := ast{
+		exps:      make(map[string]*Node),
+		nodeless:  nodeless,
+		inlined:   make(map[string]astFields),
+		recursive: make(map[string]bool),
+	}
+	precedence := make(map[string]bool)
+	for _, def := range defs {
+		a.exps[def.Children[0].Data()] = DefinitionExpression(def)
+		precedence[def.Children[0].Data()] = DefinitionPrecedence(def) != nil
+	}
+	var cf CodeFormatter
+	for _, def := range defs {
+		name := def.Children[0].Data()
+		if nodeless[name] {
+			continue
+		}
+		fs := a.fields(a.exps[name])
+		if precedence[name] {
+			// The Nodes of the operands are children of
+			// the Node of the operator applied, if any
+			fs = fs.or(astFields{{name: "Operation", op: true, min: 1, max: 1}})
+		}
+		// The labels come first as they're matched first
+		sort.SliceStable(fs, func(i, j int) bool { return fs[i].label && !fs[j].label })
+
+		var (
+			names  = map[string]bool{"Node": true}
+			fields = make([]string, len(fs))
+		)
+		cf.Add("// " + astType(name) + " is the typed form of a Node created by " + name + ".\n")
+		cf.Add("type " + astType(name) + " struct {\n")
+		cf.Inc()
+		cf.Add("Node *Node\n")
+		for i, f := range fs {
+			field := f.name
+			if f.label {
+				field = exported(field)
+			}
+			for names[field] || token.Lookup(field).IsKeyword() {
+				field += "_"
+			}
+			names[field] = true
+			fields[i] = field
+
+			typ := "*Node"
+			if !f.label && !f.op {
+				typ = "*" + astType(f.name)
+			} else if f.rule != "" {
+				typ = "*" + astType(f.rule)
+			}
+			if f.max == astMany {
+				typ = "[]" + typ
+			}
+			cf.Add(field + " " + typ)
+			if f.min == 0 && f.max == 1 {
+				cf.Add(" // optional")
+			}
+			cf.Add("\n")
+		}
+		cf.Dec()
+		cf.Add("}\n\n")
+
+		conv := astConverter(name)
+		cf.Add("// " + conv + " converts \"n\", a Node created by " + name + ", to its typed form.\n")
+		cf.Add("func " + conv + "(n *Node) *" + astType(name) + " {\n")
+		cf.Inc()
+		cf.Add("if n == nil {\n\treturn nil\n}\nret := &" + astType(name) + "{Node: n}\n")
+		if len(fs) > 0 {
+			cf.Add("for _, child := range n.Children {\n")
+			cf.Inc()
+			cf.Add("switch {\n")
+			for i, f := range fs {
+				var set string
+				if f.max == astMany {
+					set = "ret." + fields[i] + " = append(ret." + fields[i] + ", %s)\n"
+				} else {
+					set = "ret." + fields[i] + " = %s\n"
+				}
+				if f.op {
+					cf.Add("case child.Name == \"BinaryOp\" || child.Name == \"PrefixOp\" || child.Name == \"PostfixOp\":\n")
+					cf.Add("\t" + fmt.Sprintf(set, "child"))
+				} else if !f.label {
+					cf.Add("case child.Name == " + strconv.Quote(f.name) + ":\n")
+					cf.Add("\t" + fmt.Sprintf(set, astConverter(f.name)+"(child)"))
+				} else if f.rule != "" {
+					// A Node named after the label is
+					// created when the rule didn't match
+					cf.Add("case child.Label == " + strconv.Quote(f.name) + ":\n")
+					cf.Add("\tif child.Name == " + strconv.Quote(f.rule) + " {\n\t\t" + fmt.Sprintf(set, astConverter(f.rule)+"(child)") + "\t}\n")
+				} else {
+					cf.Add("case child.Label == " + strconv.Quote(f.name) + ":\n")
+					cf.Add("\t" + fmt.Sprintf(set, "child"))
+				}
+			}
+			cf.Add("}\n")
+			cf.Dec()
+			cf.Add("}\n")
+		}
+		cf.Add("return ret\n")
+		cf.Dec()
+		cf.Add("}\n\n")
+	}
+	return cf.String()
+}
@@ -34,6 +34,15 @@ type (
 		Error() Error
 	}
 
+	// RecoveringParser is implemented by Parsers able to
+	// recover from errors and continue parsing.
+	RecoveringParser interface {
+		Parser
+		// Returns the errors recovered from during the
+		// last parse, in the order they were encountered.
+		Errors() []Error
+	}
+
 	Error interface {
 		Line() int
 		Column() int
@@ -46,6 +55,12 @@ type (
 		column      int
 		description string
 	}
+
+	// CutFailure is what parsers panic with when the expression
+	// following a cut fails, which stops them from backtracking.
+	// Their Parse methods recover from it and return false.
+	CutFailure struct{}
+
 	Reader interface {
 		Len() int
 		Pos() int
@@ -55,6 +70,29 @@ type (
 		Substring(start, end int) string
 		Seek(offset int)
 	}
+
+	// TrackingReader is implemented by Readers keeping track
+	// of how far they have been read, which is what determines
+	// the input a memoized outcome depends on.
+	TrackingReader interface {
+		Reader
+		// Returns the offset following the furthest
+		// rune read since the last call to Track.
+		Examined() int
+		// Restarts tracking from "offset".
+		Track(offset int)
+	}
+
+	// PositionReader is implemented by Readers able to convert
+	// between offsets and lines and columns, with the columns
+	// counted in a chosen unit. Lines and columns start at 1.
+	PositionReader interface {
+		Reader
+		// Returns the line and column of "offset".
+		Position(offset int, unit ColumnUnit) (line, column int)
+		// Returns the offset of "column" on "line".
+		Offset(line, column int, unit ColumnUnit) int
+	}
 )
 
 func NewError(line, column int, description string) Error {
@@ -66,3 +104,25 @@ func (be *BasicError) Error() string {
 func (be *BasicError) Line() int           { return be.line }
 func (be *BasicError) Column() int         { return be.column }
 func (be *BasicError) Description() string { return be.description }
+
+// RecoveredErrors returns the errors recovered from that the Error
+// Nodes in the tree of "root" hold as their Value, in the order the
+// Nodes appear in it.
+func RecoveredErrors(root *Node) (ret []Error) {
+	for _, child := range root.Children {
+		if err, ok := child.Value.(Error); ok && child.Name == "Error" {
+			ret = append(ret, err)
+		}
+		ret = append(ret, RecoveredErrors(child)...)
+	}
+	return
+}
+
+// IsCutFailure reports whether "r", as returned by recover, is a
+// CutFailure, and panics with it again unless it's nil.
+func IsCutFailure(r interface{}) bool {
+	if _, ok := r.(CutFailure); ok || r == nil {
+		return ok
+	}
+	panic(r)
+}
@@ -0,0 +1,929 @@
+//go:generate "pegparser" "-peg=calculator.peg" "-notest" "-ignore=Spacing" "-imports=strconv" "-ast" "-gogenerate"
+
+package calculator
+
+import (
+	"github.com/jxo/lime/text"
+	. "github.com/jxo/parser"
+	"io"
+	"strconv"
+)
+
+type CALCULATOR struct {
+	ParserData  Reader
+	IgnoreRange text.Region
+	Root        Node
+	LastError   int
+	Expected    Expected
+	Backtrack   Backtrack
+}
+
+func (p *CALCULATOR) RootNode() *Node {
+	return &p.Root
+}
+
+func (p *CALCULATOR) SetData(data string) {
+	p.SetReader(NewReader(data))
+}
+
+// SetReader prepares the parser for parsing the data of "r".
+func (p *CALCULATOR) SetReader(r Reader) {
+	p.ParserData = r
+	p.Root = Node{Name: "CALCULATOR", P: p}
+	p.IgnoreRange = text.Region{}
+	p.LastError = 0
+	p.Expected = Expected{}
+	p.Backtrack.Reset()
+}
+
+func (p *CALCULATOR) Parse(data string) bool {
+	p.SetData(data)
+	ret := p.realParse()
+	p.Root.UpdateRange()
+	return ret
+}
+
+// ParseReader parses the data read from "r", keeping no more of it
+// in memory than what the parser might still backtrack over and the
+// data of the Nodes parsed.
+func (p *CALCULATOR) ParseReader(r io.Reader) bool {
+	s := NewStreamReader(r)
+	s.Live = &p.Root
+	s.Backtrack = &p.Backtrack
+	p.SetReader(s)
+	ret := p.realParse()
+	p.Root.UpdateRange()
+	return ret
+}
+
+func (p *CALCULATOR) Data(start, end int) string {
+	return p.ParserData.Substring(start, end)
+}
+
+func (p *CALCULATOR) Error() Error {
+	return p.Expected.Error(p.ParserData, p.LastError)
+}
+
+func (p *CALCULATOR) Errors() []Error {
+	return RecoveredErrors(&p.Root)
+}
+
+func (p *CALCULATOR) realParse() bool {
+	return p.Calculator()
+}
+
+// actionCalculator1 is an action block of Calculator, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionCalculator1(c []*Node, data string) interface{} {
+	return c[0].Value
+}
+
+func (p *CALCULATOR) Calculator() bool {
+	// Calculator  <- Spacing Sum EndOfFile { return c[0].Value }
+	accept := false
+	accept = true
+	var value interface{}
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			saveValue := value
+			accept = p.Spacing()
+			if accept {
+				accept = p.Sum()
+				if accept {
+					accept = p.EndOfFile()
+					if accept {
+					}
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+				value = saveValue
+			}
+		}
+		if accept {
+			value = p.actionCalculator1(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+		}
+		p.Backtrack.Release(saved)
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Calculator")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Calculator"
+		node.P = p
+		node.Value = value
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+// actionSum1 is an action block of Sum, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionSum1(c []*Node, data string) interface{} {
+	v := c[0].Value.(float64)
+	for i := 1; i+1 < len(c); i += 2 {
+		if c[i].Data() == "+" {
+			v += c[i+1].Value.(float64)
+		} else {
+			v -= c[i+1].Value.(float64)
+		}
+	}
+	return v
+}
+
+func (p *CALCULATOR) Sum() bool {
+	// Sum         <- first:Product (AddOp Spacing rest:Product)* {
+	//                    v := c[0].Value.(float64)
+	//                    for i := 1; i+1 < len(c); i += 2 {
+	//                        if c[i].Data() == "+" {
+	//                            v += c[i+1].Value.(float64)
+	//                        } else {
+	//                            v -= c[i+1].Value.(float64)
+	//                        }
+	//                    }
+	//                    return v
+	//                }
+	accept := false
+	accept = true
+	var value interface{}
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			saveValue := value
+			{
+				save := p.ParserData.Pos()
+				accept = p.Product()
+				if accept {
+					p.Root.AddLabel(save, p.ParserData.Pos(), "first")
+				}
+			}
+			if accept {
+				{
+					accept = true
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
+						{
+							save := p.ParserData.Pos()
+							saveValue := value
+							accept = p.AddOp()
+							if accept {
+								accept = p.Spacing()
+								if accept {
+									{
+										save := p.ParserData.Pos()
+										accept = p.Product()
+										if accept {
+											p.Root.AddLabel(save, p.ParserData.Pos(), "rest")
+										}
+									}
+									if accept {
+									}
+								}
+							}
+							if !accept {
+								if p.LastError < p.ParserData.Pos() {
+									p.LastError = p.ParserData.Pos()
+								}
+								p.ParserData.Seek(save)
+								value = saveValue
+							}
+						}
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
+						}
+					}
+					accept = true
+				}
+				if accept {
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+				value = saveValue
+			}
+		}
+		if accept {
+			value = p.actionSum1(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+		}
+		p.Backtrack.Release(saved)
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Sum")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Sum"
+		node.P = p
+		node.Value = value
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+// actionProduct1 is an action block of Product, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionProduct1(c []*Node, data string) interface{} {
+	v := c[0].Value.(float64)
+	for i := 1; i+1 < len(c); i += 2 {
+		if c[i].Data() == "*" {
+			v *= c[i+1].Value.(float64)
+		} else {
+			v /= c[i+1].Value.(float64)
+		}
+	}
+	return v
+}
+
+func (p *CALCULATOR) Product() bool {
+	// Product     <- Value (MulOp Spacing Value)* {
+	//                    v := c[0].Value.(float64)
+	//                    for i := 1; i+1 < len(c); i += 2 {
+	//                        if c[i].Data() == "*" {
+	//                            v *= c[i+1].Value.(float64)
+	//                        } else {
+	//                            v /= c[i+1].Value.(float64)
+	//                        }
+	//                    }
+	//                    return v
+	//                }
+	accept := false
+	accept = true
+	var value interface{}
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			saveValue := value
+			accept = p.Value()
+			if accept {
+				{
+					accept = true
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
+						{
+							save := p.ParserData.Pos()
+							saveValue := value
+							accept = p.MulOp()
+							if accept {
+								accept = p.Spacing()
+								if accept {
+									accept = p.Value()
+									if accept {
+									}
+								}
+							}
+							if !accept {
+								if p.LastError < p.ParserData.Pos() {
+									p.LastError = p.ParserData.Pos()
+								}
+								p.ParserData.Seek(save)
+								value = saveValue
+							}
+						}
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
+						}
+					}
+					accept = true
+				}
+				if accept {
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+				value = saveValue
+			}
+		}
+		if accept {
+			value = p.actionProduct1(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+		}
+		p.Backtrack.Release(saved)
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Product")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Product"
+		node.P = p
+		node.Value = value
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+// actionValue1 is an action block of Value, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionValue1(c []*Node, data string) interface{} {
+	return c[0].Value
+}
+
+// actionValue2 is an action block of Value, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionValue2(c []*Node, data string) interface{} {
+	return -c[0].Value.(float64)
+}
+
+// actionValue3 is an action block of Value, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionValue3(c []*Node, data string) interface{} {
+	return c[0].Value
+}
+
+func (p *CALCULATOR) Value() bool {
+	// Value       <- Number Spacing { return c[0].Value }
+	//              / '-' Spacing Value { return -c[0].Value.(float64) }
+	//              / '(' Spacing Sum ')' Spacing { return c[0].Value }
+	accept := false
+	accept = true
+	var value interface{}
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			saved := p.Backtrack.Save(save)
+			{
+				save := p.ParserData.Pos()
+				saveValue := value
+				accept = p.Number()
+				if accept {
+					accept = p.Spacing()
+					if accept {
+					}
+				}
+				if !accept {
+					if p.LastError < p.ParserData.Pos() {
+						p.LastError = p.ParserData.Pos()
+					}
+					p.ParserData.Seek(save)
+					value = saveValue
+				}
+			}
+			if accept {
+				value = p.actionValue1(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+			}
+			p.Backtrack.Release(saved)
+		}
+		if !accept {
+			{
+				save := p.ParserData.Pos()
+				saved := p.Backtrack.Save(save)
+				{
+					save := p.ParserData.Pos()
+					saveValue := value
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != '-' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"-\"")
+						}
+					}
+					if accept {
+						accept = p.Spacing()
+						if accept {
+							accept = p.Value()
+							if accept {
+							}
+						}
+					}
+					if !accept {
+						if p.LastError < p.ParserData.Pos() {
+							p.LastError = p.ParserData.Pos()
+						}
+						p.ParserData.Seek(save)
+						value = saveValue
+					}
+				}
+				if accept {
+					value = p.actionValue2(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+				}
+				p.Backtrack.Release(saved)
+			}
+			if !accept {
+				{
+					save := p.ParserData.Pos()
+					saved := p.Backtrack.Save(save)
+					{
+						save := p.ParserData.Pos()
+						saveValue := value
+						{
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '(' {
+								p.ParserData.UnRead()
+								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"(\"")
+							}
+						}
+						if accept {
+							accept = p.Spacing()
+							if accept {
+								accept = p.Sum()
+								if accept {
+									{
+										pos := p.ParserData.Pos()
+										if p.ParserData.Read() != ')' {
+											p.ParserData.UnRead()
+											accept = false
+										} else {
+											accept = true
+										}
+										if !accept {
+											p.Expected.Add(pos, "\")\"")
+										}
+									}
+									if accept {
+										accept = p.Spacing()
+										if accept {
+										}
+									}
+								}
+							}
+						}
+						if !accept {
+							if p.LastError < p.ParserData.Pos() {
+								p.LastError = p.ParserData.Pos()
+							}
+							p.ParserData.Seek(save)
+							value = saveValue
+						}
+					}
+					if accept {
+						value = p.actionValue3(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+					}
+					p.Backtrack.Release(saved)
+				}
+				if !accept {
+				}
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Value")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Value"
+		node.P = p
+		node.Value = value
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+// actionNumber1 is an action block of Number, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *CALCULATOR) actionNumber1(c []*Node, data string) interface{} {
+	v, _ := strconv.ParseFloat(data, 64)
+	return v
+}
+
+func (p *CALCULATOR) Number() bool {
+	// Number      <- [0-9]+ ('.' [0-9]+)? {
+	//                    v, _ := strconv.ParseFloat(data, 64)
+	//                    return v
+	//                }
+	accept := false
+	accept = true
+	var value interface{}
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			saveValue := value
+			{
+				save := p.ParserData.Pos()
+				{
+					pos := p.ParserData.Pos()
+					c := p.ParserData.Read()
+					if c >= '0' && c <= '9' {
+						accept = true
+					} else {
+						p.ParserData.UnRead()
+						accept = false
+					}
+					if !accept {
+						p.Expected.Add(pos, "[0-9]")
+					}
+				}
+				if !accept {
+					p.ParserData.Seek(save)
+				} else {
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
+						{
+							pos := p.ParserData.Pos()
+							c := p.ParserData.Read()
+							if c >= '0' && c <= '9' {
+								accept = true
+							} else {
+								p.ParserData.UnRead()
+								accept = false
+							}
+							if !accept {
+								p.Expected.Add(pos, "[0-9]")
+							}
+						}
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
+						}
+					}
+					accept = true
+				}
+			}
+			if accept {
+				{
+					saved := p.Backtrack.Save(p.ParserData.Pos())
+					{
+						save := p.ParserData.Pos()
+						saveValue := value
+						{
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '.' {
+								p.ParserData.UnRead()
+								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\".\"")
+							}
+						}
+						if accept {
+							{
+								save := p.ParserData.Pos()
+								{
+									pos := p.ParserData.Pos()
+									c := p.ParserData.Read()
+									if c >= '0' && c <= '9' {
+										accept = true
+									} else {
+										p.ParserData.UnRead()
+										accept = false
+									}
+									if !accept {
+										p.Expected.Add(pos, "[0-9]")
+									}
+								}
+								if !accept {
+									p.ParserData.Seek(save)
+								} else {
+									for accept {
+										pos := p.ParserData.Pos()
+										saved := p.Backtrack.Save(pos)
+										{
+											pos := p.ParserData.Pos()
+											c := p.ParserData.Read()
+											if c >= '0' && c <= '9' {
+												accept = true
+											} else {
+												p.ParserData.UnRead()
+												accept = false
+											}
+											if !accept {
+												p.Expected.Add(pos, "[0-9]")
+											}
+										}
+										p.Backtrack.Release(saved)
+										if pos == p.ParserData.Pos() {
+											break
+										}
+									}
+									accept = true
+								}
+							}
+							if accept {
+							}
+						}
+						if !accept {
+							if p.LastError < p.ParserData.Pos() {
+								p.LastError = p.ParserData.Pos()
+							}
+							p.ParserData.Seek(save)
+							value = saveValue
+						}
+					}
+					p.Backtrack.Release(saved)
+					accept = true
+				}
+				if accept {
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+				value = saveValue
+			}
+		}
+		if accept {
+			value = p.actionNumber1(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))
+		}
+		p.Backtrack.Release(saved)
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Number")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Number"
+		node.P = p
+		node.Value = value
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *CALCULATOR) AddOp() bool {
+	// AddOp       <- '+' / '-'
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '+' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"+\"")
+			}
+		}
+		if !accept {
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '-' {
+					p.ParserData.UnRead()
+					accept = false
+				} else {
+					accept = true
+				}
+				if !accept {
+					p.Expected.Add(pos, "\"-\"")
+				}
+			}
+			if !accept {
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "AddOp")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "AddOp"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *CALCULATOR) MulOp() bool {
+	// MulOp       <- '*' / '/'
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '*' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"*\"")
+			}
+		}
+		if !accept {
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '/' {
+					p.ParserData.UnRead()
+					accept = false
+				} else {
+					accept = true
+				}
+				if !accept {
+					p.Expected.Add(pos, "\"/\"")
+				}
+			}
+			if !accept {
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "MulOp")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "MulOp"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *CALCULATOR) Spacing() bool {
+	// Spacing     <- [ \t\r\n]*
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	p.Expected.Mute()
+	{
+		accept = true
+		for accept {
+			pos := p.ParserData.Pos()
+			saved := p.Backtrack.Save(pos)
+			{
+				pos := p.ParserData.Pos()
+				{
+					accept = false
+					c := p.ParserData.Read()
+					if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+						accept = true
+					} else {
+						p.ParserData.UnRead()
+					}
+				}
+				if !accept {
+					p.Expected.Add(pos, "[ \\t\\r\\n]")
+				}
+			}
+			p.Backtrack.Release(saved)
+			if pos == p.ParserData.Pos() {
+				break
+			}
+		}
+		accept = true
+	}
+	p.Expected.Unmute()
+	if accept && start != p.ParserData.Pos() {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
+			p.IgnoreRange.A = start
+		}
+		p.IgnoreRange.B = p.ParserData.Pos()
+	}
+	return accept
+}
+
+func (p *CALCULATOR) EndOfFile() bool {
+	// EndOfFile   <- !.
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		s := p.ParserData.Pos()
+		saved := p.Backtrack.Save(s)
+		p.Expected.Mute()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Pos() >= p.ParserData.Len() {
+				accept = false
+			} else {
+				p.ParserData.Read()
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "any character")
+			}
+		}
+		p.Expected.Unmute()
+		p.Backtrack.Release(saved)
+		p.ParserData.Seek(s)
+		p.Root.Discard(s)
+		accept = !accept
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "EndOfFile")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "EndOfFile"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
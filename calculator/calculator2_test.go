@@ -0,0 +1,57 @@
+package calculator
+
+import (
+	"testing"
+)
+
+func TestParser2(t *testing.T) {
+	tests := []struct {
+		in  string
+		out float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"8 - 2 - 1", 5},
+		{"10 / 4 - 0.5", 2},
+		{" 2 * (3 + -4) * 5 ", -10},
+	}
+	var p CALCULATOR
+
+	for _, test := range tests {
+		if !p.Parse(test.in) {
+			t.Errorf("Didn't parse %q correctly: %s\n", test.in, p.Error())
+		} else if v := p.RootNode().Children[0].Value; v != test.out {
+			t.Errorf("Expected %q to evaluate to %v, got %v", test.in, test.out, v)
+		}
+	}
+}
+
+func TestAST(t *testing.T) {
+	var p CALCULATOR
+	if !p.Parse("1 + (2 - 3) * -4") {
+		t.Fatalf("Didn't parse correctly: %s\n", p.Error())
+	}
+	calc := NewCalculatorNode(p.RootNode().Children[0])
+	if calc.Sum == nil || calc.EndOfFile == nil {
+		t.Fatalf("Expected a Sum and an EndOfFile, got %+v", calc)
+	}
+	sum := calc.Sum
+	if len(sum.Rest) != 1 || len(sum.AddOp) != 1 || sum.AddOp[0].Node.Data() != "+" {
+		t.Fatalf("Expected a single addition, got %+v", sum)
+	}
+	if v := sum.First.Value[0].Number; v == nil || v.Node.Data() != "1" {
+		t.Errorf("Expected the first operand to be the Number 1, got %+v", v)
+	}
+	product := sum.Rest[0]
+	if len(product.Value) != 2 || len(product.MulOp) != 1 {
+		t.Fatalf("Expected a single multiplication, got %+v", product)
+	}
+	if group := product.Value[0]; group.Sum == nil || group.Number != nil || group.Value != nil {
+		t.Errorf("Expected a parenthesized Sum, got %+v", group)
+	} else if len(group.Sum.Rest) != 1 || group.Sum.AddOp[0].Node.Data() != "-" {
+		t.Errorf("Expected a single subtraction, got %+v", group.Sum)
+	}
+	if neg := product.Value[1]; neg.Value == nil || neg.Value.Number.Node.Data() != "4" {
+		t.Errorf("Expected the negated Number 4, got %+v", neg)
+	}
+}
@@ -0,0 +1,167 @@
+package calculator
+
+import (
+	. "github.com/jxo/parser"
+)
+
+// CalculatorNode is the typed form of a Node created by Calculator.
+type CalculatorNode struct {
+	Node      *Node
+	Sum       *SumNode
+	EndOfFile *EndOfFileNode
+}
+
+// NewCalculatorNode converts "n", a Node created by Calculator, to its typed form.
+func NewCalculatorNode(n *Node) *CalculatorNode {
+	if n == nil {
+		return nil
+	}
+	ret := &CalculatorNode{Node: n}
+	for _, child := range n.Children {
+		switch {
+		case child.Name == "Sum":
+			ret.Sum = NewSumNode(child)
+		case child.Name == "EndOfFile":
+			ret.EndOfFile = NewEndOfFileNode(child)
+		}
+	}
+	return ret
+}
+
+// SumNode is the typed form of a Node created by Sum.
+type SumNode struct {
+	Node  *Node
+	First *ProductNode
+	Rest  []*ProductNode
+	AddOp []*AddOpNode
+}
+
+// NewSumNode converts "n", a Node created by Sum, to its typed form.
+func NewSumNode(n *Node) *SumNode {
+	if n == nil {
+		return nil
+	}
+	ret := &SumNode{Node: n}
+	for _, child := range n.Children {
+		switch {
+		case child.Label == "first":
+			if child.Name == "Product" {
+				ret.First = NewProductNode(child)
+			}
+		case child.Label == "rest":
+			if child.Name == "Product" {
+				ret.Rest = append(ret.Rest, NewProductNode(child))
+			}
+		case child.Name == "AddOp":
+			ret.AddOp = append(ret.AddOp, NewAddOpNode(child))
+		}
+	}
+	return ret
+}
+
+// ProductNode is the typed form of a Node created by Product.
+type ProductNode struct {
+	Node  *Node
+	Value []*ValueNode
+	MulOp []*MulOpNode
+}
+
+// NewProductNode converts "n", a Node created by Product, to its typed form.
+func NewProductNode(n *Node) *ProductNode {
+	if n == nil {
+		return nil
+	}
+	ret := &ProductNode{Node: n}
+	for _, child := range n.Children {
+		switch {
+		case child.Name == "Value":
+			ret.Value = append(ret.Value, NewValueNode(child))
+		case child.Name == "MulOp":
+			ret.MulOp = append(ret.MulOp, NewMulOpNode(child))
+		}
+	}
+	return ret
+}
+
+// ValueNode is the typed form of a Node created by Value.
+type ValueNode struct {
+	Node   *Node
+	Number *NumberNode // optional
+	Value  *ValueNode  // optional
+	Sum    *SumNode    // optional
+}
+
+// NewValueNode converts "n", a Node created by Value, to its typed form.
+func NewValueNode(n *Node) *ValueNode {
+	if n == nil {
+		return nil
+	}
+	ret := &ValueNode{Node: n}
+	for _, child := range n.Children {
+		switch {
+		case child.Name == "Number":
+			ret.Number = NewNumberNode(child)
+		case child.Name == "Value":
+			ret.Value = NewValueNode(child)
+		case child.Name == "Sum":
+			ret.Sum = NewSumNode(child)
+		}
+	}
+	return ret
+}
+
+// NumberNode is the typed form of a Node created by Number.
+type NumberNode struct {
+	Node *Node
+}
+
+// NewNumberNode converts "n", a Node created by Number, to its typed form.
+func NewNumberNode(n *Node) *NumberNode {
+	if n == nil {
+		return nil
+	}
+	ret := &NumberNode{Node: n}
+	return ret
+}
+
+// AddOpNode is the typed form of a Node created by AddOp.
+type AddOpNode struct {
+	Node *Node
+}
+
+// NewAddOpNode converts "n", a Node created by AddOp, to its typed form.
+func NewAddOpNode(n *Node) *AddOpNode {
+	if n == nil {
+		return nil
+	}
+	ret := &AddOpNode{Node: n}
+	return ret
+}
+
+// MulOpNode is the typed form of a Node created by MulOp.
+type MulOpNode struct {
+	Node *Node
+}
+
+// NewMulOpNode converts "n", a Node created by MulOp, to its typed form.
+func NewMulOpNode(n *Node) *MulOpNode {
+	if n == nil {
+		return nil
+	}
+	ret := &MulOpNode{Node: n}
+	return ret
+}
+
+// EndOfFileNode is the typed form of a Node created by EndOfFile.
+type EndOfFileNode struct {
+	Node *Node
+}
+
+// NewEndOfFileNode converts "n", a Node created by EndOfFile, to its typed form.
+func NewEndOfFileNode(n *Node) *EndOfFileNode {
+	if n == nil {
+		return nil
+	}
+	ret := &EndOfFileNode{Node: n}
+	return ret
+}
@@ -29,6 +29,7 @@ import (
 	"container/list"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 type CGenerator struct {
@@ -41,12 +42,20 @@ type CGenerator struct {
 	Imports         []string
 	havefunctions   bool
 	currentName     string
+	// The property tables added to the output, along with the
+	// runtime code for characters once it's been added
+	tables map[string]bool
+	cut    bool
 }
 
 func (g *CGenerator) SetCustomActions(actions []CustomAction) {
 	g.CustomActions = actions
 }
 
+func (g *CGenerator) SetCut() {
+	g.cut = true
+}
+
 func (g *CGenerator) AddNode(data, defName string) string {
 	return `accept = TRUE;
 const char* __restrict__ start = p->parserData.pos;
@@ -70,7 +79,7 @@ func (g *CGenerator) Ignore(data string) string {
 const char* __restrict__ start = p->parserData.pos;
 ` + g.Call(data) + `
 if (accept && start != p->parserData.pos) {
-	if (start < p->ignoreRange.start || p->ignoreRange.start == 0) {
+	if (start < p->ignoreRange.start || start > p->ignoreRange.end || p->ignoreRange.start == 0) {
 		p->ignoreRange.start = start;
 	}
 	p->ignoreRange.end = p->parserData.pos;
@@ -79,16 +88,23 @@ if (accept && start != p->parserData.pos) {
 }
 func (g *CGenerator) MakeParserFunction(node *Node) error {
 	id := node.Children[0]
-	exp := node.Children[len(node.Children)-1]
+	exp := DefinitionExpression(node)
 	defName := helper(g, id)
 	g.currentName = defName
 	data := helper(g, exp)
+	if levels := DefinitionPrecedence(node); levels != nil {
+		data = g.Precedence(data, precedenceLevels(g, levels))
+	}
 
 	g.realOutput += "static int p_" + defName + "(" + g.s.Name + "*);\n"
 
 	if !g.havefunctions {
 		g.havefunctions = true
-		g.output += "static int " + g.s.Name + "_parse2(" + g.s.Name + "* __restrict__ p) {\n\treturn p_" + defName + "(p);\n}\n"
+		g.output += "static int " + g.s.Name + "_parse2(" + g.s.Name + "* __restrict__ p) {\n"
+		if g.cut {
+			g.output += "\tif (setjmp(p->cut)) {\n\t\treturn FALSE;\n\t}\n"
+		}
+		g.output += "\treturn p_" + defName + "(p);\n}\n"
 	}
 
 	indenter := CodeFormatter{}
@@ -153,90 +169,57 @@ func (g *CGenerator) MakeParserCall(value string) string {
 }
 
 func (g *CGenerator) CheckInRange(a, b string) string {
-	return `if (p->parserData.pos >= p->parserData.end) {
-	accept = FALSE;
-} else {
-	const char c = *p->parserData.pos;
-	if (c >= '` + a + `' && c <= '` + b + `') {
-		p->parserData.pos++;
-		accept = TRUE;
-	} else {
-		accept = FALSE;
-	}
-}`
+	lo, _ := Unescape(a)
+	hi, _ := Unescape(b)
+	return g.CheckClass(&CharClass{Ranges: []RuneRange{{lo[0], hi[0]}}})
 }
 
 func (g *CGenerator) CheckInSet(a string) string {
-	a = strings.Replace(a, "\\[", "[", -1)
-	a = strings.Replace(a, "\\]", "]", -1)
-	tests := ""
-	for i := 0; i < len(a); i++ {
-		if len(tests) > 0 {
-			tests += " || "
-		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-
-		tests += "c == '" + c2 + "'"
+	runes, _ := Unescape(a)
+	class := &CharClass{}
+	for _, r := range runes {
+		class.Ranges = append(class.Ranges, RuneRange{r, r})
 	}
-	return `{
-	accept = FALSE;
-	if (p->parserData.pos < p->parserData.end) {
-		const char c =  *p->parserData.pos;
-		if (` + tests + `) {
-			p->parserData.pos++;
-			accept = TRUE;
-		}
-	}
-}`
+	return g.CheckClass(class)
 }
 
 func (g *CGenerator) CheckAnyChar() string {
-	return `if (p->parserData.pos >= p->parserData.end) {
-	accept = FALSE;
-} else {
-	p->parserData.pos++;
-	accept = TRUE;
+	g.table(nil)
+	return `{
+	int c;
+	int n = Rune_decode(p->parserData.pos, p->parserData.end, &c);
+	accept = n > 0;
+	p->parserData.pos += n;
 }`
 }
 
 func (g *CGenerator) CheckNext(a string) string {
-	/*
-	 */
-
-	if a[0] == '\'' {
-		return `if (p->parserData.pos >= p->parserData.end || *p->parserData.pos != ` + a + `) {
+	runes, _ := Unescape(a[1 : len(a)-1])
+	for _, r := range runes {
+		if r >= utf8.RuneSelf {
+			// The characters are decoded from the input and compared
+			// as code points, as for a literal ignoring case
+			sets := make([][]rune, len(runes))
+			for i, r := range runes {
+				sets[i] = []rune{r}
+			}
+			return g.checkRunes(sets)
+		}
+	}
+	if len(runes) == 1 {
+		return `if (p->parserData.pos >= p->parserData.end || *p->parserData.pos != ` + cRune(runes[0]) + `) {
 	accept = FALSE;
 } else {
 	p->parserData.pos++;
 	accept = TRUE;
 }`
 	}
-	a = a[1 : len(a)-1]
 	tests := ""
-	pos := 0
-	for i := 0; i < len(a); i, pos = i+1, pos+1 {
+	for i, r := range runes {
 		if len(tests) > 0 {
 			tests += " || "
 		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "\\\"" {
-			c2 = "\""
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-		tests += fmt.Sprintf("*(p->parserData.pos + %d) != '%s'", pos, c2)
+		tests += fmt.Sprintf("*(p->parserData.pos + %d) != %s", i, cRune(r))
 	}
 	return fmt.Sprintf(`{
 	accept = TRUE;
@@ -252,7 +235,282 @@ func (g *CGenerator) CheckNext(a string) string {
 	if (accept) {
 		p->parserData.pos += %d;
 	}
-}`, pos, tests, pos)
+}`, len(runes), tests, len(runes))
+}
+
+// cUnicode is the code decoding the UTF-8 characters of the input, added
+// to the output of parsers with negated or Unicode classes or literals
+// ignoring case.
+const cUnicode = `
+/* Decodes the character at "s" into "c", returning its length, or
+   1 with "c" set to 0xFFFD if it isn't valid UTF-8. At "end", 0 is
+   returned with "c" set to -1. */
+static int Rune_decode(const char* __restrict__ s, const char* __restrict__ end, int* c)
+{
+    static const int min[] = {0, 0, 0x80, 0x800, 0x10000};
+    const unsigned char* u = (const unsigned char*) s;
+    int n, i;
+    if (s >= end) {
+        *c = -1;
+        return 0;
+    }
+    if (u[0] < 0x80) {
+        *c = u[0];
+        return 1;
+    } else if ((u[0] & 0xE0) == 0xC0) {
+        n = 2;
+        *c = u[0] & 0x1F;
+    } else if ((u[0] & 0xF0) == 0xE0) {
+        n = 3;
+        *c = u[0] & 0x0F;
+    } else if ((u[0] & 0xF8) == 0xF0) {
+        n = 4;
+        *c = u[0] & 0x07;
+    } else {
+        *c = 0xFFFD;
+        return 1;
+    }
+    if (end - s < n) {
+        *c = 0xFFFD;
+        return 1;
+    }
+    for (i = 1; i < n; i++) {
+        if ((u[i] & 0xC0) != 0x80) {
+            *c = 0xFFFD;
+            return 1;
+        }
+        *c = (*c << 6) | (u[i] & 0x3F);
+    }
+    if (*c < min[n] || *c > 0x10FFFF || (*c >= 0xD800 && *c <= 0xDFFF)) {
+        *c = 0xFFFD;
+        return 1;
+    }
+    return n;
+}
+
+/* Returns whether "c" is in the "n" bounds of the sorted ranges of "table". */
+static int Rune_inTable(const int* table, int n, int c)
+{
+    int lo = 0, hi = n/2;
+    while (lo < hi) {
+        int m = (lo+hi)/2;
+        if (c < table[2*m]) {
+            hi = m;
+        } else if (c > table[2*m+1]) {
+            lo = m+1;
+        } else {
+            return TRUE;
+        }
+    }
+    return FALSE;
+}
+`
+
+// table adds the runtime code for characters to the output, along with
+// the table of the Unicode properties of "class" if it has any, returning
+// the name of the table.
+func (g *CGenerator) table(class *CharClass) string {
+	if g.tables == nil {
+		g.tables = make(map[string]bool)
+		g.realOutput += cUnicode
+	}
+	if class == nil || len(class.Properties) == 0 {
+		return ""
+	}
+	name := propertyTableName(class)
+	if !g.tables[name] {
+		g.tables[name] = true
+		g.realOutput += "static const int " + name + "[] = {" + formatTable(class.PropertyTable(), "    ") + "\n};\n"
+	}
+	return name
+}
+
+func (g *CGenerator) CheckClass(class *CharClass) string {
+	var tests []string
+	for _, r := range class.Ranges {
+		if r.Lo == r.Hi {
+			tests = append(tests, "c == "+cRune(r.Lo))
+		} else {
+			tests = append(tests, "(c >= "+cRune(r.Lo)+" && c <= "+cRune(r.Hi)+")")
+		}
+	}
+	if table := g.table(class); table != "" {
+		tests = append(tests, "Rune_inTable("+table+", sizeof("+table+")/sizeof(int), c)")
+	}
+	test := "(" + strings.Join(tests, " || ") + ")"
+	if class.Negated {
+		test = "!" + test
+	}
+	return `{
+	int c;
+	int n = Rune_decode(p->parserData.pos, p->parserData.end, &c);
+	accept = n > 0 && ` + test + `;
+	if (accept) {
+		p->parserData.pos += n;
+	}
+}`
+}
+
+func (g *CGenerator) CheckNextFold(a []rune) string {
+	sets := make([][]rune, len(a))
+	for i, r := range a {
+		sets[i] = foldOrbit(r)
+	}
+	return g.checkRunes(sets)
+}
+
+// checkRunes returns the code accepting the characters following if each
+// of them is one of the characters in its set of "sets".
+func (g *CGenerator) checkRunes(sets [][]rune) string {
+	g.table(nil)
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add("const char* __restrict__ s = p->parserData.pos;\nint c;\nint n = 0;\naccept = TRUE;\n")
+	for _, set := range sets {
+		var tests []string
+		for _, r := range set {
+			tests = append(tests, "c == "+cRune(r))
+		}
+		cf.Add("if (accept) {\n\ts += n;\n\tn = Rune_decode(s, p->parserData.end, &c);\n\taccept = " + strings.Join(tests, " || ") + ";\n}\n")
+	}
+	cf.Add("if (accept) {\n\tp->parserData.pos = s + n;\n}")
+	cf.Dec()
+	cf.Add("\n}")
+	return cf.String()
+}
+
+func (g *CGenerator) Cut(data string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add(g.Call(data) + "\nif (!accept) {\n\tlongjmp(p->cut, 1);\n}\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+func (g *CGenerator) Precedence(operand string, levels []PrecedenceLevel) string {
+	return g.precedence(operand, levels, "int", func(start, name string) string {
+		return `Node* node = Node_cleanup(&p->_root, ` + start + `, p->parserData.pos);
+node->name = "` + name + `";
+Range_clip(&node->range, &p->ignoreRange);
+Node_pushback(&p->_root, node);`
+	}, func(pos string) string {
+		return "Node_discard(&p->_root, " + pos + ");"
+	})
+}
+
+// precedence adds the function applying the operators of a precedence
+// block to the output, returning the code calling it. The function
+// returns a "boolean" and creates Nodes with the code "wrap" returns,
+// and discards them with the code "discard" returns, so that it can
+// be used for both C and C++.
+func (g *CGenerator) precedence(operand string, levels []PrecedenceLevel, boolean string, wrap func(start, name string) string, discard func(pos string) string) string {
+	name := "precedence_" + g.currentName
+	g.realOutput += "static " + boolean + " " + name + "(" + g.s.Name + "*, int);\n"
+	var cf CodeFormatter
+	cf.Add("// " + name + ` applies the operators of ` + g.currentName + ` binding at
+// least as tightly as the level "min" to its operands.
+static ` + boolean + " " + name + "(" + g.s.Name + `* __restrict__ p, int min) {
+`)
+	cf.Inc()
+	cf.Add("int accept = FALSE;\nconst char* __restrict__ start = p->parserData.pos;\n")
+	for _, l := range levels {
+		if l.Associativity == "NONE" {
+			// The operators of a non-associative level can't follow
+			// the ones it has just applied
+			cf.Add("int last = 0;\n")
+			break
+		}
+	}
+	// The operator is matched within a block creating a Node for it
+	operator := func(code string) {
+		cf.Add("{\n")
+		cf.Inc()
+		cf.Add("const char* __restrict__ pos = p->parserData.pos;\n" + g.Call(code) + "\nif (accept) {\n\t" + strings.Replace(wrap("pos", "Operator"), "\n", "\n\t", -1) + "\n} else {\n\t" + discard("pos") + "\n}\n")
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	prefix, binary := false, false
+	for i, l := range levels {
+		if l.Associativity != "PREFIX" {
+			binary = binary || l.Associativity != "POSTFIX"
+			continue
+		}
+		prefix = true
+		// A prefix operator applies wherever an operand is expected,
+		// binding its operand at least as tightly as its own level
+		cf.Add("if (!accept) {\n")
+		cf.Inc()
+		operator(l.Operator)
+		cf.Add(`if (accept) {
+	int level = ` + fmt.Sprint(i+1) + `;
+	if (level < min) {
+		level = min;
+	}
+	if ((accept = ` + name + `(p, level))) {
+		` + strings.Replace(wrap("start", "PrefixOp"), "\n", "\n\t\t", -1) + `
+	} else {
+		p->parserData.pos = start;
+		` + discard("start") + `
+	}
+}
+`)
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	if prefix {
+		cf.Add("if (!accept) {\n")
+		cf.Inc()
+	}
+	cf.Add(g.Call(operand) + "\nif (!accept) {\n\treturn FALSE;\n}\n")
+	if prefix {
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	cf.Add("for (;;) {\n")
+	cf.Inc()
+	if binary {
+		cf.Add("const char* __restrict__ save = p->parserData.pos;\n")
+	}
+	for i, l := range levels {
+		level := fmt.Sprint(i + 1)
+		switch l.Associativity {
+		case "PREFIX":
+			continue
+		case "NONE":
+			cf.Add("if (min <= " + level + " && last != " + level + ") {\n")
+		default:
+			cf.Add("if (min <= " + level + ") {\n")
+		}
+		cf.Inc()
+		operator(l.Operator)
+		if l.Associativity == "POSTFIX" {
+			cf.Add("if (accept) {\n\t" + strings.Replace(wrap("start", "PostfixOp"), "\n", "\n\t", -1) + "\n\tcontinue;\n}\n")
+		} else {
+			// The right operand of a right associative operator may
+			// apply it again, while for the others it binds tighter
+			next := fmt.Sprint(i + 2)
+			if l.Associativity == "RIGHT" {
+				next = level
+			}
+			cf.Add("if (accept) {\n\tif ((accept = " + name + "(p, " + next + "))) {\n\t\t" + strings.Replace(wrap("start", "BinaryOp"), "\n", "\n\t\t", -1) + "\n")
+			if l.Associativity == "NONE" {
+				cf.Add("\t\tlast = " + level + ";\n")
+			}
+			cf.Add("\t\tcontinue;\n\t}\n\tp->parserData.pos = save;\n\t" + discard("save") + "\n}\n")
+		}
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	cf.Add("return TRUE;\n")
+	cf.Dec()
+	cf.Add("}\n")
+	cf.Dec()
+	cf.Add("}\n\n")
+	g.output += cf.String()
+	return "accept = " + name + "(p, 1);"
 }
 
 func (g *CGenerator) AssertNot(a string) string {
@@ -421,11 +679,17 @@ func (g *CGenerator) Begin(s GeneratorSettings) error {
 `, "RangeClass ignoreRange;",
 		"Node _root;",
 		"const char* __restrict__ LastError;")
+	include, cut := "", ""
+	if g.cut {
+		// Parsing returns to where it started with longjmp
+		// when an expression following a cut fails
+		include, cut = "\n#include <setjmp.h>", "\n    jmp_buf cut;"
+	}
 	g.realOutput += g.s.Header + `
 #include <stdio.h>
 #include <stdlib.h>
 #include <memory.h>
-#include <assert.h>
+#include <assert.h>` + include + `
 
 typedef struct {
     const char* __restrict__ start;
@@ -540,7 +804,7 @@ typedef struct {
         const char * __restrict__ pos;
     } parserData;
     Node _root;
-    Range ignoreRange;
+    Range ignoreRange;` + cut + `
 } {{ParserName}};
 
 Node* {{ParserName}}_rootNode({{ParserName}}* p) {
@@ -654,6 +918,8 @@ func (g *CGenerator) Finish() error {
 	}
 	g.realOutput = ""
 	g.output = ""
+	g.tables = nil
+	g.cut = false
 	ln := strings.ToLower(g.s.Name)
 
 	if err := g.s.WriteFile(ln+".c", ret); err != nil {
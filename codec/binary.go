@@ -0,0 +1,237 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/jxo/lime/text"
+	. "github.com/jxo/parser"
+	"io"
+)
+
+const (
+	binaryMagic   = "PEGN"
+	binaryVersion = 1
+)
+
+// The flags telling what's set of a Node in the binary format
+const (
+	binaryLabel = 1 << iota
+	binaryLeading
+	binaryTrailing
+	binaryData
+)
+
+type binaryEncoder struct {
+	w       *bufio.Writer
+	strings map[string]int
+	buf     [binary.MaxVarintLen64]byte
+}
+
+func (e *binaryEncoder) uint(i uint64) {
+	e.w.Write(e.buf[:binary.PutUvarint(e.buf[:], i)])
+}
+
+func (e *binaryEncoder) int(i int) {
+	e.w.Write(e.buf[:binary.PutVarint(e.buf[:], int64(i))])
+}
+
+func (e *binaryEncoder) string(s string) {
+	e.uint(uint64(len(s)))
+	e.w.WriteString(s)
+}
+
+// name writes "s" as the index of the same string written
+// before, or as a new one following the number of strings.
+func (e *binaryEncoder) name(s string) {
+	if i, ok := e.strings[s]; ok {
+		e.uint(uint64(i))
+		return
+	}
+	e.uint(uint64(len(e.strings)))
+	e.strings[s] = len(e.strings)
+	e.string(s)
+}
+
+func (e *binaryEncoder) region(r text.Region) {
+	e.uint(uint64(r.A))
+	e.int(r.B - r.A)
+}
+
+func (e *binaryEncoder) node(n *Node, data bool) {
+	var flags byte
+	if n.Label != "" {
+		flags |= binaryLabel
+	}
+	if n.Leading != (text.Region{}) {
+		flags |= binaryLeading
+	}
+	if n.Trailing != (text.Region{}) {
+		flags |= binaryTrailing
+	}
+	if data && len(n.Children) == 0 {
+		flags |= binaryData
+	}
+	e.name(n.Name)
+	e.w.WriteByte(flags)
+	e.region(n.Range)
+	if flags&binaryLabel != 0 {
+		e.name(n.Label)
+	}
+	if flags&binaryLeading != 0 {
+		e.region(n.Leading)
+	}
+	if flags&binaryTrailing != 0 {
+		e.region(n.Trailing)
+	}
+	if flags&binaryData != 0 {
+		e.string(n.Data())
+	}
+	e.uint(uint64(len(n.Children)))
+	for _, child := range n.Children {
+		e.node(child, data)
+	}
+}
+
+// EncodeBinary writes the tree rooted at "n" to "w" in the binary
+// format, including the data of the leaf Nodes if "data" is set.
+func EncodeBinary(w io.Writer, n *Node, data bool) error {
+	e := binaryEncoder{w: bufio.NewWriter(w), strings: make(map[string]int)}
+	e.w.WriteString(binaryMagic)
+	e.uint(binaryVersion)
+	e.node(n, data)
+	return e.w.Flush()
+}
+
+type binaryDecoder struct {
+	r       *bufio.Reader
+	strings []string
+}
+
+func (d *binaryDecoder) uint() (uint64, error) {
+	i, err := binary.ReadUvarint(d.r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return i, err
+}
+
+func (d *binaryDecoder) int() (int, error) {
+	i, err := binary.ReadVarint(d.r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return int(i), err
+}
+
+func (d *binaryDecoder) string() (string, error) {
+	n, err := d.uint()
+	if err != nil {
+		return "", err
+	}
+	// Only what's actually there is allocated for corrupt lengths
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (d *binaryDecoder) name() (string, error) {
+	i, err := d.uint()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case i < uint64(len(d.strings)):
+		return d.strings[i], nil
+	case i == uint64(len(d.strings)):
+		s, err := d.string()
+		d.strings = append(d.strings, s)
+		return s, err
+	}
+	return "", fmt.Errorf("Malformed binary tree: string %d of %d", i, len(d.strings))
+}
+
+func (d *binaryDecoder) region() (text.Region, error) {
+	a, err := d.uint()
+	if err != nil {
+		return text.Region{}, err
+	}
+	size, err := d.int()
+	return text.Region{A: int(a), B: int(a) + size}, err
+}
+
+func (d *binaryDecoder) node() (*Node, error) {
+	var (
+		n   Node
+		err error
+	)
+	if n.Name, err = d.name(); err != nil {
+		return nil, err
+	}
+	flags, err := d.r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if n.Range, err = d.region(); err != nil {
+		return nil, err
+	}
+	if flags&binaryLabel != 0 {
+		if n.Label, err = d.name(); err != nil {
+			return nil, err
+		}
+	}
+	if flags&binaryLeading != 0 {
+		if n.Leading, err = d.region(); err != nil {
+			return nil, err
+		}
+	}
+	if flags&binaryTrailing != 0 {
+		if n.Trailing, err = d.region(); err != nil {
+			return nil, err
+		}
+	}
+	if flags&binaryData != 0 {
+		if _, err = d.string(); err != nil {
+			return nil, err
+		}
+	}
+	count, err := d.uint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < count; i++ {
+		child, err := d.node()
+		if err != nil {
+			return nil, err
+		}
+		n.Append(child)
+	}
+	return &n, nil
+}
+
+// DecodeBinary reads a tree written by EncodeBinary from "r",
+// with "p" as the DataSource of its Nodes.
+func DecodeBinary(r io.Reader, p DataSource) (*Node, error) {
+	d := binaryDecoder{r: bufio.NewReader(r)}
+	var magic [len(binaryMagic)]byte
+	if _, err := io.ReadFull(d.r, magic[:]); err != nil || string(magic[:]) != binaryMagic {
+		return nil, fmt.Errorf("Malformed binary tree: missing %q header", binaryMagic)
+	}
+	if version, err := d.uint(); err != nil {
+		return nil, err
+	} else if version != binaryVersion {
+		return nil, fmt.Errorf("Unsupported binary tree version %d", version)
+	}
+	ret, err := d.node()
+	if err != nil {
+		return nil, err
+	}
+	attach(ret, p)
+	return ret, nil
+}
@@ -0,0 +1,48 @@
+// Package codec encodes trees of Nodes in formats other than the text
+// Node.String returns, so that they can be stored and read back later,
+// or consumed by programs written in other languages:
+//
+// JSON, with each Node being an object of its "name", "range" as an array
+// of its beginning and end, and when set, its "label", "leading" and
+// "trailing" text as arrays like the range, the "data" of leaf Nodes and
+// its "children":
+//
+//	{"name": "Sum", "range": [0, 3], "children": [
+//		{"name": "Value", "range": [0, 1], "data": "1"},
+//		{"name": "Value", "range": [2, 3], "data": "2"}]}
+//
+// S-expressions, with each Node being a list of its name, beginning and
+// end followed by keywords for what's set and its children:
+//
+//	(Sum 0 3 (Value 0 1 :data "1") (Value 2 3 :data "2"))
+//
+// The names are quoted like the strings, with Go escapes, unless they're
+// made of letters, digits and underscores only. The keywords are :label,
+// :leading, :trailing and :data, with the leading and trailing text given
+// as its beginning and end.
+//
+// A compact binary format, starting with the bytes "PEGN" and a version.
+// Each Node is then written as its name, a byte of flags for what's set,
+// its beginning, its size, what's set of its label, leading and trailing
+// text and data, its number of children, and its children. Numbers are
+// variable length integers as written by encoding/binary, with the sizes
+// signed. Strings are a length followed by their bytes, except for names
+// and labels, which are the index of an earlier one, or the number of
+// earlier ones followed by a new string.
+//
+// The Values of the Nodes aren't encoded, and the data is only encoded
+// when asked for, as it's otherwise read from the DataSource the decoded
+// Nodes are given.
+package codec
+
+import (
+	. "github.com/jxo/parser"
+)
+
+// attach sets the P of the tree rooted at "n" to "p".
+func attach(n *Node, p DataSource) {
+	n.P = p
+	for _, child := range n.Children {
+		attach(child, p)
+	}
+}
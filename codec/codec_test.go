@@ -0,0 +1,113 @@
+package codec
+
+import (
+	"bytes"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var formats = []struct {
+	name   string
+	encode func(io.Writer, *Node, bool) error
+	decode func(io.Reader, DataSource) (*Node, error)
+}{
+	{"json", EncodeJSON, DecodeJSON},
+	{"sexp", EncodeSexp, DecodeSexp},
+	{"binary", EncodeBinary, DecodeBinary},
+}
+
+// equal reports whether the trees rooted at "a" and "b" are the same.
+func equal(a, b *Node) bool {
+	if a.Name != b.Name || a.Range != b.Range || a.Label != b.Label || a.Leading != b.Leading || a.Trailing != b.Trailing || a.P != b.P || len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Children {
+		if !equal(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRoundTrip(t *testing.T) {
+	data, err := ioutil.ReadFile("../peg/peg.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p peg.Peg
+	if !p.Parse(string(data)) {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	root := p.RootNode()
+	// Labels and trivia aren't created by peg.Peg
+	root.Children[0].Children[0].Label = "name \"quoted\""
+	root.Children[1].Leading.B = 4
+	root.Children[1].Trailing = root.Children[1].Range
+	root.Children[2].Name = "a (name) with spaces"
+
+	for _, f := range formats {
+		for _, withData := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := f.encode(&buf, root, withData); err != nil {
+				t.Fatalf("%s: %s", f.name, err)
+			}
+			n, err := f.decode(&buf, &p)
+			if err != nil {
+				t.Fatalf("%s: %s", f.name, err)
+			}
+			if !equal(root, n) {
+				t.Errorf("%s: the decoded tree differs\n%s", f.name, n)
+			}
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	var p peg.Peg
+	if !p.Parse("A <- 'a'\n") {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	def := p.RootNode().Children[0]
+	def.Children[0].Label = "name"
+	tests := map[string]string{
+		"json": `{"name":"Definition","range":[0,9],"children":[{"name":"Identifier","range":[0,1],"label":"name","data":"A"},{"name":"Expression","range":[5,9],"children":[{"name":"Sequence","range":[5,9],"children":[{"name":"Prefix","range":[5,9],"children":[{"name":"Suffix","range":[5,9],"children":[{"name":"Primary","range":[5,8],"children":[{"name":"Literal","range":[5,8],"children":[{"name":"Char","range":[6,7],"data":"a"}]}]}]}]}]}]}]}
+`,
+		"sexp": `(Definition 0 9 (Identifier 0 1 :label "name" :data "A") (Expression 5 9 (Sequence 5 9 (Prefix 5 9 (Suffix 5 9 (Primary 5 8 (Literal 5 8 (Char 6 7 :data "a"))))))))
+`,
+	}
+	for _, f := range formats[:2] {
+		var buf bytes.Buffer
+		if err := f.encode(&buf, def, true); err != nil {
+			t.Fatalf("%s: %s", f.name, err)
+		}
+		if s := buf.String(); s != tests[f.name] {
+			t.Errorf("%s: unexpected encoding %s", f.name, s)
+		}
+	}
+	var buf bytes.Buffer
+	if err := EncodeBinary(&buf, def.Children[0], true); err != nil {
+		t.Fatal(err)
+	}
+	if b, want := buf.Bytes(), "PEGN\x01\x00\nIdentifier\x09\x00\x02\x01\x04name\x01A\x00"; string(b) != want {
+		t.Errorf("Unexpected encoding %q", b)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := map[string][]string{
+		"json":   {"", "{", `{"name": 1}`},
+		"sexp":   {"", "(", "A 0 1", "(A 0)", "(A x 1)", "(A 0 1 :label B)", "(A 0 1 :size 2)", `(A 0 1 "B")`, "(A 0 1 (B 0 1)", `("A 0 1)`, "(A-B 0 1)"},
+		"binary": {"", "PEGN", "PEGN\x02\x00", "PEGN\x01\x01\x01A", "PEGN\x01\x00\x01A\x00\x00\x00\x01", "PEGN\x01\x00\x01A\x01\x00\x00\x00"},
+	}
+	for _, f := range formats {
+		for _, test := range tests[f.name] {
+			if _, err := f.decode(strings.NewReader(test), nil); err == nil {
+				t.Errorf("%s: expected an error for %q", f.name, test)
+			}
+		}
+	}
+}
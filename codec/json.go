@@ -0,0 +1,81 @@
+package codec
+
+import (
+	"encoding/json"
+	"github.com/jxo/lime/text"
+	. "github.com/jxo/parser"
+	"io"
+)
+
+// jsonNode is the JSON form of a Node.
+type jsonNode struct {
+	Name     string      `json:"name"`
+	Range    [2]int      `json:"range"`
+	Label    string      `json:"label,omitempty"`
+	Leading  *[2]int     `json:"leading,omitempty"`
+	Trailing *[2]int     `json:"trailing,omitempty"`
+	Data     *string     `json:"data,omitempty"`
+	Children []*jsonNode `json:"children,omitempty"`
+}
+
+// region returns the JSON form of "r", or nil if it's not set.
+func region(r text.Region) *[2]int {
+	if r == (text.Region{}) {
+		return nil
+	}
+	return &[2]int{r.A, r.B}
+}
+
+func toJSON(n *Node, data bool) *jsonNode {
+	ret := &jsonNode{
+		Name:     n.Name,
+		Range:    [2]int{n.Range.A, n.Range.B},
+		Label:    n.Label,
+		Leading:  region(n.Leading),
+		Trailing: region(n.Trailing),
+	}
+	if data && len(n.Children) == 0 {
+		d := n.Data()
+		ret.Data = &d
+	}
+	for _, child := range n.Children {
+		ret.Children = append(ret.Children, toJSON(child, data))
+	}
+	return ret
+}
+
+func (j *jsonNode) node() *Node {
+	ret := &Node{
+		Name:  j.Name,
+		Range: text.Region{A: j.Range[0], B: j.Range[1]},
+		Label: j.Label,
+	}
+	if j.Leading != nil {
+		ret.Leading = text.Region{A: j.Leading[0], B: j.Leading[1]}
+	}
+	if j.Trailing != nil {
+		ret.Trailing = text.Region{A: j.Trailing[0], B: j.Trailing[1]}
+	}
+	for _, child := range j.Children {
+		ret.Children = append(ret.Children, child.node())
+	}
+	return ret
+}
+
+// EncodeJSON writes the tree rooted at "n" to "w" as JSON, including
+// the data of the leaf Nodes if "data" is set.
+func EncodeJSON(w io.Writer, n *Node, data bool) error {
+	return json.NewEncoder(w).Encode(toJSON(n, data))
+}
+
+// DecodeJSON reads a tree written by EncodeJSON from "r",
+// with "p" as the DataSource of its Nodes.
+func DecodeJSON(r io.Reader, p DataSource) (*Node, error) {
+	var j jsonNode
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, err
+	}
+	ret := j.node()
+	attach(ret, p)
+	return ret, nil
+}
@@ -0,0 +1,226 @@
+package codec
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/jxo/lime/text"
+	. "github.com/jxo/parser"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// symbol reports whether "s" can be written without quotes.
+func symbol(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func writeSexp(w *bufio.Writer, n *Node, data bool) {
+	w.WriteByte('(')
+	if symbol(n.Name) {
+		w.WriteString(n.Name)
+	} else {
+		w.WriteString(strconv.Quote(n.Name))
+	}
+	fmt.Fprintf(w, " %d %d", n.Range.A, n.Range.B)
+	if n.Label != "" {
+		w.WriteString(" :label " + strconv.Quote(n.Label))
+	}
+	if n.Leading != (text.Region{}) {
+		fmt.Fprintf(w, " :leading %d %d", n.Leading.A, n.Leading.B)
+	}
+	if n.Trailing != (text.Region{}) {
+		fmt.Fprintf(w, " :trailing %d %d", n.Trailing.A, n.Trailing.B)
+	}
+	if data && len(n.Children) == 0 {
+		w.WriteString(" :data " + strconv.Quote(n.Data()))
+	}
+	for _, child := range n.Children {
+		w.WriteByte(' ')
+		writeSexp(w, child, data)
+	}
+	w.WriteByte(')')
+}
+
+// EncodeSexp writes the tree rooted at "n" to "w" as an S-expression,
+// including the data of the leaf Nodes if "data" is set.
+func EncodeSexp(w io.Writer, n *Node, data bool) error {
+	bw := bufio.NewWriter(w)
+	writeSexp(bw, n, data)
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+// sexpDecoder reads the tokens of an S-expression, with "pos"
+// being the offset of the next one for errors.
+type sexpDecoder struct {
+	r   *bufio.Reader
+	pos int
+}
+
+func (d *sexpDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Malformed S-expression at offset %d: %s", d.pos, fmt.Sprintf(format, args...))
+}
+
+func (d *sexpDecoder) read() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		return 0, d.errorf("unexpected EOF")
+	} else if err == nil {
+		d.pos++
+	}
+	return b, err
+}
+
+// token returns the next token, which is either a parenthesis, an
+// unquoted string or a quoted one, which is returned unquoted.
+func (d *sexpDecoder) token() (tok string, quoted bool, err error) {
+	b, err := d.read()
+	for err == nil && strings.IndexByte(" \t\r\n", b) >= 0 {
+		b, err = d.read()
+	}
+	if err != nil {
+		return "", false, err
+	}
+	switch b {
+	case '(', ')':
+		return string(b), false, nil
+	case '"':
+		buf := []byte{b}
+		for {
+			if b, err = d.read(); err != nil {
+				return "", false, err
+			}
+			buf = append(buf, b)
+			if b == '\\' {
+				if b, err = d.read(); err != nil {
+					return "", false, err
+				}
+				buf = append(buf, b)
+			} else if b == '"' {
+				break
+			}
+		}
+		s, err := strconv.Unquote(string(buf))
+		if err != nil {
+			return "", false, d.errorf("%s", err)
+		}
+		return s, true, nil
+	}
+	buf := []byte{b}
+	for {
+		b, err := d.r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", false, err
+		}
+		if strings.IndexByte(" \t\r\n()\"", b) >= 0 {
+			d.r.UnreadByte()
+			break
+		}
+		d.pos++
+		buf = append(buf, b)
+	}
+	return string(buf), false, nil
+}
+
+func (d *sexpDecoder) int() (int, error) {
+	tok, quoted, err := d.token()
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || quoted {
+		return 0, d.errorf("expected a number, found %q", tok)
+	}
+	return i, nil
+}
+
+func (d *sexpDecoder) region() (text.Region, error) {
+	a, err := d.int()
+	if err != nil {
+		return text.Region{}, err
+	}
+	b, err := d.int()
+	return text.Region{A: a, B: b}, err
+}
+
+// node reads a Node, whose opening parenthesis has been read.
+func (d *sexpDecoder) node() (*Node, error) {
+	name, quoted, err := d.token()
+	if err != nil {
+		return nil, err
+	}
+	if !quoted && !symbol(name) {
+		return nil, d.errorf("expected a name, found %q", name)
+	}
+	n := &Node{Name: name}
+	if n.Range, err = d.region(); err != nil {
+		return nil, err
+	}
+	for {
+		tok, quoted, err := d.token()
+		if err != nil {
+			return nil, err
+		}
+		if quoted {
+			return nil, d.errorf("unexpected string %q", tok)
+		}
+		switch tok {
+		case ")":
+			return n, nil
+		case "(":
+			child, err := d.node()
+			if err != nil {
+				return nil, err
+			}
+			n.Append(child)
+		case ":label", ":data":
+			s, quoted, err := d.token()
+			if err != nil {
+				return nil, err
+			} else if !quoted {
+				return nil, d.errorf("expected a string, found %q", s)
+			}
+			if tok == ":label" {
+				n.Label = s
+			}
+		case ":leading":
+			if n.Leading, err = d.region(); err != nil {
+				return nil, err
+			}
+		case ":trailing":
+			if n.Trailing, err = d.region(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, d.errorf("unexpected %q", tok)
+		}
+	}
+}
+
+// DecodeSexp reads a tree written by EncodeSexp from "r",
+// with "p" as the DataSource of its Nodes.
+func DecodeSexp(r io.Reader, p DataSource) (*Node, error) {
+	d := sexpDecoder{r: bufio.NewReader(r)}
+	if tok, _, err := d.token(); err != nil {
+		return nil, err
+	} else if tok != "(" {
+		return nil, d.errorf("expected '(', found %q", tok)
+	}
+	ret, err := d.node()
+	if err != nil {
+		return nil, err
+	}
+	attach(ret, p)
+	return ret, nil
+}
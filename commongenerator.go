@@ -103,7 +103,7 @@ package parser
 // start := p.ParserData.Pos
 // ` + g.Call(data) + `
 // if accept && start != p.ParserData.Pos {
-// 	if start < p.IgnoreRange.Start || p.IgnoreRange.Start == 0 {
+// 	if start < p.IgnoreRange.Start || start > p.IgnoreRange.End || p.IgnoreRange.Start == 0 {
 // 		p.IgnoreRange.Start = start
 // 	}
 // 	p.IgnoreRange.End = p.ParserData.Pos
@@ -54,16 +54,23 @@ if (p->ignoreRange.start >= end || p->ignoreRange.end <= start) {
 
 func (g *CPPGenerator) MakeParserFunction(node *Node) error {
 	id := node.Children[0]
-	exp := node.Children[len(node.Children)-1]
+	exp := DefinitionExpression(node)
 	defName := helper(g, id)
 	g.currentName = defName
 	data := helper(g, exp)
+	if levels := DefinitionPrecedence(node); levels != nil {
+		data = g.Precedence(data, precedenceLevels(g, levels))
+	}
 
 	g.realOutput += "static bool p_" + defName + "(" + g.s.Name + "*);\n"
 
 	if !g.havefunctions {
 		g.havefunctions = true
-		g.output += "bool " + g.s.Name + "::realParse() {\n\treturn p_" + defName + "(this);\n}\n"
+		if g.cut {
+			g.output += "bool " + g.s.Name + "::realParse() {\n\ttry {\n\t\treturn p_" + defName + "(this);\n\t} catch (CutFailure&) {\n\t\treturn false;\n\t}\n}\n"
+		} else {
+			g.output += "bool " + g.s.Name + "::realParse() {\n\treturn p_" + defName + "(this);\n}\n"
+		}
 	}
 
 	indenter := CodeFormatter{}
@@ -123,6 +130,28 @@ func (g *CPPGenerator) MakeParserFunction(node *Node) error {
 	return nil
 }
 
+func (g *CPPGenerator) Precedence(operand string, levels []PrecedenceLevel) string {
+	return g.precedence(operand, levels, "bool", func(start, name string) string {
+		return `NodeMember node = p->Root.Cleanup(` + start + `, p->parserData.pos);
+node->Name = "` + name + `";
+node->P = p;
+node->Range.Clip(p->ignoreRange);
+p->Root.Append(node);`
+	}, func(pos string) string {
+		return "p->Root.Discard(" + pos + ");"
+	})
+}
+
+func (g *CPPGenerator) Cut(data string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add(g.Call(data) + "\nif (!accept) {\n\tthrow CutFailure();\n}\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
 func (g *CPPGenerator) Begin(s GeneratorSettings) error {
 	g.s = s
 	// imports := ""
@@ -137,11 +166,18 @@ func (g *CPPGenerator) Begin(s GeneratorSettings) error {
 #include <vector>
 #include <algorithm>
 #include <memory>
+#include <cstring>
 
 using namespace std;
 #define TRUE true
 #define FALSE false
 `
+	if g.cut {
+		g.realOutput += `
+// Thrown when an expression following a cut fails
+struct CutFailure {};
+`
+	}
 	members := g.ParserVariables
 	members = append(members, `struct {
 		const char* __restrict__ pos;
@@ -206,7 +242,7 @@ class Node {
 public:
 	NodeMemberContainer Children;
 	const char* __restrict__ Name;
-	Range Range;
+	::Range Range;
 
 	void print(string indent="");
 	~Node() {
@@ -323,8 +359,8 @@ string ` + g.s.Name + `::Data(const char* __restrict__ start, const char* __rest
 	if (this->parserData.end == 0) {
 		return "";
 	}
-	if (start < 0) {
-		start = 0;
+	if (start < this->parserData.data) {
+		start = this->parserData.data;
 	}
 	if (end > this->parserData.end) {
 		end = this->parserData.end;
@@ -366,6 +402,9 @@ int main(int argc, char **argv)
 	{{ParserName}} p;
 	if (p.Parse(data, size)) {
 		`+dumptree_s+`
+	} else {
+		printf("Failed to parse\n");
+		exit(1);
 	}`, "{{ParserName}}", g.s.Name, -1)
 	if g.s.Bench {
 		g.realOutput += `
@@ -398,6 +437,8 @@ func (g *CPPGenerator) Finish() error {
 	}
 	g.realOutput = ""
 	g.output = ""
+	g.tables = nil
+	g.cut = false
 
 	ln := strings.ToLower(g.s.Name)
 	if err := g.s.WriteFile(ln+".cpp", ret); err != nil {
@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Expected tracks what a parser attempted to match at the furthest
+// offset an attempt failed at, so that its errors can tell what
+// was wanted rather than just what was found.
+type Expected struct {
+	// The furthest offset an attempt failed at
+	Pos int
+	// Descriptions of the literals, character classes
+	// and definitions that were attempted at Pos
+	Items []string
+
+	muted int
+}
+
+// Add records that "item" was attempted but didn't match at "pos".
+func (e *Expected) Add(pos int, item string) {
+	if e.muted > 0 || pos < e.Pos {
+		return
+	} else if pos > e.Pos {
+		e.Pos = pos
+		e.Items = e.Items[:0]
+	}
+	for _, i := range e.Items {
+		if i == item {
+			return
+		}
+	}
+	e.Items = append(e.Items, item)
+}
+
+// Mute stops failures from being recorded until the matching call
+// to Unmute. It's used while evaluating predicates, where a failure
+// to match isn't something the input is expected to contain.
+func (e *Expected) Mute() {
+	e.muted++
+}
+
+// Unmute undoes the previous call to Mute.
+func (e *Expected) Unmute() {
+	e.muted--
+}
+
+// Mark is called when a definition starting at "pos" is entered,
+// and returns the value to pass to Collapse when it returns.
+func (e *Expected) Mark(pos int) int {
+	if e.Pos == pos {
+		return len(e.Items)
+	} else if e.Pos < pos {
+		return 0
+	}
+	return -1
+}
+
+// Collapse is called when the definition "name" started at "pos"
+// returns. The attempts it recorded at "pos" are replaced by the name
+// of the definition if it failed and are dropped if it accepted, as
+// an empty match says nothing about what the input should contain.
+func (e *Expected) Collapse(pos, mark int, accept bool, name string) {
+	if mark < 0 || e.muted > 0 || e.Pos > pos {
+		return
+	}
+	if e.Pos == pos && mark < len(e.Items) {
+		e.Items = e.Items[:mark]
+	}
+	if !accept {
+		e.Add(pos, name)
+	}
+}
+
+// Merge records the attempts recorded in "other" as well.
+func (e *Expected) Merge(other *Expected) {
+	for _, item := range other.Items {
+		e.Add(other.Pos, item)
+	}
+}
+
+// Commit is called when a parser passes a cut. The attempts recorded so
+// far are set aside and returned, so that a failure following the cut is
+// reported where it happened rather than where an alternative the parser
+// gave up on got furthest. They're put back with Restore once what
+// follows the cut has matched.
+func (e *Expected) Commit() Expected {
+	saved := Expected{Pos: e.Pos, Items: e.Items}
+	e.Pos, e.Items = 0, nil
+	return saved
+}
+
+// Restore records the attempts set aside by Commit again, ahead
+// of the ones recorded since.
+func (e *Expected) Restore(saved Expected) {
+	since := Expected{Pos: e.Pos, Items: e.Items}
+	e.Pos, e.Items = saved.Pos, saved.Items
+	muted := e.muted
+	e.muted = 0
+	e.Merge(&since)
+	e.muted = muted
+}
+
+// Error returns the error for input "r" that failed to parse. The
+// error is reported at the furthest of "lastError" and the offset
+// attempts were recorded at, unless "r" is a StreamReader that
+// failed to read its input.
+func (e *Expected) Error(r Reader, lastError int) Error {
+	pos := lastError
+	if len(e.Items) > 0 && e.Pos >= pos {
+		pos = e.Pos
+	}
+	line, column := r.LineCol(pos)
+	if s, ok := r.(*StreamReader); ok && s.Err() != nil {
+		// The input itself couldn't be read
+		return NewError(line, column, s.Err().Error())
+	}
+
+	found := "EOF"
+	if pos < r.Len() {
+		r.Seek(pos)
+		found = string(r.Read())
+	}
+	if pos != e.Pos || len(e.Items) == 0 {
+		switch found {
+		case "\r", "\n":
+			found = "new line"
+		}
+		return NewError(line, column, "Unexpected "+found)
+	}
+	if pos < r.Len() {
+		found = strconv.Quote(found)
+	}
+	items := e.Items[len(e.Items)-1]
+	if len(e.Items) > 1 {
+		items = strings.Join(e.Items[:len(e.Items)-1], ", ") + " or " + items
+	}
+	return NewError(line, column, "expected "+items+" but found "+found)
+}
+
+// DescribeLiteral returns the Expected item for the PEG literal "data",
+// quotes included, along with the "i" of a literal ignoring case.
+func DescribeLiteral(data string) string {
+	suffix := ""
+	if strings.HasSuffix(data, "i") {
+		data, suffix = data[:len(data)-1], "i"
+	}
+	runes, err := Unescape(data[1 : len(data)-1])
+	if err != nil {
+		return data + suffix
+	}
+	return strconv.Quote(string(runes)) + suffix
+}
+
+// SpacingRules finds the definitions of the grammar in "rootNode" that
+// look like spacing: nothing depends on them matching, as they can match
+// without consuming any input or are only ever called as optional or
+// repeated, as in "Spacing?", and they call no definitions other than
+// ones calling nothing but such definitions themselves. When they're
+// ignored, what they attempt isn't what the input is expected to contain.
+func SpacingRules(rootNode *Node) map[string]bool {
+	var (
+		names    []string
+		nullable = NullableRules(rootNode)
+		required = make(map[string]bool)
+		calls    = make(map[string]map[string]bool)
+		visit    func(name string, node *Node, optional bool)
+	)
+	visit = func(name string, node *Node, optional bool) {
+		switch node.Name {
+		case "Suffix":
+			back := node.Children[len(node.Children)-1]
+			visit(name, node.Children[0], back.Name == "QUESTION" || back.Name == "STAR")
+			return
+		case "Primary":
+			if front := node.Children[0]; front.Name == "Identifier" {
+				calls[name][front.Data()] = true
+				if !optional {
+					required[front.Data()] = true
+				}
+				return
+			}
+		}
+		for _, child := range node.Children {
+			visit(name, child, false)
+		}
+	}
+	for _, node := range rootNode.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		name := node.Children[0].Data()
+		if names = append(names, name); len(names) == 1 {
+			// Parsing fails when the first definition does
+			required[name] = true
+		}
+		calls[name] = make(map[string]bool)
+		visit(name, node, false)
+	}
+
+	// The definitions only calling definitions that call nothing
+	// else, recursion aside
+	lexical := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			if lexical[name] {
+				continue
+			}
+			lexical[name] = true
+			for call := range calls[name] {
+				if !lexical[call] || call == name {
+					lexical[name] = false
+					break
+				}
+			}
+			changed = changed || lexical[name]
+		}
+	}
+	rules := make(map[string]bool)
+	for _, name := range names {
+		if lexical[name] && (nullable[name] || !required[name]) {
+			rules[name] = true
+		}
+	}
+	return rules
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpected(t *testing.T) {
+	var (
+		e Expected
+		r = NewReader("[1,]")
+	)
+	e.Add(1, `"]"`)
+	e.Add(3, `"]"`)
+	e.Add(3, `"]"`)
+	e.Mute()
+	e.Add(3, "Ignored")
+	e.Unmute()
+	e.Add(2, `","`)
+	if e.Pos != 3 || len(e.Items) != 1 {
+		t.Errorf("Unexpected items %d: %v", e.Pos, e.Items)
+	}
+
+	mark := e.Mark(3)
+	e.Add(3, "[0-9]")
+	e.Add(3, `"-"`)
+	e.Collapse(3, mark, false, "Integer")
+	mark = e.Mark(3)
+	e.Add(3, "[ \\t]")
+	e.Collapse(3, mark, true, "Spacing")
+
+	if err := e.Error(r, 2).Error(); err != `1,4: expected "]" or Integer but found "]"` {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	e.Add(4, "Value")
+	e.Add(4, `"{"`)
+	e.Add(4, `"["`)
+	if err := e.Error(r, 2).Error(); err != `1,5: expected Value, "{" or "[" but found EOF` {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := (&Expected{}).Error(r, 3).Error(); err != "1,4: Unexpected ]" {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestExpectedCommit(t *testing.T) {
+	var (
+		e Expected
+		r = NewReader("[1,]")
+	)
+	e.Add(3, `"]"`)
+	saved := e.Commit()
+	e.Add(2, `","`)
+	if err := e.Error(r, 2).Error(); err != `1,3: expected "," but found ","` {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	// The attempts set aside are restored even while muted
+	e.Add(3, "Value")
+	e.Mute()
+	e.Restore(saved)
+	e.Unmute()
+	if s := fmt.Sprint(e.Items); e.Pos != 3 || s != `["]" Value]` {
+		t.Errorf("Unexpected items %d: %s", e.Pos, s)
+	}
+}
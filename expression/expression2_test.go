@@ -6,14 +6,29 @@ import (
 
 func TestParser2(t *testing.T) {
 	tests := [][]string{{"(MyMask & (Test >> 3)) << 0x2", `0-29: "EXPRESSION"
-	0-29: "ShiftLeft"
-		1-20: "Mask"
+	0-29: "BinaryOp"
+		1-20: "BinaryOp"
 			1-7: "Identifier" - Data: "MyMask"
-			11-20: "ShiftRight"
+			8-9: "Operator" - Data: "&"
+			11-20: "BinaryOp"
 				11-15: "Identifier" - Data: "Test"
+				16-18: "Operator" - Data: ">>"
 				19-20: "Constant" - Data: "3"
+		23-25: "Operator" - Data: "<<"
 		26-29: "Constant" - Data: "0x2"
 	29-29: "EndOfFile" - Data: ""
+`}, {"A & B << 2 << C", `0-15: "EXPRESSION"
+	0-15: "BinaryOp"
+		0-1: "Identifier" - Data: "A"
+		2-3: "Operator" - Data: "&"
+		3-15: "BinaryOp"
+			3-10: "BinaryOp"
+				4-5: "Identifier" - Data: "B"
+				6-8: "Operator" - Data: "<<"
+				9-10: "Constant" - Data: "2"
+			11-13: "Operator" - Data: "<<"
+			14-15: "Identifier" - Data: "C"
+	15-15: "EndOfFile" - Data: ""
 `},
 	}
 	var p EXPRESSION
@@ -0,0 +1,377 @@
+// Package format formats grammars written in the peg.Peg syntax.
+//
+// The definitions are written one after the other with their "<-" aligned,
+// and with the alternatives of their ordered choices continuing on lines
+// beginning with "/" aligned below it. A continuation line is started where
+// the grammar had one, or where the line would grow longer than the width
+// given. Comments are kept where they were, and blank lines are kept between
+// the definitions, while the rest of the spacing is normalised.
+package format
+
+import (
+	"bytes"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// comment is a comment from "pos" to "end", not including
+	// the line break ending it.
+	comment struct {
+		pos, end int
+	}
+
+	// piece is a part of a definition that's written on a line of its own
+	// or after the previous part, beginning at "start" in the source and
+	// ending at "end".
+	piece struct {
+		start, end int
+		text       string
+	}
+
+	formatter struct {
+		src string
+		// Whether each byte of the source is spacing or part of a comment
+		trivia   []bool
+		comments []comment
+		// The index of the next comment to be written
+		next int
+		// The source offset following what was written last
+		end int
+		// The width to wrap lines at, and the width of the
+		// names the definitions are padded to
+		width, names int
+		// The number of columns the "<-" of the definition
+		// being written moves, which its actions move along
+		shift int
+		buf   bytes.Buffer
+	}
+)
+
+// Format formats the grammar "src", wrapping ordered choices longer than
+// "width" columns, or none if it's 0. The returned error is a peg.Peg parse
+// Error if "src" isn't a valid grammar.
+func Format(src string, width int) (string, error) {
+	var p peg.Peg
+	// A grammar that doesn't parse completely still parses as its
+	// Definitions up to the error, without an EndOfFile node
+	if !p.Parse(src) {
+		return "", p.Error()
+	} else if c := p.RootNode().Children; c[len(c)-1].Name != "EndOfFile" {
+		return "", p.Error()
+	}
+	f := formatter{src: src, width: width}
+	f.lex()
+	var nodes []*Node
+	for _, n := range p.RootNode().Children {
+		if n.Name == "Definition" {
+			if l := utf8.RuneCountInString(f.head(n)); l > f.names {
+				f.names = l
+			}
+		}
+		if n.Name == "Definition" || n.Name == "Import" {
+			nodes = append(nodes, n)
+		}
+	}
+	for i, n := range nodes {
+		f.own(n.Range.Begin(), "")
+		limit := len(src)
+		if i+1 < len(nodes) {
+			limit = nodes[i+1].Range.Begin()
+		}
+		if n.Name == "Import" {
+			r := n.Range
+			f.write([]piece{{start: r.Begin(), end: f.codeEnd(r.Begin(), r.End()), text: "%import " + f.token(n.Children[len(n.Children)-1])}}, limit, "")
+		} else {
+			f.definition(n, limit)
+		}
+	}
+	f.own(len(src), "")
+	f.buf.WriteByte('\n')
+	return f.buf.String(), nil
+}
+
+// lex finds the spacing and comments of the source, skipping over the
+// literals, classes and actions of the grammar.
+func (f *formatter) lex() {
+	src := f.src
+	f.trivia = make([]bool, len(src))
+	for i := 0; i < len(src); {
+		switch c := src[i]; c {
+		case ' ', '\t', '\r', '\n':
+			f.trivia[i] = true
+			i++
+		case '#':
+			c := comment{pos: i, end: i}
+			for c.end < len(src) && src[c.end] != '\n' && src[c.end] != '\r' {
+				c.end++
+			}
+			f.comments = append(f.comments, c)
+			for ; i < c.end; i++ {
+				f.trivia[i] = true
+			}
+		case '\'', '"':
+			i = skipQuoted(src, i, c)
+		case '[':
+			i = skipQuoted(src, i, ']')
+		case '{':
+			i = skipCode(src, i)
+		default:
+			i++
+		}
+	}
+}
+
+// skipQuoted returns the offset following the unescaped "end" closing
+// what begins at "i".
+func skipQuoted(src string, i int, end byte) int {
+	for i++; i < len(src) && src[i] != end; i++ {
+		if src[i] == '\\' {
+			i++
+		}
+	}
+	return i + 1
+}
+
+// skipCode returns the offset following the action beginning at "i",
+// skipping over the strings and comments in its code like CodeChunk.
+func skipCode(src string, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch c := src[i]; {
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case c == '"' || c == '\'':
+			i = skipQuoted(src, i, c)
+			continue
+		case c == '`':
+			if j := strings.IndexByte(src[i+1:], '`'); j >= 0 {
+				i += j + 1
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			if j := strings.Index(src[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			}
+		}
+		i++
+	}
+	return i
+}
+
+// codeEnd returns the offset following the last byte from "a" to "b"
+// that isn't spacing or part of a comment, or "a" if there's none.
+func (f *formatter) codeEnd(a, b int) int {
+	for b > a && f.trivia[b-1] {
+		b--
+	}
+	return b
+}
+
+// token returns the source of "n" without the spacing following it.
+func (f *formatter) token(n *Node) string {
+	return f.src[n.Range.Begin():f.codeEnd(n.Range.Begin(), n.Range.End())]
+}
+
+// text returns the formatted peg.Peg expression "n".
+func (f *formatter) text(n *Node) string {
+	var parts []string
+	switch n.Name {
+	case "Expression":
+		for _, child := range n.Children {
+			parts = append(parts, f.text(child))
+		}
+		return strings.Join(parts, " / ")
+	case "Sequence":
+		for _, child := range n.Children {
+			parts = append(parts, f.text(child))
+		}
+		return strings.Join(parts, " ")
+	case "Prefix", "Suffix":
+		for _, child := range n.Children {
+			parts = append(parts, f.text(child))
+		}
+		return strings.Join(parts, "")
+	case "Label":
+		return f.text(n.Children[0]) + ":"
+	case "Call":
+		for _, child := range n.Children[1:] {
+			parts = append(parts, f.text(child))
+		}
+		return f.text(n.Children[0]) + "(" + strings.Join(parts, ", ") + ")"
+	case "Primary":
+		if child := n.Children[0]; child.Name == "Expression" {
+			return "(" + f.text(child) + ")"
+		}
+		return f.text(n.Children[0])
+	case "Action":
+		return f.action(n)
+	}
+	return f.token(n)
+}
+
+// action returns the source of the action "n", with the lines following
+// its first moved along with the "<-" of its definition.
+func (f *formatter) action(n *Node) string {
+	lines := strings.Split(f.token(n), "\n")
+	for i := 1; i < len(lines); i++ {
+		if f.shift > 0 && strings.TrimSpace(lines[i]) != "" {
+			lines[i] = strings.Repeat(" ", f.shift) + lines[i]
+		}
+		for j := 0; j < -f.shift && strings.HasPrefix(lines[i], " "); j++ {
+			lines[i] = lines[i][1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// head returns the formatted name of the peg.Peg Definition node "def",
+// along with its parameters if it has any.
+func (f *formatter) head(def *Node) string {
+	name := f.token(def.Children[0])
+	if params := DefinitionParameters(def); params != nil {
+		name += "(" + strings.Join(params, ", ") + ")"
+	}
+	return name
+}
+
+// column returns the column of the offset "pos" in the source.
+func (f *formatter) column(pos int) int {
+	return utf8.RuneCountInString(f.src[strings.LastIndexByte(f.src[:pos], '\n')+1 : pos])
+}
+
+// newline starts a new line for what begins at "pos" in the source,
+// after a blank line if there's one before it and "blank" is set.
+func (f *formatter) newline(pos int, blank bool) {
+	if f.buf.Len() == 0 {
+		return
+	}
+	f.buf.WriteByte('\n')
+	if blank && strings.Count(f.src[f.end:pos], "\n") > 1 {
+		f.buf.WriteByte('\n')
+	}
+}
+
+// lineWidth returns the width of the last line written.
+func (f *formatter) lineWidth() int {
+	b := f.buf.Bytes()
+	return utf8.RuneCount(b[bytes.LastIndexByte(b, '\n')+1:])
+}
+
+// own writes the comments before "limit" on lines of their own, indented
+// by "indent".
+func (f *formatter) own(limit int, indent string) {
+	for ; f.next < len(f.comments) && f.comments[f.next].pos < limit; f.next++ {
+		c := f.comments[f.next]
+		f.newline(c.pos, indent == "")
+		f.buf.WriteString(indent)
+		f.buf.WriteString(f.src[c.pos:c.end])
+		f.end = c.end
+	}
+}
+
+// definition writes the peg.Peg Definition node "def", along with the
+// comments in it and those before "limit" following it on the same line.
+func (f *formatter) definition(def *Node, limit int) {
+	var (
+		name   = f.head(def)
+		start  = def.Children[0].Range.Begin()
+		arrow  = start
+		indent = strings.Repeat(" ", f.names+2)
+		pieces []piece
+	)
+	for f.trivia[arrow] || !strings.HasPrefix(f.src[arrow:], "<-") {
+		arrow++
+	}
+	f.shift = f.names + 1 - f.column(arrow)
+	for i, seq := range DefinitionExpression(def).Children {
+		p := piece{start: seq.Range.Begin(), end: f.codeEnd(seq.Range.Begin(), seq.Range.End()), text: f.text(seq)}
+		if i == 0 {
+			p.start = start
+			p.text = name + strings.Repeat(" ", f.names-utf8.RuneCountInString(name)) + " <- " + p.text
+		} else {
+			// The piece begins at the SLASH preceding the Sequence
+			for p.start--; f.trivia[p.start]; p.start-- {
+			}
+			p.text = "/ " + p.text
+		}
+		pieces = append(pieces, p)
+	}
+	for _, level := range DefinitionPrecedence(def) {
+		r := level.Range
+		pieces = append(pieces, piece{start: r.Begin(), end: f.codeEnd(r.Begin(), r.End()), text: "%" + strings.ToLower(level.Children[0].Name) + " " + f.text(level.Children[1])})
+	}
+	for _, child := range def.Children {
+		if child.Name == "Recovery" {
+			r := child.Range
+			pieces = append(pieces, piece{start: r.Begin(), end: f.codeEnd(r.Begin(), r.End()), text: "%recover " + f.text(DefinitionRecovery(def))})
+		}
+	}
+	f.write(pieces, limit, indent)
+}
+
+// write writes the pieces of a definition or an import on a new line,
+// continuing onto lines indented by "indent", along with the comments in
+// them and those before "limit" following them on the same line.
+func (f *formatter) write(pieces []piece, limit int, indent string) {
+	wrap := false
+	for i, p := range pieces {
+		first := p.text
+		if nl := strings.IndexByte(first, '\n'); nl >= 0 {
+			first = first[:nl]
+		}
+		if i == 0 {
+			f.newline(p.start, true)
+		} else if wrap || strings.Contains(f.src[pieces[i-1].end:p.start], "\n") ||
+			f.width > 0 && f.lineWidth()+1+utf8.RuneCountInString(first) > f.width {
+			f.buf.WriteByte('\n')
+			f.buf.WriteString(indent)
+		} else {
+			f.buf.WriteByte(' ')
+		}
+		f.buf.WriteString(p.text)
+		f.end = p.end
+
+		// The comments in the piece, or following it on the same line,
+		// trail it, while the others go on lines of their own before the
+		// next piece, or the next definition after the last piece
+		next := limit
+		if i+1 < len(pieces) {
+			next = pieces[i+1].start
+		}
+		trailing := 0
+		for ; f.next < len(f.comments) && f.comments[f.next].pos < next; f.next++ {
+			c := f.comments[f.next]
+			if c.pos >= p.end && strings.Contains(f.src[p.end:c.pos], "\n") {
+				break
+			}
+			if trailing == 0 {
+				f.buf.WriteByte(' ')
+			} else {
+				f.buf.WriteByte('\n')
+				f.buf.WriteString(indent)
+			}
+			f.buf.WriteString(f.src[c.pos:c.end])
+			if c.end > f.end {
+				f.end = c.end
+			}
+			trailing++
+		}
+		wrap = trailing > 0
+		if i+1 < len(pieces) {
+			n := f.next
+			f.own(next, indent)
+			wrap = wrap || f.next > n
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package format
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in, out string
+		width   int
+	}{
+		{
+			"A<-'a'   B\nLonger  <-  ( x / y )* &z !. [a-z]+ label:A?\n",
+			"A      <- 'a' B\nLonger <- (x / y)* &z !. [a-z]+ label:A?\n",
+			80,
+		},
+		{
+			"# Header\n\n\n# More\nA <- b   # trailing\n   / c\n# Before B\n\nB <- d\n# Footer\n",
+			"# Header\n\n# More\nA <- b # trailing\n   / c\n# Before B\n\nB <- d\n# Footer\n",
+			80,
+		},
+		{
+			"Rule <- alpha beta / gamma delta / epsilon zeta / eta\n",
+			"Rule <- alpha beta / gamma delta\n      / epsilon zeta / eta\n",
+			35,
+		},
+		{
+			"A <- b # one\n  # two\n  / c\n    %recover d\n",
+			"A <- b # one\n   # two\n   / c\n   %recover d\n",
+			0,
+		},
+		{
+			"A <- b (c # inside\n  d) / e\n",
+			"A <- b (c d) # inside\n   / e\n",
+			0,
+		},
+		{
+			"Long <- b { return 1 }\nA  <- b {\n         x := '}' // }\n     }\n",
+			"Long <- b { return 1 }\nA    <- b {\n           x := '}' // }\n       }\n",
+			0,
+		},
+		{
+			"# Imports\n%import   \"a.peg\"  # a\n%import \"b.peg\"\n\nA<-b\n",
+			"# Imports\n%import \"a.peg\" # a\n%import \"b.peg\"\n\nA <- b\n",
+			0,
+		},
+		{
+			"A <- List( b ,c d )\nList (x,sep)<- x (sep x)*\n",
+			"A            <- List(b, c d)\nList(x, sep) <- x (sep x)*\n",
+			0,
+		},
+		{
+			"A <- \"#\" '#' [#] { \"#\" }\n",
+			"A <- \"#\" '#' [#] { \"#\" }\n",
+			0,
+		},
+		{
+			"A<-\"select\"i   [^\\p{L}#]  'b'i\n",
+			"A <- \"select\"i [^\\p{L}#] 'b'i\n",
+			0,
+		},
+		{
+			"A<-'('~B  ')' /~  C\n",
+			"A <- '(' ~ B ')' / ~ C\n",
+			0,
+		},
+		{
+			"Expr<-Value /'(' Expr ')'\n  %left  '+'/'-'\n  %right '^' %prefix '-' # minus\n%recover  . \n",
+			"Expr <- Value / '(' Expr ')'\n      %left '+' / '-'\n      %right '^' %prefix '-' # minus\n      %recover .\n",
+			0,
+		},
+	}
+	for i, test := range tests {
+		out, err := Format(test.in, test.width)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		} else if out != test.out {
+			t.Errorf("Test %d: expected\n%s\ngot\n%s", i, test.out, out)
+		} else if again, _ := Format(out, test.width); again != out {
+			t.Errorf("Test %d: formatting again gave\n%s", i, again)
+		}
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	for _, file := range []string{"../peg/peg.peg", "../calculator/calculator.peg", "../json/json.peg", "../test.peg", "../precedence.peg"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Format(string(data), 80)
+		if err != nil {
+			t.Errorf("%s: %s", file, err)
+			continue
+		}
+		if again, err := Format(out, 80); err != nil {
+			t.Errorf("%s: %s", file, err)
+		} else if again != out {
+			t.Errorf("%s: formatting again gave\n%s\ninstead of\n%s", file, again, out)
+		}
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	for _, in := range []string{"A <- ", "A <- b\n<- c"} {
+		if _, err := Format(in, 80); err == nil {
+			t.Errorf("Expected an error formatting %q", in)
+		}
+	}
+}
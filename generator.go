@@ -26,6 +26,8 @@ package parser
 
 import (
 	"container/list"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -61,6 +63,26 @@ type (
 		FileName   string
 		WriteFile  func(name, data string) error
 		Heatmap    bool
+		// Whether to memoize the outcome of each rule per input offset,
+		// so that backtracking never evaluates a rule twice at the same offset.
+		// Nodes left behind by a sequence that failed part way through are
+		// dropped when a memoized rule is replayed, rather than ending up as
+		// children of the next node created at the same offset.
+		Memoize bool
+		// Whether to also generate a Go type for each Definition
+		// creating Nodes, along with a function converting such
+		// Nodes to it. Only supported by the GoGenerator.
+		AST bool
+		// Whether the ignored text, such as spacing and comments,
+		// is kept as the Trivia of the parser and attached to the
+		// Nodes as their Leading and Trailing text by Parse.
+		Trivia bool
+		// The path of the grammar file, which the paths it
+		// imports are relative to.
+		Grammar string
+		// Import returns the peg.Peg Grammar node of the imported
+		// grammar file "file", see ResolveImports.
+		Import func(file string) (*Node, error)
 	}
 
 	Group interface {
@@ -135,6 +157,101 @@ type (
 		Name   string
 		Action func(Generator, string) string
 	}
+
+	// ExpectedGenerator is implemented by Generators whose parsers
+	// report what they expected to find when parsing fails.
+	ExpectedGenerator interface {
+		// Wraps the code "data" matching a terminal, recording
+		// the description "item" as expected if it fails.
+		Expect(data, item string) string
+	}
+
+	// RecoveryGenerator is implemented by Generators supporting
+	// Definitions with a %recover expression.
+	RecoveryGenerator interface {
+		// Wraps the code "data" of a Definition so that when it fails
+		// after having consumed input, the error is recorded and the
+		// code "recovery" is used to skip ahead to where parsing can
+		// continue.
+		Recover(data, recovery string) string
+	}
+
+	// LeftRecursiveGenerator is implemented by Generators able to
+	// generate parsers for left recursive grammars.
+	LeftRecursiveGenerator interface {
+		// Called before generation starts with the definitions
+		// found by LeftRecursion, which are nil if there are none.
+		SetLeftRecursion(heads, involved []string)
+	}
+
+	// ActionGenerator is implemented by Generators supporting
+	// Sequences ending with an action block.
+	ActionGenerator interface {
+		// Wraps the code "data" of a Sequence so that when it matches,
+		// the action block's code "code" is run to compute the value
+		// of the Node created for the Definition it's in.
+		Action(data, code string) string
+	}
+
+	// LabelGenerator is implemented by Generators supporting
+	// labeled expressions.
+	LabelGenerator interface {
+		// Wraps the code "data" of an expression so that when it
+		// matches, the Nodes it created are labeled with "label"
+		// as Node.AddLabel does.
+		Label(data, label string) string
+	}
+
+	// SpacingGenerator is implemented by Generators whose parsers
+	// leave what ignored spacing attempts out of their errors.
+	SpacingGenerator interface {
+		// Called before generation starts with the definitions
+		// found by SpacingRules.
+		SetSpacing(rules map[string]bool)
+	}
+
+	// UnicodeGenerator is implemented by Generators supporting negated
+	// classes, classes of Unicode properties and literals ignoring case.
+	UnicodeGenerator interface {
+		// Accept and consume input if a character of "class" follows.
+		// Backtracks if it doesn't.
+		CheckClass(class *CharClass) string
+
+		// Accept and consume input if "a" follows, comparing its
+		// characters as FoldRune does. Backtracks if it doesn't.
+		CheckNextFold(a []rune) string
+	}
+
+	// CutGenerator is implemented by Generators supporting cuts.
+	CutGenerator interface {
+		// Called before generation starts if the grammar has cuts.
+		SetCut()
+
+		// Wraps the code "data" following a cut in a Sequence so that
+		// when it fails, parsing stops with an error at the offset it
+		// failed at, rather than backtracking to try something else.
+		Cut(data string) string
+	}
+
+	// PrecedenceLevel is a level of the precedence block of a Definition.
+	PrecedenceLevel struct {
+		// The name of the node giving the associativity of the
+		// level, which is LEFT, RIGHT, NONE, PREFIX or POSTFIX
+		Associativity string
+		// The code matching the operators of the level
+		Operator string
+	}
+
+	// PrecedenceGenerator is implemented by Generators supporting
+	// Definitions with a precedence block.
+	PrecedenceGenerator interface {
+		// Returns the code applying the operators of "levels", ordered
+		// from the loosest binding to the tightest, to the operands
+		// matched by the code "operand". Each operator applied creates
+		// a BinaryOp, PrefixOp or PostfixOp Node, holding the Nodes of
+		// its operands along with an Operator Node for the operator.
+		Precedence(operand string, levels []PrecedenceLevel) string
+	}
 )
 
 func (i *CodeFormatter) Level() string {
@@ -168,9 +285,51 @@ func (i *CodeFormatter) String() string {
 	return i.data
 }
 
+// Unescape decodes the escape sequences the PEG Char definition allows.
+func Unescape(s string) (ret []rune, err error) {
+	for len(s) > 0 {
+		if s[0] == '\\' && len(s) > 1 {
+			switch c := s[1]; c {
+			case '[', ']', '\'', '"':
+				ret = append(ret, rune(c))
+				s = s[2:]
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				n := 2
+				for n < len(s) && n < 4 && s[n] >= '0' && s[n] <= '7' {
+					n++
+				}
+				v, _ := strconv.ParseUint(s[1:n], 8, 32)
+				ret = append(ret, rune(v))
+				s = s[n:]
+				continue
+			}
+		}
+		r, _, tail, err := strconv.UnquoteChar(s, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid character %q", s)
+		}
+		ret = append(ret, r)
+		s = tail
+	}
+	return
+}
+
+// expect wraps the code "data" with ExpectedGenerator.Expect
+// if "gen" implements it.
+func expect(gen Generator, data, item string) string {
+	if eg, ok := gen.(ExpectedGenerator); ok {
+		return eg.Expect(data, item)
+	}
+	return data
+}
+
 func helper(gen Generator, node *Node) (retstring string) {
 	switch node.Name {
 	case "Class":
+		if class, err := NewCharClass(node); err == nil && !class.simple() {
+			return expect(gen, gen.(UnicodeGenerator).CheckClass(class), strings.TrimSpace(node.Data()))
+		}
 		others := ""
 		var exps []string
 		for _, child := range node.Children {
@@ -190,16 +349,21 @@ func helper(gen Generator, node *Node) (retstring string) {
 			for _, e := range exps {
 				g.Add(e, "")
 			}
-			return gen.EndGroup(g)
+			return expect(gen, gen.EndGroup(g), strings.TrimSpace(node.Data()))
 		} else {
-			return exps[0]
+			return expect(gen, exps[0], strings.TrimSpace(node.Data()))
 		}
 	case "DOT":
-		return gen.CheckAnyChar()
+		return expect(gen, gen.CheckAnyChar(), "any character")
 	case "Identifier":
 		return node.Data()
 	case "Literal":
-		return gen.CheckNext(node.Data())
+		if LiteralIgnoresCase(node) {
+			data := node.Data()
+			runes, _ := Unescape(data[1 : len(data)-2])
+			return expect(gen, gen.(UnicodeGenerator).CheckNextFold(runes), DescribeLiteral(data))
+		}
+		return expect(gen, gen.CheckNext(node.Data()), DescribeLiteral(node.Data()))
 	case "Expression":
 		if len(node.Children) == 1 {
 			return helper(gen, node.Children[0])
@@ -212,30 +376,30 @@ func helper(gen Generator, node *Node) (retstring string) {
 		}
 		return
 	case "Sequence":
-		if len(node.Children) == 1 {
-			return helper(gen, node.Children[0])
-		} else {
-			g := gen.BeginGroup(true)
-			for _, child := range node.Children {
-				g.Add(helper(gen, child), child.Name)
-			}
-			return gen.EndGroup(g)
+		children := node.Children
+		code := SequenceAction(node)
+		if code != nil {
+			children = children[:len(children)-1]
+		}
+		retstring = sequence(gen, children)
+		if code != nil {
+			return gen.(ActionGenerator).Action(retstring, code.Data())
 		}
 		return
 	case "Prefix":
-		front := node.Children[0]
-		if len(node.Children) == 1 {
-			return helper(gen, front)
-		} else {
-			exp := helper(gen, node.Children[len(node.Children)-1])
-			switch front.Name {
+		exp := helper(gen, node.Children[len(node.Children)-1])
+		if label := PrefixLabel(node); label != "" {
+			exp = gen.(LabelGenerator).Label(exp, label)
+		}
+		if pred := PrefixPredicate(node); pred != nil {
+			switch pred.Name {
 			case "NOT":
-				return gen.AssertNot(exp)
+				exp = gen.AssertNot(exp)
 			case "AND":
-				return gen.AssertAnd(exp)
+				exp = gen.AssertAnd(exp)
 			}
 		}
-		panic("Shouldn't reach this: " + front.Name)
+		return exp
 	case "Suffix":
 		if len(node.Children) == 1 {
 			return helper(gen, node.Children[0])
@@ -271,7 +435,121 @@ func helper(gen Generator, node *Node) (retstring string) {
 	return
 }
 
+// sequence returns the code matching the Prefix and CUT nodes "children"
+// of a Sequence one after the other. What follows a cut is wrapped with
+// CutGenerator.Cut.
+func sequence(gen Generator, children []*Node) string {
+	for children[len(children)-1].Name == "CUT" {
+		// Nothing follows that could fail
+		children = children[:len(children)-1]
+	}
+	if children[0].Name == "CUT" {
+		return gen.(CutGenerator).Cut(sequence(gen, children[1:]))
+	} else if len(children) == 1 {
+		return helper(gen, children[0])
+	}
+	g := gen.BeginGroup(true)
+	for i, child := range children {
+		if child.Name == "CUT" {
+			g.Add(sequence(gen, children[i:]), "Sequence")
+			break
+		}
+		g.Add(helper(gen, child), child.Name)
+	}
+	return gen.EndGroup(g)
+}
+
+// precedenceLevels returns the levels of the Level nodes "levels" of a
+// precedence block, with the code "gen" generates for their operators.
+func precedenceLevels(gen Generator, levels []*Node) (ret []PrecedenceLevel) {
+	for _, level := range levels {
+		ret = append(ret, PrecedenceLevel{level.Children[0].Name, helper(gen, level.Children[1])})
+	}
+	return
+}
+
+// contains reports whether "node" contains a node named "name".
+func contains(node *Node, name string) bool {
+	if node.Name == name {
+		return true
+	}
+	for _, child := range node.Children {
+		if contains(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func GenerateParser(rootNode *Node, gen Generator, s GeneratorSettings) error {
+	rootNode, err := ResolveImports(rootNode, s.Grammar, s.Import)
+	if err != nil {
+		return err
+	}
+	if rootNode, err = ExpandTemplates(rootNode); err != nil {
+		return fmt.Errorf("%s: %s", err.(*TemplateError).Rule, err)
+	}
+	// Also set without left recursion, so that a reused generator
+	// doesn't keep that of the previous grammar
+	if heads, involved := LeftRecursion(rootNode); len(heads) > 0 {
+		if lr, ok := gen.(LeftRecursiveGenerator); ok {
+			lr.SetLeftRecursion(heads, involved)
+		} else {
+			return fmt.Errorf("The generator doesn't support left recursion, which is used by: %s", strings.Join(heads, ", "))
+		}
+	} else if lr, ok := gen.(LeftRecursiveGenerator); ok {
+		lr.SetLeftRecursion(nil, nil)
+	}
+	if _, ok := gen.(RecoveryGenerator); !ok {
+		for _, node := range rootNode.Children {
+			if node.Name == "Definition" && DefinitionRecovery(node) != nil {
+				return fmt.Errorf("The generator doesn't support error recovery, which is used by: %s", node.Children[0].Data())
+			}
+		}
+	}
+	if _, ok := gen.(ActionGenerator); !ok {
+		for _, node := range rootNode.Children {
+			if node.Name == "Definition" && contains(node, "Action") {
+				return fmt.Errorf("The generator doesn't support action blocks, which are used by: %s", node.Children[0].Data())
+			}
+		}
+	}
+	if _, ok := gen.(LabelGenerator); !ok {
+		for _, node := range rootNode.Children {
+			if node.Name == "Definition" && contains(node, "Label") {
+				return fmt.Errorf("The generator doesn't support labels, which are used by: %s", node.Children[0].Data())
+			}
+		}
+	}
+	for _, node := range rootNode.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		if used, err := unicodeUse(node); err != nil {
+			return fmt.Errorf("%s: %s", node.Children[0].Data(), err)
+		} else if _, ok := gen.(UnicodeGenerator); used && !ok {
+			return fmt.Errorf("The generator doesn't support negated or Unicode classes and literals ignoring case, which are used by: %s", node.Children[0].Data())
+		}
+	}
+	if cg, ok := gen.(CutGenerator); !ok {
+		for _, node := range rootNode.Children {
+			if node.Name == "Definition" && contains(node, "CUT") {
+				return fmt.Errorf("The generator doesn't support cuts, which are used by: %s", node.Children[0].Data())
+			}
+		}
+	} else if contains(rootNode, "CUT") {
+		cg.SetCut()
+	}
+	if _, ok := gen.(PrecedenceGenerator); !ok {
+		for _, node := range rootNode.Children {
+			if node.Name == "Definition" && DefinitionPrecedence(node) != nil {
+				return fmt.Errorf("The generator doesn't support precedence blocks, which are used by: %s", node.Children[0].Data())
+			}
+		}
+	}
+	if sg, ok := gen.(SpacingGenerator); ok {
+		sg.SetSpacing(SpacingRules(rootNode))
+	}
 	if err := gen.Begin(s); err != nil {
 		return err
 	}
@@ -0,0 +1,375 @@
+package parser_test
+
+import (
+	"fmt"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generatorTest is a grammar the parsers of every generator are tested
+// with, along with inputs they should accept and reject.
+type generatorTest struct {
+	name, grammar  string
+	accept, reject []string
+}
+
+// cutTests are the grammars with cuts the generated parsers are tested with.
+var cutTests = []generatorTest{
+	{"Statement", `Program   <- (Statement ';')* !.
+Statement <- "while" ~ '(' Name ')' Statement
+           / "print" ~ ' ' Name
+           / Name '=' Name
+Name      <- [a-z]+`, []string{"print x;while(x)print y;", "x=y;"}, []string{"printx=y;", "while x;", "whilex=y;"}},
+	{"Item", `List <- Item (',' ~ Item)* !.
+Item <- [0-9]+ / ~ 'x'`, []string{"1,2,x", "x"}, []string{"1,", "1,2,", "y"}},
+}
+
+// precedenceTests are the grammars with precedence blocks the generated
+// parsers are tested with.
+var precedenceTests = []generatorTest{
+	{"Arith", `Program <- Expr !.
+Expr    <- Value / '(' Expr ')'
+           %none '<' / '='
+           %left '+' / '-'
+           %left '*' / '/'
+           %right '^'
+           %prefix '-'
+           %postfix '!'
+Value   <- [0-9]+`, []string{"1+2*3", "1-2-3", "2^3^4", "-2^3!", "(1<2)<3", "1<2+3", "2*-3-4", "--1!!"}, []string{"1<2<3", "1+", "+1", "1**2", "(1", ""}},
+	{"Not", `Program <- Expr !.
+Expr    <- Name
+           %prefix "not "
+           %left " and "
+Name    <- [a-z]+`, []string{"not a and b", "a and not b and c"}, []string{"not ", "a and"}},
+	{"Unary", `Program <- Expr !.
+Expr    <- Name
+           %prefix '-'
+           %postfix '!' / '?'
+Name    <- [a-z]+`, []string{"-a!?", "--a", "a!!"}, []string{"-", "a-", "!a"}},
+}
+
+// recoveryTests are the grammars with %recover expressions the generated
+// parsers are tested with.
+var recoveryTests = []generatorTest{
+	{"Retry", `S    <- Stmt 'x' / Stmt 'y'
+Stmt <- 'a' 'b' %recover (!'x' !'y' .)*`, []string{"acy", "abx", "ay"}, []string{"acz", "b"}},
+}
+
+// repetitionTests are the grammars with repetitions of expressions that
+// can match without consuming anything the generated parsers are tested
+// with.
+var repetitionTests = []generatorTest{
+	{"Lists", `List <- Item* (',' Item+)* !.
+Item <- 'a'? 'b'*`, []string{"", "ab", "aab", "a,b,", "bb,,a"}, []string{"c", "a,c"}},
+}
+
+// parseGrammar returns the peg.Peg Grammar node of "grammar".
+func parseGrammar(t *testing.T, grammar string) *Node {
+	var p peg.Peg
+	if !p.Parse(grammar + "\n") {
+		t.Fatalf("Didn't parse %q correctly: %s", grammar, p.Error())
+	}
+	return p.RootNode()
+}
+
+// generate generates a parser for "grammar" with "gen" in "dir",
+// parsing the file "input".
+func generate(t *testing.T, gen Generator, name, grammar, dir, input string) {
+	s := GeneratorSettings{
+		Name:     name,
+		Testname: input,
+		WriteFile: func(file, data string) error {
+			return ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0644)
+		},
+	}
+	if err := GenerateParser(parseGrammar(t, grammar), gen, s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testGo checks that the Go parsers generated for "tests" accept and
+// reject their inputs, creating the same trees as the interpreter.
+func testGo(t *testing.T, tests []generatorTest) {
+	if testing.Short() {
+		t.Skip("Not building the generated parsers in short mode")
+	}
+	// The parsers are generated in a directory in the module,
+	// so that they can import it
+	dir, err := ioutil.TempDir(".", "generatortest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range tests {
+		pkg := filepath.Join(dir, strings.ToLower(test.name))
+		if err := os.Mkdir(pkg, 0755); err != nil {
+			t.Fatal(err)
+		}
+		input, _ := filepath.Abs(filepath.Join(pkg, "input"))
+		if err := ioutil.WriteFile(input, []byte(test.accept[0]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, &GoGenerator{}, test.name, test.grammar, pkg, input)
+
+		var cases string
+		for _, in := range test.accept {
+			cases += fmt.Sprintf("\t\t{%q, true},\n", in)
+		}
+		for _, in := range test.reject {
+			cases += fmt.Sprintf("\t\t{%q, false},\n", in)
+		}
+		inputs := "package " + strings.ToLower(test.name) + `
+
+import (
+	"fmt"
+	"github.com/jxo/parser/interpreter"
+	"github.com/jxo/parser/peg"
+	"testing"
+)
+
+func TestInputs(t *testing.T) {
+	var g peg.Peg
+	if !g.Parse(` + fmt.Sprintf("%q", test.grammar+"\n") + `) {
+		t.Fatal(g.Error())
+	}
+	in, err := interpreter.New(` + fmt.Sprintf("%q", test.name) + `, g.RootNode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		in string
+		ok bool
+	}{
+` + cases + `	} {
+		var p ` + test.name + `
+		if ok := p.Parse(test.in); ok != test.ok {
+			t.Errorf("Parsing %q returned %v, expected %v", test.in, ok, test.ok)
+		} else if in.Parse(test.in) != ok {
+			t.Errorf("The interpreter returned %v for %q", !ok, test.in)
+		} else if a, b := p.RootNode().String(), in.RootNode().String(); ok && a != b {
+			t.Errorf("Trees differ for %q\nGenerated: %s\nInterpreted: %s", test.in, a, b)
+		} else if a, b := fmt.Sprint(p.Errors()), fmt.Sprint(in.Errors()); a != b {
+			t.Errorf("Recovered errors differ for %q\nGenerated: %s\nInterpreted: %s", test.in, a, b)
+		}
+	}
+}
+`
+		if err := ioutil.WriteFile(filepath.Join(pkg, "inputs_test.go"), []byte(inputs), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("go", "test", "./"+dir+"/...").CombinedOutput(); err != nil {
+		t.Errorf("%s\n%s", err, out)
+	}
+}
+
+// testGenerators checks that the parsers the other generators generate
+// for "tests" accept and reject their inputs, for the languages whose
+// tools are installed.
+func testGenerators(t *testing.T, tests []generatorTest) {
+	if testing.Short() {
+		t.Skip("Not building the generated parsers in short mode")
+	}
+	for _, lang := range []struct {
+		name string
+		gen  func() Generator
+		// The commands building and running the parser, with
+		// NAME and name replaced by the name of the grammar
+		build, run []string
+	}{
+		{"c", func() Generator { return &CGenerator{} }, []string{"cc", "-o", "parser", "name.c"}, []string{"./parser"}},
+		{"cpp", func() Generator { return &CPPGenerator{} }, []string{"c++", "-o", "parser", "name.cpp"}, []string{"./parser"}},
+		{"java", func() Generator { return &JavaGenerator{} }, []string{"javac", "NAME.java"}, []string{"java", "NAME"}},
+		{"py", func() Generator { return &PyGenerator{} }, nil, []string{"python3", "name.py"}},
+	} {
+		lang := lang
+		t.Run(lang.name, func(t *testing.T) {
+			for _, cmd := range [][]string{lang.build, lang.run} {
+				if len(cmd) == 0 || strings.HasPrefix(cmd[0], "./") {
+					continue
+				}
+				if _, err := exec.LookPath(cmd[0]); err != nil {
+					t.Skipf("%s isn't installed", cmd[0])
+				}
+			}
+			for _, test := range tests {
+				dir, err := ioutil.TempDir("", "generatortest")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+				input := filepath.Join(dir, "input")
+				generate(t, lang.gen(), test.name, test.grammar, dir, input)
+
+				command := func(args []string) *exec.Cmd {
+					var ret []string
+					for _, arg := range args {
+						arg = strings.Replace(arg, "NAME", test.name, -1)
+						ret = append(ret, strings.Replace(arg, "name", strings.ToLower(test.name), -1))
+					}
+					c := exec.Command(ret[0], ret[1:]...)
+					c.Dir = dir
+					return c
+				}
+				if lang.build != nil {
+					if out, err := command(lang.build).CombinedOutput(); err != nil {
+						t.Errorf("Building the %s parser failed: %s\n%s", test.name, err, out)
+						continue
+					}
+				}
+				parse := func(in string, ok bool) {
+					if err := ioutil.WriteFile(input, []byte(in), 0644); err != nil {
+						t.Fatal(err)
+					}
+					out, err := command(lang.run).CombinedOutput()
+					if err != nil && !strings.Contains(string(out), "Failed to parse") {
+						t.Errorf("Running the %s parser on %q failed: %s\n%s", test.name, in, err, out)
+					} else if (err == nil) != ok {
+						t.Errorf("The %s parser returned %v for %q, expected %v", test.name, err == nil, in, ok)
+					}
+				}
+				for _, in := range test.accept {
+					parse(in, true)
+				}
+				for _, in := range test.reject {
+					parse(in, false)
+				}
+			}
+		})
+	}
+}
+
+func TestActionValuesGo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Not building the generated parsers in short mode")
+	}
+	dir, err := ioutil.TempDir(".", "generatortest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The values of the action blocks of what was backtracked over
+	// aren't kept
+	generate(t, &GoGenerator{}, "Values", `A <- (B 'y' { return 1 }) 'x' / &(B { return 2 }) B 'y'?
+B <- 'b'`, dir, "input")
+	values := `package values
+
+import (
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		value interface{}
+	}{
+		{"byx", 1},
+		{"by", nil},
+		{"b", nil},
+	} {
+		var p Values
+		if !p.Parse(test.in) {
+			t.Errorf("Didn't parse %q correctly: %s", test.in, p.Error())
+		} else if v := p.RootNode().Children[0].Value; v != test.value {
+			t.Errorf("Expected %q to give the value %v, got %v", test.in, test.value, v)
+		}
+	}
+}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "values_test.go"), []byte(values), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("go", "test", "./"+dir).CombinedOutput(); err != nil {
+		t.Errorf("%s\n%s", err, out)
+	}
+}
+
+func TestRecoverGo(t *testing.T) {
+	testGo(t, recoveryTests)
+}
+
+func TestRepetitionGo(t *testing.T) {
+	testGo(t, repetitionTests)
+}
+
+func TestCutGo(t *testing.T) {
+	testGo(t, cutTests)
+}
+
+func TestCutGenerators(t *testing.T) {
+	testGenerators(t, cutTests)
+}
+
+func TestPrecedenceGo(t *testing.T) {
+	testGo(t, precedenceTests)
+}
+
+func TestPrecedenceGenerators(t *testing.T) {
+	testGenerators(t, precedenceTests)
+}
+
+// TestPrecedenceJava checks the Java code of a precedence block against
+// the one in testdata, as the Java parsers are only built and run where
+// a JDK is installed.
+func TestPrecedenceJava(t *testing.T) {
+	var java string
+	s := GeneratorSettings{
+		Name: "Arith",
+		WriteFile: func(name, data string) error {
+			if name == "Arith.java" {
+				java = data
+			}
+			return nil
+		},
+	}
+	if err := GenerateParser(parseGrammar(t, precedenceTests[0].grammar), &JavaGenerator{}, s); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/precedence.java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := java
+	if i := strings.Index(got, "\tprivate boolean precedence_Expr("); i >= 0 {
+		got = got[i:]
+		got = got[:strings.Index(got, "\n\t}\n")+4]
+	}
+	if got != string(want) {
+		t.Errorf("The Java code of the precedence block differs from testdata/precedence.java:\n%s", got)
+	}
+}
+
+// TestGoGeneratorReuse checks that a GoGenerator generates the same parser
+// for a grammar after being used for a left recursive one as a new one.
+func TestGoGeneratorReuse(t *testing.T) {
+	gen := func(g *GoGenerator, grammar string) string {
+		var out string
+		s := GeneratorSettings{
+			Name: "Reuse",
+			WriteFile: func(name, data string) error {
+				if name == "reuse.go" {
+					out = data
+				}
+				return nil
+			},
+		}
+		if err := GenerateParser(parseGrammar(t, grammar), g, s); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	grammar := `A <- B 'x'
+B <- 'b'+`
+	var g GoGenerator
+	gen(&g, `E <- E '+' N / N
+N <- [0-9]+`)
+	if got, want := gen(&g, grammar), gen(&GoGenerator{}, grammar); got != want {
+		t.Errorf("The reused generator generated:\n%s\nexpected:\n%s", got, want)
+	}
+}
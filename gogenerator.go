@@ -28,8 +28,11 @@ package parser
 import (
 	"container/list"
 	"fmt"
+	"go/format"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 type GoGenerator struct {
@@ -46,6 +49,12 @@ type GoGenerator struct {
 	debug, bench          bool
 	inlineCount           int
 	calledP               bool
+	ruleCount             int
+	heads, involved       map[string]bool
+	cut                   bool
+	valued                bool
+	spacing               map[string]bool
+	definitions           []*Node
 	RootNode              *Node
 }
 
@@ -53,21 +62,51 @@ func (g *GoGenerator) SetCustomActions(actions []CustomAction) {
 	g.CustomActions = actions
 }
 
+func (g *GoGenerator) SetLeftRecursion(heads, involved []string) {
+	g.heads = make(map[string]bool)
+	g.involved = make(map[string]bool)
+	for _, name := range heads {
+		g.heads[name] = true
+	}
+	for _, name := range involved {
+		g.involved[name] = true
+	}
+}
+
+func (g *GoGenerator) SetSpacing(rules map[string]bool) {
+	g.spacing = rules
+}
+
+func (g *GoGenerator) SetCut() {
+	g.cut = true
+}
+
 func (g *GoGenerator) AddNode(data, defName string) string {
-	ret := `accept = true
-start := p.ParserData.Pos()
+	ret := "accept = true\n"
+	if g.valued {
+		ret += "var value interface{}\n"
+	}
+	ret += `start := p.ParserData.Pos()
+mark := p.Expected.Mark(start)
+entered := p.Backtrack.Enter(start)
 ` + g.Call(data) + `
 end := p.ParserData.Pos()
+p.Expected.Collapse(start, mark, accept, "` + defName + `")
 if accept {
 `
 	if g.calledP || true {
 		ret += `	node := p.Root.Cleanup(start, end)
 	node.Name = "` + defName + `"
 	node.P = p
-	node.Range = node.Range.Clip(p.IgnoreRange)
+`
+		if g.valued {
+			ret += "	node.Value = value\n"
+		}
+		ret += `	node.Range = node.Range.Clip(p.IgnoreRange)
 	p.Root.Append(node)
 } else {
-	p.Root.Discard(start)`
+	p.Root.Discard(start)` + g.trivia(`
+	p.Trivia.Discard(start)`)
 	} else {
 		ret += `	node := &Node{Range:text.Region{start,end}}
 	node.Name = "` + defName + `"
@@ -77,6 +116,7 @@ if accept {
 	}
 	ret += `
 }
+p.Backtrack.Release(entered)
 if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 	p.IgnoreRange = text.Region{}
 }
@@ -85,28 +125,75 @@ if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 }
 
 func (g *GoGenerator) Ignore(data string) string {
+	call := g.Call(data)
+	if g.spacing[g.currentName] {
+		// What ignored definitions like spacing attempt isn't
+		// what the input is expected to contain
+		call = "p.Expected.Mute()\n" + call + "\np.Expected.Unmute()"
+	}
 	return `accept = true
 start := p.ParserData.Pos()
-` + g.Call(data) + `
+` + call + `
 if accept && start != p.ParserData.Pos() {
-	if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+	if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 		p.IgnoreRange.A = start
 	}
 	p.IgnoreRange.B = p.ParserData.Pos()
-}
+` + g.trivia(`	p.Trivia.AddTrivia(&p.Root, "`+g.currentName+`", start, p.ParserData.Pos())
+`) + `}
 `
 }
+
+// trivia returns "code" if the parser keeps trivia.
+func (g *GoGenerator) trivia(code string) string {
+	if g.s.Trivia {
+		return code
+	}
+	return ""
+}
 func (g *GoGenerator) MakeParserFunction(node *Node) error {
 	g.calledP = false
 	id := node.Children[0]
-	exp := node.Children[len(node.Children)-1]
+	exp := DefinitionExpression(node)
 	defName := helper(g, id)
 	g.currentName = defName
+	g.definitions = append(g.definitions, node)
+	var custom *CustomAction
+	for i := range g.CustomActions {
+		if defName == g.CustomActions[i].Name {
+			custom = &g.CustomActions[i]
+			break
+		}
+	}
+	// Only the Nodes added by default are given the value of an action
+	g.valued = custom == nil && contains(node, "Action")
 	data := helper(g, exp)
+	if levels := DefinitionPrecedence(node); levels != nil {
+		if contains(node, "Action") {
+			return fmt.Errorf("%s: action blocks can't be used along with a precedence block", defName)
+		}
+		data = g.Precedence(data, precedenceLevels(g, levels))
+	}
+	if recovery := DefinitionRecovery(node); recovery != nil {
+		data = g.Recover(data, helper(g, recovery))
+	}
 
 	if !g.havefunctions {
 		g.havefunctions = true
-		g.output += "func (p *" + g.s.Name + ") realParse() bool {\n\treturn p." + defName + "()\n}\n"
+		if g.cut {
+			g.output += `func (p *` + g.s.Name + `) realParse() (accept bool) {
+	defer func() {
+		if IsCutFailure(recover()) {
+			accept = false
+`
+			if g.s.Memoize {
+				// The entries of the rules the parse stopped in are still pending
+				g.output += "\t\t\tp.Memo.Entries = make(map[MemoKey]*MemoEntry)\n"
+			}
+			g.output += "\t\t}\n\t}()\n\treturn p." + defName + "()\n}\n"
+		} else {
+			g.output += "func (p *" + g.s.Name + ") realParse() bool {\n\treturn p." + defName + "()\n}\n"
+		}
 	}
 
 	indenter := CodeFormatter{}
@@ -132,17 +219,34 @@ defer func() {
 `)
 	}
 
-	defaultAction := true
-	for i := range g.CustomActions {
-		if defName == g.CustomActions[i].Name {
-			defaultAction = false
-			data = g.CustomActions[i].Action(g, data)
-			break
-		}
-	}
-	if defaultAction {
+	if custom != nil {
+		data = custom.Action(g, data)
+	} else {
 		data = g.AddNode(data, defName)
 	}
+	ret := "return accept\n"
+	if g.heads[defName] || (g.s.Memoize && !g.involved[defName]) {
+		indenter.Add(fmt.Sprintf(`memo, done := p.Memo.Enter(%d)
+if done {
+	return p.Memo.Replay(memo)
+}
+`, g.ruleCount))
+		if !strings.HasPrefix(data, "accept") && data[0] != '{' {
+			data = "accept = " + data
+		}
+		if g.heads[defName] {
+			var cf CodeFormatter
+			cf.Add("accept = false\nsaved := p.Backtrack.Save(p.ParserData.Pos())\nfor {\n")
+			cf.Inc()
+			cf.Add(strings.TrimRight(data, "\n") + "\nif !p.Memo.Grow(memo, accept) {\n\tbreak\n}\n")
+			cf.Dec()
+			cf.Add("}\np.Backtrack.Release(saved)\naccept = p.Memo.Replay(memo)\n")
+			data = cf.String()
+		} else {
+			ret = "return p.Memo.Leave(memo, accept)\n"
+		}
+	}
+	g.ruleCount++
 	if g.s.DebugLevel > DebugLevelNone {
 		if strings.HasPrefix(data, "accept") || data[0] == '{' {
 			data = "accept := false\n" + data
@@ -185,13 +289,11 @@ if p2 < p.ParserData.Len() {
 				indenter.Dec("}")
 			}
 
-			indenter.Add(`
-return accept
-`)
+			indenter.Add("\n" + ret)
 		}
 	} else {
 		if strings.HasPrefix(data, "accept") || data[0] == '{' {
-			end := "return accept\n"
+			end := ret
 			if data[len(data)-1] != '\n' {
 				end = "\n" + end
 			}
@@ -248,7 +350,7 @@ func (g *GoGenerator) CheckInRange(a, b string) string {
 		log.Printf(fm.Level()+"accept = false; character %c not range", c)`
 	}
 	return `c := p.ParserData.Read()
-if c >= '` + a + `' && c <= '` + b + `' {
+if c >= ` + goRune(a) + ` && c <= ` + goRune(b) + ` {
 	accept = true
 } else {
 	p.ParserData.UnRead()
@@ -257,23 +359,13 @@ if c >= '` + a + `' && c <= '` + b + `' {
 }
 
 func (g *GoGenerator) CheckInSet(a string) string {
-	a = strings.Replace(a, "\\[", "[", -1)
-	a = strings.Replace(a, "\\]", "]", -1)
+	runes, _ := Unescape(a)
 	tests := ""
-	for i := 0; i < len(a); i++ {
+	for _, r := range runes {
 		if len(tests) > 0 {
 			tests += " || "
 		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-
-		tests += "c == '" + c2 + "'"
+		tests += "c == " + strconv.QuoteRune(r)
 	}
 	return `{
 	accept = false
@@ -305,7 +397,7 @@ func (g *GoGenerator) CheckNext(a string) string {
 	}
 
 	if a[0] == '\'' {
-		return `if p.ParserData.Read() != ` + a + ` {
+		return `if p.ParserData.Read() != ` + goRune(a[1:len(a)-1]) + ` {
 	p.ParserData.UnRead()
 	accept = false` + extra1 + `
 } else {
@@ -317,24 +409,13 @@ func (g *GoGenerator) CheckNext(a string) string {
 		extra2 = fmt.Sprintf(`
 	log.Printf(fm.Level()+"accept = false; expected %s, have %%s", p.ParserData.Substring(s,e))`, a)
 	}
+	runes, _ := Unescape(a)
 	tests := ""
-	pos := 0
-	for i := 0; i < len(a); i, pos = i+1, pos+1 {
+	for _, r := range runes {
 		if len(tests) > 0 {
 			tests += " || "
 		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "\\\"" {
-			c2 = "\""
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-		tests += fmt.Sprintf("p.ParserData.Read() != '%s'", c2)
+		tests += "p.ParserData.Read() != " + strconv.QuoteRune(r)
 	}
 	return fmt.Sprintf(`{
 	accept = true
@@ -346,21 +427,355 @@ func (g *GoGenerator) CheckNext(a string) string {
 }`, tests, extra2)
 }
 
+// goRune returns the PEG Char "char" as a Go rune literal.
+func goRune(char string) string {
+	r, _ := Unescape(char)
+	return strconv.QuoteRune(r[0])
+}
+
+func (g *GoGenerator) CheckClass(class *CharClass) string {
+	var tests []string
+	for _, r := range class.Ranges {
+		if r.Lo == r.Hi {
+			tests = append(tests, "c == "+strconv.QuoteRune(r.Lo))
+		} else {
+			tests = append(tests, "c >= "+strconv.QuoteRune(r.Lo)+" && c <= "+strconv.QuoteRune(r.Hi))
+		}
+	}
+	for _, name := range class.Properties {
+		tests = append(tests, "HasProperty("+strconv.Quote(name)+", c)")
+	}
+	test := strings.Join(tests, " || ")
+	if class.Negated {
+		test = "!(" + test + ")"
+	}
+	return `{
+	accept = false
+	if p.ParserData.Pos() < p.ParserData.Len() {
+		if c := p.ParserData.Read(); ` + test + ` {
+			accept = true
+		} else {
+			p.ParserData.UnRead()
+		}
+	}
+}`
+}
+
+func (g *GoGenerator) CheckNextFold(a []rune) string {
+	var tests []string
+	for _, r := range a {
+		if f := FoldRune(r); f == r && unicode.SimpleFold(r) == r {
+			tests = append(tests, "p.ParserData.Read() != "+strconv.QuoteRune(r))
+		} else {
+			tests = append(tests, "FoldRune(p.ParserData.Read()) != "+strconv.QuoteRune(f))
+		}
+	}
+	return `{
+	accept = true
+	s := p.ParserData.Pos()
+	if ` + strings.Join(tests, " || ") + ` {
+		p.ParserData.Seek(s)
+		accept = false
+	}
+}`
+}
+
+func (g *GoGenerator) Expect(data, item string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add("pos := p.ParserData.Pos()\n" + g.Call(data) + "\nif !accept {\n\tp.Expected.Add(pos, " + strconv.Quote(item) + ")\n}\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+func (g *GoGenerator) Cut(data string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add(`lastError, expected := p.LastError, p.Expected.Commit()
+p.LastError = 0
+` + g.Call(data) + `
+if !accept {
+	` + strings.Replace(g.UpdateError(""), "\n", "\n\t", -1) + `
+	panic(CutFailure{})
+}
+if p.LastError < lastError {
+	p.LastError = lastError
+}
+p.Expected.Restore(expected)
+`)
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+// wrap returns the code moving the Nodes created since "start" to a
+// new Node named "name", like AddNode does for a Definition.
+func (g *GoGenerator) wrap(start, name string) string {
+	return `node := p.Root.Cleanup(` + start + `, p.ParserData.Pos())
+node.Name = "` + name + `"
+node.P = p
+node.Range = node.Range.Clip(p.IgnoreRange)
+p.Root.Append(node)`
+}
+
+func (g *GoGenerator) Precedence(operand string, levels []PrecedenceLevel) string {
+	name := "precedence" + g.currentName
+	var cf CodeFormatter
+	cf.Add("// " + name + ` applies the operators of ` + g.currentName + ` binding at
+// least as tightly as the level "min" to its operands.
+func (p *` + g.s.Name + `) ` + name + `(min int) bool {
+`)
+	cf.Inc()
+	cf.Add("accept := false\nstart := p.ParserData.Pos()\nsaved := p.Backtrack.Save(start)\n")
+	for _, l := range levels {
+		if l.Associativity == "NONE" {
+			// The operators of a non-associative level can't follow
+			// the ones it has just applied
+			cf.Add("last := 0\n")
+			break
+		}
+	}
+	// The operator is matched within a block creating a Node for it
+	operator := func(code string) {
+		cf.Add("{\n")
+		cf.Inc()
+		cf.Add("pos := p.ParserData.Pos()\n" + g.Call(code) + "\nif accept {\n\t" + strings.Replace(g.wrap("pos", "Operator"), "\n", "\n\t", -1) + "\n} else {\n\tp.Root.Discard(pos)\n}\n")
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	prefix, binary := false, false
+	for i, l := range levels {
+		if l.Associativity != "PREFIX" {
+			binary = binary || l.Associativity != "POSTFIX"
+			continue
+		}
+		prefix = true
+		// A prefix operator applies wherever an operand is expected,
+		// binding its operand at least as tightly as its own level
+		level := strconv.Itoa(i + 1)
+		cf.Add("if !accept {\n")
+		cf.Inc()
+		operator(l.Operator)
+		cf.Add(`if accept {
+	level := ` + level + `
+	if level < min {
+		level = min
+	}
+	if accept = p.` + name + `(level); accept {
+		` + strings.Replace(g.wrap("start", "PrefixOp"), "\n", "\n\t\t", -1) + `
+	} else {
+		p.ParserData.Seek(start)
+		p.Root.Discard(start)
+	}
+}
+`)
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	if prefix {
+		cf.Add("if !accept {\n")
+		cf.Inc()
+	}
+	cf.Add(g.Call(operand) + "\nif !accept {\n\tp.Backtrack.Release(saved)\n\treturn false\n}\n")
+	if prefix {
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	cf.Add("for {\n")
+	cf.Inc()
+	// Each operator is tried from where the one before it stopped
+	cf.Add("p.Backtrack.Release(saved)\nsaved = p.Backtrack.Save(p.ParserData.Pos())\n")
+	if binary {
+		cf.Add("save := p.ParserData.Pos()\n")
+	}
+	for i, l := range levels {
+		level := strconv.Itoa(i + 1)
+		switch l.Associativity {
+		case "PREFIX":
+			continue
+		case "NONE":
+			cf.Add("if min <= " + level + " && last != " + level + " {\n")
+		default:
+			cf.Add("if min <= " + level + " {\n")
+		}
+		cf.Inc()
+		operator(l.Operator)
+		if l.Associativity == "POSTFIX" {
+			cf.Add("if accept {\n\t" + strings.Replace(g.wrap("start", "PostfixOp"), "\n", "\n\t", -1) + "\n\tcontinue\n}\n")
+		} else {
+			// The right operand of a right associative operator may
+			// apply it again, while for the others it binds tighter
+			next := strconv.Itoa(i + 2)
+			if l.Associativity == "RIGHT" {
+				next = level
+			}
+			cf.Add("if accept {\n\tif accept = p." + name + "(" + next + "); accept {\n\t\t" + strings.Replace(g.wrap("start", "BinaryOp"), "\n", "\n\t\t", -1) + "\n")
+			if l.Associativity == "NONE" {
+				cf.Add("\t\tlast = " + level + "\n")
+			}
+			cf.Add("\t\tcontinue\n\t}\n\tp.ParserData.Seek(save)\n\tp.Root.Discard(save)\n}\n")
+		}
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	cf.Add("p.Backtrack.Release(saved)\nreturn true\n")
+	cf.Dec()
+	cf.Add("}\n")
+	cf.Dec()
+	cf.Add("}\n\n")
+	g.currentFunctions += cf.String()
+	return "accept = p." + name + "(1)"
+}
+
+func (g *GoGenerator) Recover(data, recovery string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	// The error is described by what the definition itself attempted
+	cf.Add(`save := p.ParserData.Pos()
+saved := p.Backtrack.Save(save)
+lastError, expected := p.LastError, p.Expected.Commit()
+p.LastError = 0
+` + g.Call(data) + `
+if !accept && p.LastError > save {
+	err := p.Error()
+	failed := p.LastError
+	p.ParserData.Seek(save)
+	p.Expected.Mute()
+`)
+	cf.Inc()
+	cf.Add(g.Call(recovery))
+	cf.Dec()
+	cf.Add(`
+	p.Expected.Unmute()
+	p.LastError = failed
+	if accept && p.ParserData.Pos() > save {
+		node := p.Root.Cleanup(save, p.ParserData.Pos())
+		node.Name = "Error"
+		node.P = p
+		node.Value = err
+		p.Root.Append(node)
+	} else {
+		accept = false
+		p.ParserData.Seek(save)
+		p.Root.Discard(save)
+	}
+}
+p.Backtrack.Release(saved)
+if p.LastError < lastError {
+	p.LastError = lastError
+}
+p.Expected.Restore(expected)
+`)
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+// dedent trims the space around "code" and the indentation its
+// lines after the first have in common.
+func dedent(code string) string {
+	lines := strings.Split(strings.TrimSpace(code), "\n")
+	indent, first := "", true
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			indent, first = lead, false
+		}
+		for !strings.HasPrefix(lead, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(lines[i], " \t"), indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (g *GoGenerator) Action(data, code string) string {
+	g.currentFunctionsCount++
+	name := "action" + g.currentName + strconv.Itoa(g.currentFunctionsCount)
+	g.currentFunctions += "// " + name + " is an action block of " + g.currentName + `, given the
+// Nodes "c" created and the "data" matched by its Sequence.
+func (p *` + g.s.Name + `) ` + name + `(c []*Node, data string) interface{} {
+	` + strings.Replace(dedent(code), "\n", "\n\t", -1) + `
+}
+
+`
+	call := "p." + name + "(p.Root.Tail(save), p.ParserData.Substring(save, p.ParserData.Pos()))"
+	if g.valued {
+		call = "value = " + call
+	}
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	// The action reads the data matched, which must still be buffered
+	cf.Add("save := p.ParserData.Pos()\nsaved := p.Backtrack.Save(save)\n" + g.Call(data) + "\nif accept {\n\t" + call + "\n}\np.Backtrack.Release(saved)\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+func (g *GoGenerator) Label(data, label string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add("save := p.ParserData.Pos()\n" + g.Call(data) + "\nif accept {\n\tp.Root.AddLabel(save, p.ParserData.Pos(), " + strconv.Quote(label) + ")\n}\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+// saveValue returns the code saving the value the action blocks of the
+// definition have computed, to be restored along with the position
+// when the input they matched is backtracked over.
+func (g *GoGenerator) saveValue() string {
+	if !g.valued {
+		return ""
+	}
+	return "saveValue := value\n"
+}
+
+// restoreValue returns the code restoring the value saved by saveValue.
+func (g *GoGenerator) restoreValue() string {
+	if !g.valued {
+		return ""
+	}
+	return "value = saveValue\n"
+}
+
 func (g *GoGenerator) AssertNot(a string) string {
-	return `s := p.ParserData.Pos()
-` + g.Call(a) + `
-p.ParserData.Seek(s)
-p.Root.Discard(s)
-accept = !accept`
+	return g.assert(a, "accept = !accept\n")
 }
 
 func (g *GoGenerator) AssertAnd(a string) string {
-	return `s := p.ParserData.Pos()
-` + g.Call(a) + `
-p.ParserData.Seek(s)
-p.Root.Discard(s)`
+	return g.assert(a, "")
 }
 
+// assert returns the code of a predicate matching "a" without consuming
+// any input, followed by "end".
+func (g *GoGenerator) assert(a, end string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add("s := p.ParserData.Pos()\nsaved := p.Backtrack.Save(s)\n" + g.saveValue() + "p.Expected.Mute()\n" + g.Call(a) + "\np.Expected.Unmute()\np.Backtrack.Release(saved)\np.ParserData.Seek(s)\np.Root.Discard(s)\n" + g.restoreValue() + end)
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+// nothingConsumed ends the loop of a repetition whose expression matched
+// without consuming anything, as it would loop forever.
+const nothingConsumed = `if pos == p.ParserData.Pos() {
+	break
+}`
+
 func (g *GoGenerator) ZeroOrMore(a string) string {
 	var cf CodeFormatter
 	cf.Add("{\n")
@@ -368,7 +783,7 @@ func (g *GoGenerator) ZeroOrMore(a string) string {
 	cf.Add("accept = true")
 	cf.Add("\nfor accept {\n")
 	cf.Inc()
-	cf.Add(g.Call(a))
+	cf.Add("pos := p.ParserData.Pos()\nsaved := p.Backtrack.Save(pos)\n" + g.Call(a) + "\np.Backtrack.Release(saved)\n" + nothingConsumed)
 	cf.Dec()
 	cf.Add("\n}\n")
 	cf.Add("accept = true\n")
@@ -390,7 +805,7 @@ if !accept {
 `)
 	cf.Inc()
 	cf.Inc()
-	cf.Add(g.Call(a) + "\n")
+	cf.Add("pos := p.ParserData.Pos()\nsaved := p.Backtrack.Save(pos)\n" + g.Call(a) + "\np.Backtrack.Release(saved)\n" + nothingConsumed + "\n")
 	cf.Dec()
 	cf.Add(`}
 accept = true
@@ -403,7 +818,13 @@ accept = true
 }
 
 func (g *GoGenerator) Maybe(a string) string {
-	return g.Call(a) + "\naccept = true"
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add("saved := p.Backtrack.Save(p.ParserData.Pos())\n" + g.Call(a) + "\np.Backtrack.Release(saved)\naccept = true\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
 }
 
 type needAllGroup struct {
@@ -438,11 +859,13 @@ func (g *GoGenerator) BeginGroup(requireAll bool) Group {
 	save := p.ParserData.Pos()
 `)
 		r.cf.Inc()
+		r.cf.Add(g.saveValue())
 		return &r
 	}
 	r := needOneGroup{g: g}
 	r.cf.Add(`{
 	save := p.ParserData.Pos()
+	saved := p.Backtrack.Save(save)
 `)
 	r.cf.Inc()
 	return &r
@@ -463,7 +886,7 @@ func (g *GoGenerator) EndGroup(gr Group) string {
 		}
 		t.cf.Add("if !accept {\n")
 		t.cf.Inc()
-		t.cf.Add(g.UpdateError("TODO") + "\np.ParserData.Seek(save)\n")
+		t.cf.Add(g.UpdateError("TODO") + "\np.ParserData.Seek(save)\n" + g.restoreValue())
 		t.cf.Dec()
 		t.cf.Add("}\n")
 		t.cf.Dec()
@@ -474,7 +897,7 @@ func (g *GoGenerator) EndGroup(gr Group) string {
 			t.cf.Dec()
 			t.cf.Add("}\n")
 		}
-		t.cf.Add("if !accept {\n\tp.ParserData.Seek(save)\n}\n")
+		t.cf.Add("p.Backtrack.Release(saved)\nif !accept {\n\tp.ParserData.Seek(save)\n}\n")
 		t.cf.Dec()
 		t.cf.Add("}")
 		return t.cf.String()
@@ -503,14 +926,23 @@ func (g *GoGenerator) Call(value string) string {
 
 func (g *GoGenerator) Begin(s GeneratorSettings) error {
 	g.s = s
+	g.definitions = nil
+	g.ruleCount = 0
+	g.havefunctions = false
 	imports := `
 
 import (
 	"github.com/jxo/lime/text"
 	. "github.com/jxo/parser"
 `
-	impList := g.Imports
+	impList := append([]string{"io"}, g.Imports...)
 	members := g.ParserVariables
+	if g.s.Memoize || len(g.heads) > 0 {
+		members = append(members, "Memo Memo")
+	}
+	if g.s.Trivia {
+		members = append(members, "Trivia Node")
+	}
 	if g.s.Heatmap {
 		members = append(members, "Heatmap map[string]Heat")
 		impList = append(impList, "fmt", "time", "sort")
@@ -526,7 +958,9 @@ import (
 	g.output = g.s.Header + "\n"
 	members = append(members, "ParserData  Reader", "IgnoreRange text.Region",
 		"Root        Node",
-		"LastError   int")
+		"LastError   int",
+		"Expected    Expected",
+		"Backtrack   Backtrack")
 	g.output += fmt.Sprintln("package " + strings.ToLower(g.s.Name) + imports + "\ntype " + g.s.Name + " struct {\n\t" + strings.Join(members, "\n\t") + "\n}\n")
 
 	if g.s.DebugLevel > DebugLevelNone {
@@ -573,7 +1007,12 @@ func (t *TotHeat) String() (ret string) {
 }
 
 func (p *` + g.s.Name + `) SetData(data string) {
-	p.ParserData = NewReader(data)
+	p.SetReader(NewReader(data))
+}
+
+// SetReader prepares the parser for parsing the data of "r".
+func (p *` + g.s.Name + `) SetReader(r Reader) {
+	p.ParserData = r
 `
 	if g.s.Heatmap {
 		g.output += "	p.Heatmap = make(map[string]Heat)\n"
@@ -581,42 +1020,93 @@ func (p *` + g.s.Name + `) SetData(data string) {
 	g.output += `	p.Root = Node{Name: "` + g.s.Name + `", P: p}
 	p.IgnoreRange = text.Region{}
 	p.LastError = 0
-}
+	p.Expected = Expected{}
+	p.Backtrack.Reset()
+` + g.trivia(`	p.Trivia = Node{Name: "Trivia", P: p}
+`)
+	if g.s.Memoize || len(g.heads) > 0 {
+		g.output += `	p.Memo = Memo{
+		Data:        p.ParserData,
+		Root:        &p.Root,
+		IgnoreRange: &p.IgnoreRange,
+		LastError:   &p.LastError,
+		Expected:    &p.Expected,
+` + g.trivia(`		Trivia:      &p.Trivia,
+`) + `		Entries:     make(map[MemoKey]*MemoEntry),
+	}
+`
+	}
+	g.output += `}
 
 func (p *` + g.s.Name + `) Parse(data string) bool {
 	p.SetData(data)
 	ret := p.realParse()
 	p.Root.UpdateRange()
-	return ret
+` + g.trivia(`	AttachTrivia(&p.Root, p.Trivia.Children)
+`) + `	return ret
 }
 
+// ParseReader parses the data read from "r", keeping no more of it
+// in memory than what the parser might still backtrack over and the
+// data of the Nodes parsed.
+func (p *` + g.s.Name + `) ParseReader(r io.Reader) bool {
+	s := NewStreamReader(r)
+	s.Live = &p.Root
+	s.Backtrack = &p.Backtrack
+` + g.trivia(`	s.Trivia = &p.Trivia
+`) + `	p.SetReader(s)
+	ret := p.realParse()
+	p.Root.UpdateRange()
+` + g.trivia(`	AttachTrivia(&p.Root, p.Trivia.Children)
+`) + `	return ret
+}
+`
+	if g.s.Memoize {
+		g.output += `
+// Update parses the data again after "region" of it has been replaced
+// with "data", reusing the parts of the previous parse that the edit
+// didn't affect. The updated tree is available via RootNode, and shares
+// the Nodes that were reused with the previous tree.
+func (p *` + g.s.Name + `) Update(region text.Region, data string) bool {
+	entries := p.Memo.Edit(region, len(data))
+	p.SetData(p.ParserData.Substring(0, region.Begin()) + data + p.ParserData.Substring(region.End(), p.ParserData.Len()))
+	p.Memo.Entries = entries
+	ret := p.realParse()
+	p.Root.UpdateRange()
+` + g.trivia(`	AttachTrivia(&p.Root, p.Trivia.Children)
+`) + `	return ret
+}
+`
+	}
+	g.output += `
 func (p *` + g.s.Name + `) Data(start, end int) string {
 	return p.ParserData.Substring(start, end)
 }
 
 func (p *` + g.s.Name + `) Error() Error {
-	errstr := ""
-	line, column := p.ParserData.LineCol(p.LastError)
+	return p.Expected.Error(p.ParserData, p.LastError)
+}
 
-	if p.LastError == p.ParserData.Len() {
-		errstr = "Unexpected EOF"
-	} else {
-		p.ParserData.Seek(p.LastError)
-		if r := p.ParserData.Read(); r == '\r' || r == '\n' {
-			errstr = "Unexpected new line"
-		} else {
-			errstr = "Unexpected " + string(r)
-		}
-	}
-	return NewError(line, column, errstr)
+func (p *` + g.s.Name + `) Errors() []Error {
+	return RecoveredErrors(&p.Root)
 }
 
 `
 	return nil
 }
 
+// gofmt returns the generated Go code "src" formatted the way gofmt
+// would, or as it is if it doesn't parse so that it can be inspected.
+func gofmt(src string) string {
+	if out, err := format.Source([]byte(src)); err == nil {
+		return string(out)
+	}
+	return src
+}
+
 func (g *GoGenerator) Finish() error {
 	ret := g.output
+	g.cut = false
 	if ret[len(ret)-2:] == "\n\n" {
 		ret = ret[:len(ret)-1]
 	}
@@ -625,9 +1115,19 @@ func (g *GoGenerator) Finish() error {
 	if g.s.FileName == "" {
 		ln = strings.ToLower(g.s.Name)
 	}
-	if err := g.s.WriteFile(ln+".go", ret); err != nil {
+	if err := g.s.WriteFile(ln+".go", gofmt(ret)); err != nil {
 		return err
 	}
+	if g.s.AST {
+		nodeless := make(map[string]bool)
+		for _, action := range g.CustomActions {
+			nodeless[action.Name] = true
+		}
+		ast := "package " + strings.ToLower(g.s.Name) + "\n\nimport (\n\t. \"github.com/jxo/parser\"\n)\n\n" + GenerateAST(g.definitions, nodeless)
+		if err := g.s.WriteFile(ln+"_ast.go", gofmt(ast[:len(ast)-1])); err != nil {
+			return err
+		}
+	}
 
 	dumptree_s := ""
 	heatmap_s := ""
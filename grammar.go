@@ -0,0 +1,88 @@
+package parser
+
+// DefinitionExpression returns the Expression of the peg.Peg
+// Definition node "def".
+func DefinitionExpression(def *Node) *Node {
+	for _, child := range def.Children[1:] {
+		if child.Name == "Expression" {
+			return child
+		}
+	}
+	return nil
+}
+
+// DefinitionRecovery returns the Expression used to recover from a
+// failure of the peg.Peg Definition node "def", or nil if it has none.
+func DefinitionRecovery(def *Node) *Node {
+	for _, child := range def.Children[1:] {
+		if child.Name == "Recovery" {
+			return child.Children[len(child.Children)-1]
+		}
+	}
+	return nil
+}
+
+// DefinitionPrecedence returns the Level nodes of the precedence block
+// of the peg.Peg Definition node "def", ordered from the loosest binding
+// to the tightest, or nil if it has none. Each Level node has the node
+// giving its associativity, named LEFT, RIGHT, NONE, PREFIX or POSTFIX,
+// followed by the Expression matching its operators.
+func DefinitionPrecedence(def *Node) []*Node {
+	for _, child := range def.Children[1:] {
+		if child.Name == "Precedence" {
+			return child.Children
+		}
+	}
+	return nil
+}
+
+// DefinitionParameters returns the names of the parameters of the peg.Peg
+// Definition node "def", or nil if it has none.
+func DefinitionParameters(def *Node) (ret []string) {
+	for _, child := range def.Children[1:] {
+		if child.Name == "Parameters" {
+			for _, param := range child.Children {
+				ret = append(ret, param.Data())
+			}
+		}
+	}
+	return
+}
+
+// SequenceAction returns the Code of the action block ending the peg.Peg
+// Sequence node "seq", or nil if it has none.
+func SequenceAction(seq *Node) *Node {
+	if back := seq.Children[len(seq.Children)-1]; back.Name == "Action" {
+		return back.Children[0]
+	}
+	return nil
+}
+
+// PrefixLabel returns the label of the peg.Peg Prefix node "prefix",
+// or "" if it has none.
+func PrefixLabel(prefix *Node) string {
+	for _, child := range prefix.Children {
+		if child.Name == "Label" {
+			return child.Children[0].Data()
+		}
+	}
+	return ""
+}
+
+// PrefixPredicate returns the AND or NOT node of the peg.Peg Prefix
+// node "prefix", or nil if it has none.
+func PrefixPredicate(prefix *Node) *Node {
+	for _, child := range prefix.Children {
+		if child.Name == "AND" || child.Name == "NOT" {
+			return child
+		}
+	}
+	return nil
+}
+
+// LiteralIgnoresCase reports whether the peg.Peg Literal node "lit" matches
+// its characters ignoring their case, as it does when written with an "i"
+// following it.
+func LiteralIgnoresCase(lit *Node) bool {
+	return lit.Children[len(lit.Children)-1].Name == "IgnoreCase"
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// GrammarImports returns the paths imported by the peg.Peg Grammar node
+// "root", as written in its Import nodes.
+func GrammarImports(root *Node) (ret []string, err error) {
+	for _, node := range root.Children {
+		if node.Name != "Import" {
+			continue
+		}
+		lit := node.Children[len(node.Children)-1]
+		if LiteralIgnoresCase(lit) {
+			return nil, fmt.Errorf("The import %s can't ignore case", lit.Data())
+		}
+		var path []rune
+		for _, char := range lit.Children {
+			r, err := Unescape(char.Data())
+			if err != nil {
+				return nil, fmt.Errorf("The import %s has an %s", lit.Data(), err)
+			}
+			path = append(path, r...)
+		}
+		ret = append(ret, string(path))
+	}
+	return
+}
+
+// ResolveImports returns the peg.Peg Grammar node "root" of the grammar
+// in "file" combined with the grammars it imports, which are loaded with
+// "load". Import paths are relative to the directory of the grammar
+// importing them, and a grammar imported several times is only loaded once.
+//
+// The combined grammar has the definitions of "root" followed by those of
+// the grammars it imports, in the order they are imported in, depth first.
+// Only the first definition of each name is kept, so a grammar overrides
+// the definitions it imports, also where the imported grammar calls them.
+// The Nodes of each Definition keep the DataSource they were parsed from.
+//
+// If "root" doesn't import anything, it's returned as it is.
+func ResolveImports(root *Node, file string, load func(file string) (*Node, error)) (*Node, error) {
+	if imports, err := GrammarImports(root); err != nil {
+		return nil, err
+	} else if len(imports) == 0 {
+		return root, nil
+	} else if load == nil {
+		return nil, fmt.Errorf("The grammar imports %s, but there's no function to load it with", imports[0])
+	}
+	var (
+		ret     = &Node{Name: root.Name, Range: root.Range, P: root.P}
+		defined = make(map[string]bool)
+		loaded  = map[string]bool{filepath.Clean(file): true}
+		add     func(n *Node, file string) error
+	)
+	add = func(n *Node, file string) error {
+		for _, node := range n.Children {
+			if node.Name != "Definition" {
+				continue
+			}
+			if name := node.Children[0].Data(); !defined[name] {
+				defined[name] = true
+				ret.Append(node)
+			}
+		}
+		imports, err := GrammarImports(n)
+		if err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+		for _, path := range imports {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(file), path)
+			}
+			if path = filepath.Clean(path); loaded[path] {
+				continue
+			}
+			loaded[path] = true
+			imported, err := load(path)
+			if err != nil {
+				return err
+			}
+			if err := add(imported, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(root, file); err != nil {
+		return nil, err
+	}
+	for _, node := range root.Children {
+		if node.Name != "Definition" && node.Name != "Import" {
+			ret.Append(node)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,769 @@
+// Package interpreter executes a PEG grammar, as parsed by peg.Peg,
+// directly against input data without generating and compiling a parser
+// first.
+//
+// The Interpreter performs the same steps a parser generated by
+// parser.GoGenerator would, so the Node trees and errors it produces are
+// the same as those of the generated parser.
+package interpreter
+
+import (
+	"fmt"
+	"github.com/jxo/lime/text"
+	. "github.com/jxo/parser"
+	"io"
+	"strings"
+)
+
+// Action selects what the Interpreter does when a definition matches.
+// It's the equivalent of the parser.CustomAction hooks given to a Generator.
+type Action int
+
+const (
+	// AddNode creates a Node named after the definition. This is
+	// what happens for definitions without an explicit Action.
+	AddNode Action = iota
+	// Ignore doesn't create a Node and clips the matched data
+	// from the Range of the enclosing Node, like Generator.Ignore.
+	Ignore
+	// Call just evaluates the definition, like Generator.Call.
+	Call
+)
+
+type (
+	// Interpreter is a Parser running a grammar directly from the
+	// Node tree peg.Peg produces for it.
+	Interpreter struct {
+		ParserData  Reader
+		IgnoreRange text.Region
+		Root        Node
+		LastError   int
+		Expected    Expected
+		// Whether to keep the text of the definitions with the Ignore
+		// Action as the Trivia, like GeneratorSettings.Trivia
+		KeepTrivia bool
+		Trivia     Node
+
+		name      string
+		rules     []*rule
+		memo      Memo
+		backtrack Backtrack
+	}
+
+	rule struct {
+		name     string
+		action   Action
+		exp      expression
+		recovery expression
+		index    int
+		head     bool
+		// Whether the rule looks like spacing, as found by SpacingRules
+		spacing bool
+	}
+
+	expression interface {
+		match(p *Interpreter) bool
+	}
+
+	sequence   []expression
+	choice     []expression
+	zeroOrMore struct{ exp expression }
+	oneOrMore  struct{ exp expression }
+	maybe      struct{ exp expression }
+	assertAnd  struct{ exp expression }
+	assertNot  struct{ exp expression }
+	call       struct{ rule *rule }
+	char       rune
+	literal    []rune
+	charRange  struct{ a, b rune }
+	charSet    []rune
+	anyChar    struct{}
+	// The runes of a literal ignoring case, as returned by FoldRune
+	foldLiteral []rune
+	charClass   struct{ class *CharClass }
+
+	// expect records "item" as Expected when "exp" fails
+	expect struct {
+		exp  expression
+		item string
+	}
+
+	// cut stops parsing with a CutFailure when "exp" fails
+	cut struct{ exp expression }
+
+	// precedence applies the operators of "levels", ordered from the
+	// loosest binding to the tightest, to the operands "operand" matches
+	precedence struct {
+		operand expression
+		levels  []level
+	}
+
+	// level is a level of a precedence block, with "assoc" being the
+	// name of the node giving its associativity
+	level struct {
+		assoc string
+		op    expression
+	}
+
+	// labeled gives the Nodes "exp" creates the label "label"
+	labeled struct {
+		exp   expression
+		label string
+	}
+)
+
+// New creates an Interpreter for the grammar in "grammar", which should be
+// the root node of a peg.Peg parse. "name" is used as the Name of the root
+// Node just like GeneratorSettings.Name, and "actions" maps definition names
+// to the Action to use for them.
+//
+// The first Definition in the grammar is the one parsing starts from. The
+// calls of parameterized definitions are expanded with ExpandTemplates.
+func New(name string, grammar *Node, actions map[string]Action) (*Interpreter, error) {
+	grammar, err := ExpandTemplates(grammar)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err.(*TemplateError).Rule, err)
+	}
+	p := &Interpreter{name: name}
+	byName := make(map[string]*rule)
+	var defs []*Node
+	for _, node := range grammar.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		r := &rule{name: node.Children[0].Data(), action: actions[node.Children[0].Data()], index: len(p.rules)}
+		if _, ok := byName[r.name]; ok {
+			return nil, fmt.Errorf("duplicate definition of %s", r.name)
+		}
+		byName[r.name] = r
+		p.rules = append(p.rules, r)
+		defs = append(defs, node)
+	}
+	if len(p.rules) == 0 {
+		return nil, fmt.Errorf("the grammar doesn't contain any definitions")
+	}
+	for i, node := range defs {
+		exp, err := compile(DefinitionExpression(node), byName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", p.rules[i].name, err)
+		}
+		if levels := DefinitionPrecedence(node); levels != nil {
+			prec := precedence{operand: exp}
+			for _, l := range levels {
+				op, err := compile(l.Children[1], byName)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s", p.rules[i].name, err)
+				}
+				prec.levels = append(prec.levels, level{l.Children[0].Name, op})
+			}
+			exp = prec
+		}
+		p.rules[i].exp = exp
+		if recovery := DefinitionRecovery(node); recovery != nil {
+			if p.rules[i].recovery, err = compile(recovery, byName); err != nil {
+				return nil, fmt.Errorf("%s: %s", p.rules[i].name, err)
+			}
+		}
+	}
+	heads, _ := LeftRecursion(grammar)
+	for _, name := range heads {
+		byName[name].head = true
+	}
+	for name := range SpacingRules(grammar) {
+		byName[name].spacing = true
+	}
+	return p, nil
+}
+
+// compile turns the peg.Peg Node "node" into an expression, following
+// the same structure as the generator helper does.
+func compile(node *Node, rules map[string]*rule) (expression, error) {
+	switch node.Name {
+	case "Expression":
+		if len(node.Children) == 1 {
+			return compile(node.Children[0], rules)
+		}
+		exps := make([]expression, len(node.Children))
+		for i, child := range node.Children {
+			exp, err := compile(child, rules)
+			if err != nil {
+				return nil, err
+			}
+			exps[i] = exp
+		}
+		return choice(exps), nil
+	case "Sequence":
+		children := node.Children
+		if SequenceAction(node) != nil {
+			// Action blocks are Go code, which isn't run
+			children = children[:len(children)-1]
+		}
+		return compileSequence(children, rules)
+	case "Prefix":
+		exp, err := compile(node.Children[len(node.Children)-1], rules)
+		if err != nil {
+			return nil, err
+		}
+		if label := PrefixLabel(node); label != "" {
+			exp = labeled{exp, label}
+		}
+		if pred := PrefixPredicate(node); pred != nil && pred.Name == "AND" {
+			exp = assertAnd{exp}
+		} else if pred != nil {
+			exp = assertNot{exp}
+		}
+		return exp, nil
+	case "Suffix":
+		exp, err := compile(node.Children[0], rules)
+		if err != nil || len(node.Children) == 1 {
+			return exp, err
+		}
+		switch node.Children[len(node.Children)-1].Name {
+		case "PLUS":
+			return oneOrMore{exp}, nil
+		case "STAR":
+			return zeroOrMore{exp}, nil
+		case "QUESTION":
+			return maybe{exp}, nil
+		}
+	case "Primary":
+		front := node.Children[0]
+		if front.Name == "Identifier" {
+			r, ok := rules[front.Data()]
+			if !ok {
+				return nil, fmt.Errorf("undefined rule %s", front.Data())
+			}
+			return call{r}, nil
+		}
+		return compile(front, rules)
+	case "Literal":
+		data := node.Data()
+		if LiteralIgnoresCase(node) {
+			runes, err := Unescape(data[1 : len(data)-2])
+			if err != nil {
+				return nil, err
+			}
+			for i, r := range runes {
+				runes[i] = FoldRune(r)
+			}
+			return expect{foldLiteral(runes), DescribeLiteral(data)}, nil
+		}
+		runes, err := Unescape(data[1 : len(data)-1])
+		if err != nil {
+			return nil, err
+		}
+		if data[0] == '\'' && len(runes) == 1 {
+			return expect{char(runes[0]), DescribeLiteral(data)}, nil
+		}
+		return expect{literal(runes), DescribeLiteral(data)}, nil
+	case "Class":
+		class, err := NewCharClass(node)
+		if err != nil {
+			return nil, err
+		}
+		item := strings.TrimSpace(node.Data())
+		if class.Negated || len(class.Properties) > 0 {
+			return expect{charClass{class}, item}, nil
+		}
+		var (
+			exps []expression
+			set  charSet
+		)
+		for _, r := range class.Ranges {
+			if r.Lo != r.Hi {
+				exps = append(exps, charRange{r.Lo, r.Hi})
+			} else {
+				set = append(set, r.Lo)
+			}
+		}
+		if len(set) > 0 {
+			exps = append(exps, set)
+		}
+		if len(exps) == 1 {
+			return expect{exps[0], item}, nil
+		}
+		return expect{choice(exps), item}, nil
+	case "DOT":
+		return expect{anyChar{}, "any character"}, nil
+	}
+	return nil, fmt.Errorf("unsupported node %s: %s", node.Name, node.Data())
+}
+
+// compileSequence compiles the Prefix and CUT nodes "children"
+// of a Sequence, like the generator helper sequence does.
+func compileSequence(children []*Node, rules map[string]*rule) (expression, error) {
+	for children[len(children)-1].Name == "CUT" {
+		children = children[:len(children)-1]
+	}
+	var exps sequence
+	for i, child := range children {
+		if child.Name == "CUT" {
+			exp, err := compileSequence(children[i+1:], rules)
+			if err != nil {
+				return nil, err
+			}
+			exps = append(exps, cut{exp})
+			break
+		}
+		exp, err := compile(child, rules)
+		if err != nil {
+			return nil, err
+		}
+		exps = append(exps, exp)
+	}
+	if len(exps) == 1 {
+		return exps[0], nil
+	}
+	return exps, nil
+}
+
+func (s sequence) match(p *Interpreter) bool {
+	save := p.ParserData.Pos()
+	for _, exp := range s {
+		if !exp.match(p) {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+			return false
+		}
+	}
+	return true
+}
+
+func (c choice) match(p *Interpreter) bool {
+	save := p.ParserData.Pos()
+	saved := p.backtrack.Save(save)
+	defer p.backtrack.Release(saved)
+	for _, exp := range c {
+		if exp.match(p) {
+			return true
+		}
+	}
+	p.ParserData.Seek(save)
+	return false
+}
+
+func (z zeroOrMore) match(p *Interpreter) bool {
+	for {
+		pos := p.ParserData.Pos()
+		saved := p.backtrack.Save(pos)
+		accept := z.exp.match(p)
+		p.backtrack.Release(saved)
+		if !accept {
+			break
+		} else if pos == p.ParserData.Pos() {
+			// Nothing was consumed, so this would loop forever
+			break
+		}
+	}
+	return true
+}
+
+func (o oneOrMore) match(p *Interpreter) bool {
+	save := p.ParserData.Pos()
+	if !o.exp.match(p) {
+		p.ParserData.Seek(save)
+		return false
+	}
+	zeroOrMore(o).match(p)
+	return true
+}
+
+func (m maybe) match(p *Interpreter) bool {
+	saved := p.backtrack.Save(p.ParserData.Pos())
+	m.exp.match(p)
+	p.backtrack.Release(saved)
+	return true
+}
+
+func (a assertAnd) match(p *Interpreter) bool {
+	s := p.ParserData.Pos()
+	saved := p.backtrack.Save(s)
+	p.Expected.Mute()
+	accept := a.exp.match(p)
+	p.Expected.Unmute()
+	p.backtrack.Release(saved)
+	p.ParserData.Seek(s)
+	p.Root.Discard(s)
+	return accept
+}
+
+func (a assertNot) match(p *Interpreter) bool {
+	return !assertAnd(a).match(p)
+}
+
+func (c cut) match(p *Interpreter) bool {
+	lastError, expected := p.LastError, p.Expected.Commit()
+	p.LastError = 0
+	if !c.exp.match(p) {
+		if p.LastError < p.ParserData.Pos() {
+			p.LastError = p.ParserData.Pos()
+		}
+		panic(CutFailure{})
+	}
+	if p.LastError < lastError {
+		p.LastError = lastError
+	}
+	p.Expected.Restore(expected)
+	return true
+}
+
+func (e precedence) match(p *Interpreter) bool {
+	return e.climb(p, 1)
+}
+
+// climb applies the operators binding at least as tightly as the level
+// "min", like the function the GoGenerator generates for a precedence
+// block does.
+func (e precedence) climb(p *Interpreter, min int) bool {
+	start := p.ParserData.Pos()
+	saved := p.backtrack.Save(start)
+	defer p.backtrack.Release(saved)
+	accept := false
+	for i, l := range e.levels {
+		if l.assoc != "PREFIX" || !e.operator(p, l) {
+			continue
+		}
+		// A prefix operator applies wherever an operand is expected,
+		// binding its operand at least as tightly as its own level
+		level := i + 1
+		if level < min {
+			level = min
+		}
+		if accept = e.climb(p, level); accept {
+			p.wrap(start, "PrefixOp")
+			break
+		}
+		p.ParserData.Seek(start)
+		p.Root.Discard(start)
+	}
+	if !accept && !e.operand.match(p) {
+		return false
+	}
+	last := 0
+loop:
+	for {
+		// Each operator is tried from where the one before it stopped
+		save := p.ParserData.Pos()
+		p.backtrack.Release(saved)
+		saved = p.backtrack.Save(save)
+		for i, l := range e.levels {
+			level := i + 1
+			if l.assoc == "PREFIX" || level < min || l.assoc == "NONE" && last == level || !e.operator(p, l) {
+				continue
+			}
+			if l.assoc == "POSTFIX" {
+				p.wrap(start, "PostfixOp")
+				continue loop
+			}
+			// The right operand of a right associative operator may
+			// apply it again, while for the others it binds tighter
+			next := level + 1
+			if l.assoc == "RIGHT" {
+				next = level
+			}
+			if e.climb(p, next) {
+				p.wrap(start, "BinaryOp")
+				last = level
+				continue loop
+			}
+			p.ParserData.Seek(save)
+			p.Root.Discard(save)
+		}
+		return true
+	}
+}
+
+// operator matches an operator of the level "l", creating a Node for it.
+func (e precedence) operator(p *Interpreter, l level) bool {
+	pos := p.ParserData.Pos()
+	if !l.op.match(p) {
+		p.Root.Discard(pos)
+		return false
+	}
+	p.wrap(pos, "Operator")
+	return true
+}
+
+// wrap moves the Nodes created since "start" to a new Node named "name".
+func (p *Interpreter) wrap(start int, name string) {
+	node := p.Root.Cleanup(start, p.ParserData.Pos())
+	node.Name = name
+	node.P = p
+	node.Range = node.Range.Clip(p.IgnoreRange)
+	p.Root.Append(node)
+}
+
+func (l labeled) match(p *Interpreter) bool {
+	save := p.ParserData.Pos()
+	if !l.exp.match(p) {
+		return false
+	}
+	p.Root.AddLabel(save, p.ParserData.Pos(), l.label)
+	return true
+}
+
+func (c call) match(p *Interpreter) bool {
+	return c.rule.match(p)
+}
+
+func (e expect) match(p *Interpreter) bool {
+	pos := p.ParserData.Pos()
+	if !e.exp.match(p) {
+		p.Expected.Add(pos, e.item)
+		return false
+	}
+	return true
+}
+
+func (c char) match(p *Interpreter) bool {
+	if p.ParserData.Read() != rune(c) {
+		p.ParserData.UnRead()
+		return false
+	}
+	return true
+}
+
+func (l literal) match(p *Interpreter) bool {
+	s := p.ParserData.Pos()
+	for _, r := range l {
+		if p.ParserData.Read() != r {
+			p.ParserData.Seek(s)
+			return false
+		}
+	}
+	return true
+}
+
+func (l foldLiteral) match(p *Interpreter) bool {
+	s := p.ParserData.Pos()
+	for _, r := range l {
+		if FoldRune(p.ParserData.Read()) != r {
+			p.ParserData.Seek(s)
+			return false
+		}
+	}
+	return true
+}
+
+func (c charClass) match(p *Interpreter) bool {
+	if p.ParserData.Pos() >= p.ParserData.Len() {
+		return false
+	}
+	if !c.class.Contains(p.ParserData.Read()) {
+		p.ParserData.UnRead()
+		return false
+	}
+	return true
+}
+
+func (c charRange) match(p *Interpreter) bool {
+	if r := p.ParserData.Read(); r >= c.a && r <= c.b {
+		return true
+	}
+	p.ParserData.UnRead()
+	return false
+}
+
+func (c charSet) match(p *Interpreter) bool {
+	r := p.ParserData.Read()
+	for _, r2 := range c {
+		if r == r2 {
+			return true
+		}
+	}
+	p.ParserData.UnRead()
+	return false
+}
+
+func (anyChar) match(p *Interpreter) bool {
+	if p.ParserData.Pos() >= p.ParserData.Len() {
+		return false
+	}
+	p.ParserData.Read()
+	return true
+}
+
+func (r *rule) match(p *Interpreter) bool {
+	if !r.head {
+		return r.eval(p)
+	}
+	// Grow a seed like the GoGenerator does for left recursive definitions
+	memo, done := p.memo.Enter(r.index)
+	if done {
+		return p.memo.Replay(memo)
+	}
+	saved := p.backtrack.Save(p.ParserData.Pos())
+	for p.memo.Grow(memo, r.eval(p)) {
+	}
+	p.backtrack.Release(saved)
+	return p.memo.Replay(memo)
+}
+
+// recover evaluates the expression of the rule, using its recovery
+// expression to skip ahead if it fails after having consumed input.
+func (r *rule) recover(p *Interpreter) bool {
+	if r.recovery == nil {
+		return r.exp.match(p)
+	}
+	save := p.ParserData.Pos()
+	saved := p.backtrack.Save(save)
+	defer p.backtrack.Release(saved)
+	lastError, expected := p.LastError, p.Expected.Commit()
+	p.LastError = 0
+	accept := r.exp.match(p)
+	if !accept && p.LastError > save {
+		err := p.Error()
+		failed := p.LastError
+		p.ParserData.Seek(save)
+		p.Expected.Mute()
+		accept = r.recovery.match(p)
+		p.Expected.Unmute()
+		p.LastError = failed
+		if accept && p.ParserData.Pos() > save {
+			node := p.Root.Cleanup(save, p.ParserData.Pos())
+			node.Name = "Error"
+			node.P = p
+			node.Value = err
+			p.Root.Append(node)
+		} else {
+			accept = false
+			p.ParserData.Seek(save)
+			p.Root.Discard(save)
+		}
+	}
+	if p.LastError < lastError {
+		p.LastError = lastError
+	}
+	p.Expected.Restore(expected)
+	return accept
+}
+
+func (r *rule) eval(p *Interpreter) bool {
+	start := p.ParserData.Pos()
+	switch r.action {
+	case Call:
+		return r.recover(p)
+	case Ignore:
+		if r.spacing {
+			// What ignored rules like spacing attempt isn't what
+			// the input is expected to contain
+			p.Expected.Mute()
+			defer p.Expected.Unmute()
+		}
+		accept := r.recover(p)
+		if accept && start != p.ParserData.Pos() {
+			if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
+				p.IgnoreRange.A = start
+			}
+			p.IgnoreRange.B = p.ParserData.Pos()
+			if p.KeepTrivia {
+				p.Trivia.AddTrivia(&p.Root, r.name, start, p.ParserData.Pos())
+			}
+		}
+		return accept
+	}
+	mark := p.Expected.Mark(start)
+	entered := p.backtrack.Enter(start)
+	accept := r.recover(p)
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, r.name)
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = r.name
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+		p.Trivia.Discard(start)
+	}
+	p.backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Interpreter) RootNode() *Node {
+	return &p.Root
+}
+
+func (p *Interpreter) SetData(data string) {
+	p.ParserData = NewReader(data)
+	p.Reset()
+}
+
+func (p *Interpreter) Reset() {
+	p.ParserData.Seek(0)
+	p.Root = Node{Name: p.name, P: p}
+	p.IgnoreRange = text.Region{}
+	p.LastError = 0
+	p.Expected = Expected{}
+	p.backtrack.Reset()
+	p.Trivia = Node{Name: "Trivia", P: p}
+	p.memo = Memo{
+		Data:        p.ParserData,
+		Root:        &p.Root,
+		IgnoreRange: &p.IgnoreRange,
+		LastError:   &p.LastError,
+		Expected:    &p.Expected,
+		Entries:     make(map[MemoKey]*MemoEntry),
+	}
+	if p.KeepTrivia {
+		p.memo.Trivia = &p.Trivia
+	}
+}
+
+// parse matches the data from the first definition on, stopping
+// at the first failure following a cut.
+func (p *Interpreter) parse() (accept bool) {
+	defer func() {
+		if IsCutFailure(recover()) {
+			accept = false
+		}
+	}()
+	return p.rules[0].match(p)
+}
+
+func (p *Interpreter) Parse(data string) bool {
+	p.SetData(data)
+	ret := p.parse()
+	p.Root.UpdateRange()
+	if p.KeepTrivia {
+		AttachTrivia(&p.Root, p.Trivia.Children)
+	}
+	return ret
+}
+
+// ParseReader parses the data read from "r", keeping no more of it in
+// memory than a generated parser's ParseReader would.
+func (p *Interpreter) ParseReader(r io.Reader) bool {
+	s := NewStreamReader(r)
+	s.Live = &p.Root
+	s.Backtrack = &p.backtrack
+	if p.KeepTrivia {
+		s.Trivia = &p.Trivia
+	}
+	p.ParserData = s
+	p.Reset()
+	ret := p.parse()
+	p.Root.UpdateRange()
+	if p.KeepTrivia {
+		AttachTrivia(&p.Root, p.Trivia.Children)
+	}
+	return ret
+}
+
+func (p *Interpreter) Data(start, end int) string {
+	return p.ParserData.Substring(start, end)
+}
+
+func (p *Interpreter) Error() Error {
+	return p.Expected.Error(p.ParserData, p.LastError)
+}
+
+func (p *Interpreter) Errors() []Error {
+	return RecoveredErrors(&p.Root)
+}
@@ -0,0 +1,770 @@
+package interpreter
+
+import (
+	"fmt"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func loadGrammar(t *testing.T, data string) *peg.Peg {
+	var p peg.Peg
+	if !p.Parse(data) {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	return &p
+}
+
+func TestInterpretPeg(t *testing.T) {
+	data, err := ioutil.ReadFile("../peg/peg.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := loadGrammar(t, string(data))
+	// These match the custom actions peg.go was generated with
+	actions := map[string]Action{
+		"Spacing":    Ignore,
+		"Space":      Ignore,
+		"EndOfLine":  Ignore,
+		"IdentStart": Call,
+		"IdentCont":  Call,
+		"CodeChunk":  Call,
+		"SLASH":      Ignore,
+		"COLON":      Ignore,
+		"COMMA":      Ignore,
+		"LEFTARROW":  Ignore,
+		"OPEN":       Ignore,
+		"CLOSE":      Ignore,
+		"Comment":    Ignore,
+		"Grammar":    Ignore,
+	}
+	in, err := New("Peg", p.RootNode(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []string{string(data), "A <- 'a' / B\nB <- [a-z\\]]+ !.\n", "A <- (B", "A <- k:B !v:C { return \"}\" }\n", "A <- \"ab\"i 'c'i [^\\p{L}a-z] i <- .\n", "A <- ~ 'a' ~ B / C ~\n", "A <- B %left '+' / '-' %prefix '-'\n%recover C\n"} {
+		var gen peg.Peg
+		a, b := gen.Parse(test), in.Parse(test)
+		if a != b {
+			t.Errorf("Generated parser returned %v, but the interpreter %v for %q", a, b, test)
+		}
+		if a, b := gen.RootNode().String(), in.RootNode().String(); a != b {
+			t.Errorf("Trees differ for %q\nGenerated: %s\nInterpreted: %s", test, a, b)
+		}
+		if a, b := gen.Error().Error(), in.Error().Error(); a != b {
+			t.Errorf("Errors differ for %q: %s != %s", test, a, b)
+		}
+	}
+}
+
+func TestInterpret(t *testing.T) {
+	tests := []struct {
+		grammar, in, out string
+	}{
+		{
+			"Sum <- Value ('+' Value)* !.\nValue <- [0-9]+\n",
+			"1+23",
+			`0-4: "Test"
+	0-4: "Sum"
+		0-1: "Value" - Data: "1"
+		2-4: "Value" - Data: "23"
+`,
+		},
+		{
+			"List <- '[' Spacing? Item (',' Spacing? Item)* ']'\nItem <- \"åäö\" / [\\u0041-\\u005a]\nSpacing <- [ \\t]+\n",
+			"[åäö, X,åäö]",
+			`0-18: "Test"
+	0-18: "List"
+		1-7: "Item" - Data: "åäö"
+		9-10: "Item" - Data: "X"
+		11-17: "Item" - Data: "åäö"
+`,
+		},
+		{
+			"Pair <- 'x' Spacing Name Name !.\nName <- [a-z]+ Spacing?\nSpacing <- [ :]+\n",
+			"x:ab cd",
+			`0-7: "Test"
+	0-7: "Pair"
+		2-4: "Name" - Data: "ab"
+		5-7: "Name" - Data: "cd"
+`,
+		},
+		{
+			"Sum <- Value ('+' Value)* !. { return len(c) }\nValue <- [0-9]+ { return \"}\" }\n",
+			"1+23",
+			`0-4: "Test"
+	0-4: "Sum"
+		0-1: "Value" - Data: "1"
+		2-4: "Value" - Data: "23"
+`,
+		},
+	}
+	for _, test := range tests {
+		p := loadGrammar(t, test.grammar)
+		in, err := New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !in.Parse(test.in) {
+			t.Errorf("Didn't parse %q correctly: %s", test.in, in.Error())
+		} else if s := in.RootNode().String(); s != test.out {
+			t.Errorf("Unexpected tree for %q\n%s", test.in, s)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	p := loadGrammar(t, "Pair <- key:Name? '=' value:Item (',' value:Item)* !.\nName <- [a-z]+\nItem <- Name / [0-9]+\n")
+	in, err := New("Test", p.RootNode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Parse("=1,b") {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if s, want := in.RootNode().String(), `0-4: "Test"
+	0-4: "Pair"
+		0-0: key:"key" - Data: ""
+		1-2: value:"Item" - Data: "1"
+		3-4: value:"Item"
+			3-4: "Name" - Data: "b"
+`; s != want {
+		t.Errorf("Unexpected tree\n%s", s)
+	}
+	if !in.Parse("a=1") {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	pair := in.RootNode().Children[0]
+	if key := pair.Labeled("key"); key == nil || key.Name != "Name" || key.Data() != "a" {
+		t.Errorf("Expected the key to be the Name a, got %v", key)
+	}
+	if values := pair.AllLabeled("value"); len(values) != 1 || values[0].Data() != "1" {
+		t.Errorf("Expected a single value 1, got %v", values)
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	p := loadGrammar(t, `File    <- Spacing Entry* !.
+Entry   <- Key Spacing '=' Spacing Value Spacing
+Key     <- [a-z]+
+Value   <- [0-9]+
+Spacing <- ([ \t\n] / Comment)*
+Comment <- '#' (!'\n' .)*
+`)
+	in, err := New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore, "Comment": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.KeepTrivia = true
+	data := "# head\na = 1 # one\n  b = 2\nc = 3\n"
+	if !in.Parse(data) {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	var trivia []string
+	for _, n := range in.Trivia.Children {
+		trivia = append(trivia, fmt.Sprintf("%s %q", n.Name, n.Data()))
+	}
+	if s, want := strings.Join(trivia, ", "), `Spacing "# head\n", Spacing " ", Spacing " ", Spacing " # one\n  ", Spacing " ", Spacing " ", Spacing "\n", Spacing " ", Spacing " ", Spacing "\n"`; s != want {
+		t.Errorf("Unexpected trivia %s", s)
+	}
+
+	entries := in.RootNode().Children[0].Children
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	a, b, c := entries[0], entries[1], entries[2]
+	if r := a.Extent(); data[r.Begin():r.End()] != "# head\na = 1 # one\n" {
+		t.Errorf("Unexpected extent %q", data[r.Begin():r.End()])
+	}
+	if r := b.Extent(); data[r.Begin():r.End()] != "  b = 2\n" {
+		t.Errorf("Unexpected extent %q", data[r.Begin():r.End()])
+	}
+
+	rw := NewRewriter(data)
+	rw.Delete(b)
+	rw.Replace(a.Children[1], "42")
+	rw.InsertBefore(c, "d = 4\n")
+	if s, err := rw.Apply(); err != nil {
+		t.Error(err)
+	} else if want := "# head\na = 42 # one\nd = 4\nc = 3\n"; s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+	rw.Replace(a, "e = 5")
+	if _, err := rw.Apply(); err == nil {
+		t.Error("Expected an error for overlapping edits")
+	}
+}
+
+func TestSpacing(t *testing.T) {
+	p := loadGrammar(t, `File    <- Spacing? Pair (Comma Pair)* !.
+Pair    <- Name Equals Name
+Comma   <- ',' Spacing?
+Equals  <- '=' Spacing?
+Name    <- [a-z]+ Spacing?
+Spacing <- [ \t]+ / Comment
+Comment <- '#' [a-z]*
+`)
+	if s := fmt.Sprint(SpacingRules(p.RootNode())); s != "map[Spacing:true]" {
+		t.Errorf("Unexpected spacing rules %s", s)
+	}
+	in, err := New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore, "Comma": Ignore, "Equals": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What the ignored spacing attempts is left out of
+	// the errors, unlike what the other ignored rules do
+	for _, test := range []struct {
+		in, err string
+	}{
+		{"a = b c", `1,7: expected "," but found "c"`},
+		{"a#x b", `1,4: expected "=" but found " "`},
+	} {
+		if in.Parse(test.in) {
+			t.Errorf("Expected %q not to parse", test.in)
+		} else if err := in.Error().Error(); err != test.err {
+			t.Errorf("Unexpected error %q for %q", err, test.in)
+		}
+	}
+}
+
+func TestUndefinedRule(t *testing.T) {
+	p := loadGrammar(t, "A <- B\n")
+	if _, err := New("Test", p.RootNode(), nil); err == nil {
+		t.Error("Expected an error for the undefined rule B")
+	}
+}
+
+func TestLeftRecursion(t *testing.T) {
+	p := loadGrammar(t, `Expr    <- Sum !.
+Sum     <- Sum '+' Product / Sum '-' Product / Product
+Product <- Product '*' Postfix / Postfix
+Postfix <- Call / Member / Value
+Call    <- Postfix '(' ')'
+Member  <- Postfix '.' Value
+Value   <- [0-9a-z]+ / '(' Sum ')'
+`)
+	heads, involved := LeftRecursion(p.RootNode())
+	if a, b := fmt.Sprint(heads, involved), "[Sum Product Postfix] [Call Member]"; a != b {
+		t.Errorf("Expected %s, got %s", b, a)
+	}
+	in, err := New("Test", p.RootNode(), map[string]Action{"Postfix": Call})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Parse("1-2+a.b()*3") {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if s, want := in.RootNode().String(), `0-11: "Test"
+	0-11: "Expr"
+		0-11: "Sum"
+			0-3: "Sum"
+				0-1: "Sum"
+					0-1: "Product"
+						0-1: "Value" - Data: "1"
+				2-3: "Product"
+					2-3: "Value" - Data: "2"
+			4-11: "Product"
+				4-9: "Product"
+					4-9: "Call"
+						4-7: "Member"
+							4-5: "Value" - Data: "a"
+							6-7: "Value" - Data: "b"
+				10-11: "Value" - Data: "3"
+`; s != want {
+		t.Errorf("Unexpected tree\n%s", s)
+	}
+}
+
+func TestImports(t *testing.T) {
+	files := map[string]string{
+		"lib/common.peg": `%import "../extra.peg"
+List      <- Item (',' Spacing Item)*
+Item      <- Word Spacing
+Spacing   <- [ \t\n]*
+EndOfFile <- !.
+`,
+		"extra.peg": `Word      <- [a-z]+
+EndOfFile <- 'never'
+`,
+	}
+	var loaded []string
+	load := func(file string) (*Node, error) {
+		loaded = append(loaded, file)
+		if data, ok := files[file]; ok {
+			return loadGrammar(t, data).RootNode(), nil
+		}
+		return nil, fmt.Errorf("%s doesn't exist", file)
+	}
+	p := loadGrammar(t, `%import "lib/common.peg"
+%import "extra.peg"
+File    <- Spacing List EndOfFile
+Spacing <- ' '*
+`)
+	root, err := ResolveImports(p.RootNode(), "main.peg", load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := fmt.Sprint(loaded), "[lib/common.peg extra.peg]"; a != b {
+		t.Errorf("Expected to load %s, loaded %s", b, a)
+	}
+	var names []string
+	for _, def := range root.Children {
+		if def.Name == "Definition" {
+			names = append(names, def.Children[0].Data()+" <- "+strings.TrimSpace(DefinitionExpression(def).Data()))
+		}
+	}
+	if a, b := strings.Join(names, "\n"), `File <- Spacing List EndOfFile
+Spacing <- ' '*
+List <- Item (',' Spacing Item)*
+Item <- Word Spacing
+EndOfFile <- !.
+Word <- [a-z]+`; a != b {
+		t.Errorf("Expected the definitions %s, got %s", b, a)
+	}
+	in, err := New("Test", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Parse("a, bc") {
+		t.Errorf("Didn't parse correctly: %s", in.Error())
+	}
+	// The overridden Spacing is also called by the imported definitions
+	if in.Parse("a,\nbc") {
+		t.Error("Expected the line break not to be accepted as spacing")
+	}
+
+	files["lib/common.peg"] = `%import "missing.peg"
+` + files["lib/common.peg"]
+	if _, err := ResolveImports(p.RootNode(), "main.peg", load); err == nil || err.Error() != "lib/missing.peg doesn't exist" {
+		t.Errorf("Expected the missing import to be reported, got %v", err)
+	}
+	if _, err := ResolveImports(p.RootNode(), "main.peg", nil); err == nil {
+		t.Error("Expected an error resolving imports without a load function")
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	p := loadGrammar(t, `File        <- Bracketed(List(Pair, ',' Spacing)) !.
+Pair        <- Key Spacing? '=' Spacing? Key
+Key         <- [a-z]+
+Spacing     <- ' '+
+List(x, sep) <- x (sep x)*
+Bracketed(x) <- '[' x? ']'
+`)
+	in, err := New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Parse("[a=b, c = d]") {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if s, want := in.RootNode().String(), `0-12: "Test"
+	0-12: "File"
+		1-4: "Pair"
+			1-2: "Key" - Data: "a"
+			3-4: "Key" - Data: "b"
+		6-11: "Pair"
+			6-7: "Key" - Data: "c"
+			10-11: "Key" - Data: "d"
+`; s != want {
+		t.Errorf("Unexpected tree\n%s", s)
+	}
+
+	for _, test := range []struct {
+		grammar, err string
+	}{
+		{"A <- List('a')\nList(x, sep) <- x (sep x)*\n", "A: List takes 2 arguments, but is called with 1"},
+		{"A <- List\nList(x) <- x+\n", "A: List has parameters, but is called without arguments"},
+		{"A <- B('a')\nB <- 'b'\n", "A: B doesn't have parameters, but is called with arguments"},
+		{"A <- B('a')\nB(x) <- x C(x)\nC(y) <- B(y)\n", "C: B calls itself, which parameterized definitions can't"},
+	} {
+		if _, err := New("Test", loadGrammar(t, test.grammar).RootNode(), nil); err == nil || err.Error() != test.err {
+			t.Errorf("Expected the error %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	p := loadGrammar(t, `Query   <- SELECT Name (',' Spacing Name)* "from"i Spacing Name Rest !.
+SELECT  <- "select"i !Ident Spacing
+Name    <- [\p{L}_] Ident* Spacing
+Ident   <- [\p{L}\p{Nd}_]
+Rest    <- [^;]* ';'
+Spacing <- ' '*
+`)
+	in, err := New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		in string
+		ok bool
+	}{
+		{"select a from b;", true},
+		{"SeLeCt naïve, größe from Ωmega٣ where 1 ≠ 2;", true},
+		{"ſELECT a FROM b;", true},
+		{"selectx a from b;", false},
+		{"select 1 from b;", false},
+		{"select a from b", false},
+		{"select a€ from b;", false},
+	} {
+		if ok := in.Parse(test.in); ok != test.ok {
+			t.Errorf("Parsing %q returned %v, expected %v: %s", test.in, ok, test.ok, in.Error())
+		}
+	}
+	in.Parse("select a frum b;")
+	if s, want := in.Error().Error(), `1,10: expected "," or "from"i but found "f"`; s != want {
+		t.Errorf("Expected the error %q, got %q", want, s)
+	}
+
+	if _, err := New("Test", loadGrammar(t, "A <- [\\p{Letter}]\n").RootNode(), nil); err == nil || err.Error() != `A: \p{Letter} isn't a Unicode general category or script` {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestCut(t *testing.T) {
+	p := loadGrammar(t, `Program   <- Statement* !.
+Statement <- Name '=' Name ';'
+           / "while" ~ '(' Name ')' Statement
+           / "print" ~ ' ' Name ';'
+Name      <- [a-z]+
+`)
+	in, err := New("Test", p.RootNode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		in, err string
+	}{
+		{"print x;while(x)print y;x=y;", ""},
+		{"printx=y;", ""},
+		{"print x;while(x print y;", `1,16: expected [a-z] or ")" but found " "`},
+		// The failure following the cut is reported, even
+		// though the assignment got further
+		{"print x;printx;", `1,14: expected " " but found "x"`},
+	} {
+		ok := in.Parse(test.in)
+		if ok != (test.err == "") {
+			t.Errorf("Parsing %q returned %v", test.in, ok)
+		} else if !ok && in.Error().Error() != test.err {
+			t.Errorf("Expected the error %q for %q, got %q", test.err, test.in, in.Error())
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	p := loadGrammar(t, `Program <- Spacing Expr !.
+Expr    <- Value / '(' Spacing Expr ')' Spacing
+           %none '<' Spacing
+           %left ('+' / '-') Spacing
+           %right '^' Spacing
+           %prefix '-' Spacing
+           %postfix '!' Spacing
+Value   <- [0-9]+ Spacing
+Spacing <- ' '*
+`)
+	in, err := New("Test", p.RootNode(), map[string]Action{"Program": Call, "Spacing": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		in, out string
+	}{
+		{"1 - 2 - 3", `0-9: "Test"
+	0-9: "Expr"
+		0-9: "BinaryOp"
+			0-5: "BinaryOp"
+				0-1: "Value" - Data: "1"
+				2-3: "Operator" - Data: "-"
+				4-5: "Value" - Data: "2"
+			6-7: "Operator" - Data: "-"
+			8-9: "Value" - Data: "3"
+`},
+		{"2^3^4", `0-5: "Test"
+	0-5: "Expr"
+		0-5: "BinaryOp"
+			0-1: "Value" - Data: "2"
+			1-2: "Operator" - Data: "^"
+			2-5: "BinaryOp"
+				2-3: "Value" - Data: "3"
+				3-4: "Operator" - Data: "^"
+				4-5: "Value" - Data: "4"
+`},
+		{"-2^3 !", `0-6: "Test"
+	0-6: "Expr"
+		0-6: "BinaryOp"
+			0-2: "PrefixOp"
+				0-1: "Operator" - Data: "-"
+				1-2: "Value" - Data: "2"
+			2-3: "Operator" - Data: "^"
+			3-6: "PostfixOp"
+				3-4: "Value" - Data: "3"
+				5-6: "Operator" - Data: "!"
+`},
+		{"(1) < 2", `0-7: "Test"
+	0-7: "Expr"
+		0-7: "BinaryOp"
+			1-2: "Expr"
+				1-2: "Value" - Data: "1"
+			4-5: "Operator" - Data: "<"
+			6-7: "Value" - Data: "2"
+`},
+	} {
+		if !in.Parse(test.in) {
+			t.Errorf("Didn't parse %q correctly: %s", test.in, in.Error())
+		} else if out := in.RootNode().String(); out != test.out {
+			t.Errorf("Expected\n%s\nfor %q, got\n%s", test.out, test.in, out)
+		}
+	}
+	// The operators of a non-associative level can't follow each other
+	if in.Parse("1 < 2 < 3") {
+		t.Error("Expected \"1 < 2 < 3\" not to parse")
+	} else if err := in.Error().Error(); err != `1,7: expected "+", "-", "^" or "!" but found "<"` {
+		t.Errorf("Unexpected error %q", err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	p := loadGrammar(t, `File      <- Spacing Statement* !.
+Statement <- Name '=' Value ';' Spacing %recover (!';' .)* ';' Spacing
+Name      <- [a-z]+
+Value     <- [0-9]+
+Spacing   <- [ \n]*
+`)
+	in, err := New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Parse("a=1;\nb=x;\nc=3;\nd=;\n") {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if s, want := in.RootNode().String(), `0-19: "Test"
+	0-19: "File"
+		0-4: "Statement"
+			0-1: "Name" - Data: "a"
+			2-3: "Value" - Data: "1"
+		5-10: "Statement"
+			5-10: "Error" - Data: "b=x;
+"
+		10-14: "Statement"
+			10-11: "Name" - Data: "c"
+			12-13: "Value" - Data: "3"
+		15-19: "Statement"
+			15-19: "Error" - Data: "d=;
+"
+`; s != want {
+		t.Errorf("Unexpected tree\n%s", s)
+	}
+	if s := fmt.Sprint(in.Errors()); s != `[2,3: expected Value but found "x" 4,3: expected Value but found ";"]` {
+		t.Errorf("Unexpected errors: %s", s)
+	}
+
+	// Recovery fails when there's nothing to synchronize on
+	if in.Parse("a=1;\nb=x") {
+		t.Error("Expected the parse to fail")
+	}
+	if s := in.Error().Error(); s != `2,3: expected Value but found "x"` || len(in.Errors()) != 0 {
+		t.Errorf("Unexpected errors: %s, %v", s, in.Errors())
+	}
+
+	// The errors recovered from in an alternative that was given up on
+	// are dropped along with its Nodes, and each error only describes
+	// what its definition attempted
+	p = loadGrammar(t, "S <- Stmt 'x' / Stmt 'y'\nStmt <- 'a' 'b' %recover (!'x' !'y' .)*\n")
+	if in, err = New("Test", p.RootNode(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !in.Parse("acy") {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if s := fmt.Sprint(in.Errors()); s != `[1,2: expected "b" but found "c"]` {
+		t.Errorf("Unexpected errors: %s", s)
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	p := loadGrammar(t, `File   <- Record* !.
+Record <- 'A' Digit Digit '\n' / "AB" Digit '\n' / Blank
+Digit  <- [0-9]
+Blank  <- '\n'
+`)
+	if n, _ := Lookback(p.RootNode()); n != 10 {
+		t.Errorf("Expected a lookback of 10, got %d", n)
+	}
+	in, err := New("Test", p.RootNode(), map[string]Action{"Digit": Call, "Blank": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Repeat("A12\nAB3\n\n", 20000)
+	if !in.Parse(data) {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	want := in.RootNode().String()
+	if !in.ParseReader(iotest.OneByteReader(strings.NewReader(data))) {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if got := in.RootNode().String(); got != want {
+		t.Error("Expected the same tree as when parsing a string")
+	}
+	checkStreamed(t, in, data)
+	if line, column := in.ParserData.LineCol(len(data) - 2); line != 59999 || column != 4 {
+		t.Errorf("Expected 59999,4, got %d,%d", line, column)
+	}
+
+	if in.ParseReader(strings.NewReader(data + "AB")) {
+		t.Error("Expected parsing to fail")
+	} else if a, b := in.Error().Error(), `60001,3: expected [0-9] but found EOF`; a != b {
+		t.Errorf("Expected %s, got %s", b, a)
+	}
+
+	p = loadGrammar(t, `File  <- Line* !.
+Line  <- [a-z]* '\n'
+`)
+	if n, rule := Lookback(p.RootNode()); n != -1 || rule != "File" {
+		t.Errorf("Expected the lookback of File to be unbounded, got %d %s", n, rule)
+	}
+}
+
+// checkStreamed checks that the Nodes parsed by "in" from a reader
+// return the "data" they cover, and that reading it didn't fail.
+func checkStreamed(t *testing.T, in *Interpreter, data string) {
+	t.Helper()
+	var check func(n *Node) bool
+	check = func(n *Node) bool {
+		if len(n.Children) == 0 {
+			if r := n.Range; n.Data() != data[r.Begin():r.End()] {
+				t.Errorf("Expected the %s at %v to return its data, got %d bytes of %d", n.Name, r, len(n.Data()), r.Size())
+				return false
+			}
+		}
+		for _, child := range n.Children {
+			if !check(child) {
+				return false
+			}
+		}
+		return true
+	}
+	check(in.RootNode())
+	check(&in.Trivia)
+	if err := in.ParserData.(*StreamReader).Err(); err != nil {
+		t.Errorf("Unexpected error reading the data: %s", err)
+	}
+}
+
+func TestParseReaderData(t *testing.T) {
+	p := loadGrammar(t, `File <- Word ' ' Word !.
+Word <- [a-z]+
+`)
+	in, err := New("Test", p.RootNode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Words shorter than what's buffered, and longer than what's
+	// read at a time
+	for _, n := range []int{10, 40000} {
+		data := strings.Repeat("a", n) + " " + strings.Repeat("b", n)
+		if !in.ParseReader(strings.NewReader(data)) {
+			t.Fatalf("Didn't parse correctly: %s", in.Error())
+		}
+		checkStreamed(t, in, data)
+	}
+
+	p = loadGrammar(t, `File    <- Spacing Entry* !.
+Entry   <- Key Spacing '=' Spacing Value Spacing
+Key     <- [a-z]+
+Value   <- [0-9]+
+Spacing <- ([ \t\n] / Comment)*
+Comment <- '#' (!'\n' .)*
+`)
+	in, err = New("Test", p.RootNode(), map[string]Action{"Spacing": Ignore, "Comment": Ignore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.KeepTrivia = true
+	data := "# head\n" + strings.Repeat("a = 1 # one\n  b = 2\n", 5000)
+	// trivia describes the trivia attached to the Nodes
+	trivia := func() string {
+		var (
+			ret  []string
+			walk func(n *Node)
+		)
+		walk = func(n *Node) {
+			if n.Leading.Size() > 0 || n.Trailing.Size() > 0 {
+				ret = append(ret, fmt.Sprint(n.Name, n.Leading, n.Trailing))
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(in.RootNode())
+		return strings.Join(ret, ", ")
+	}
+	if !in.Parse(data) {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	want := trivia()
+	if want == "" {
+		t.Fatal("Expected trivia to be attached")
+	}
+	if !in.ParseReader(strings.NewReader(data)) {
+		t.Fatalf("Didn't parse correctly: %s", in.Error())
+	}
+	if got := trivia(); got != want {
+		t.Error("Expected the same trivia as when parsing a string")
+	}
+	checkStreamed(t, in, data)
+}
+
+// bufferWatcher is an io.Reader noting the largest
+// buffer of the StreamReader of "in" reading from it.
+type bufferWatcher struct {
+	r   io.Reader
+	in  *Interpreter
+	max int
+}
+
+func (w *bufferWatcher) Read(b []byte) (int, error) {
+	if s, ok := w.in.ParserData.(*StreamReader); ok && s.Buffered() > w.max {
+		w.max = s.Buffered()
+	}
+	return w.r.Read(b)
+}
+
+func TestParseReaderBuffer(t *testing.T) {
+	for _, test := range []struct {
+		file, data string
+	}{
+		{"../json/json.peg", strings.Repeat(`{"name": "a \"b\"", "values": [1, -2.5e3, true, null, {}]},`+"\n", 20000) + "[]"},
+		{"../ini/ini.peg", "; comment\n" + strings.Repeat("[section]\nkey=value\nother = 1\n", 20000)},
+	} {
+		grammar, err := ioutil.ReadFile(test.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in, err := New("Test", loadGrammar(t, string(grammar)).RootNode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !in.Parse(test.data) {
+			t.Fatalf("Didn't parse correctly: %s", in.Error())
+		}
+		want := in.RootNode().String()
+		w := bufferWatcher{r: strings.NewReader(test.data), in: in}
+		if !in.ParseReader(&w) {
+			t.Fatalf("Didn't parse %s correctly: %s", test.file, in.Error())
+		}
+		if got := in.RootNode().String(); got != want {
+			t.Errorf("Expected the same tree for %s as when parsing a string", test.file)
+		}
+		checkStreamed(t, in, test.data)
+		// The data is read in chunks of 32 KiB, and dropped from
+		// the buffer when it's no longer needed
+		if w.max > 128<<10 {
+			t.Errorf("Expected the buffer for %s to stay bounded, got %d bytes of %d", test.file, w.max, len(test.data))
+		}
+	}
+}
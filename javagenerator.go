@@ -30,6 +30,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 type JavaGenerator struct {
@@ -43,12 +44,20 @@ type JavaGenerator struct {
 	havefunctions   bool
 	currentName     string
 	saveCount       int
+	// The property tables added to the output, along with the
+	// runtime code for characters once it's been added
+	tables map[string]bool
+	cut    bool
 }
 
 func (g *JavaGenerator) SetCustomActions(actions []CustomAction) {
 	g.CustomActions = actions
 }
 
+func (g *JavaGenerator) SetCut() {
+	g.cut = true
+}
+
 func (g *JavaGenerator) AddNode(data, defName string) string {
 	return `accept = true;
 int start = parserData.pos;
@@ -72,7 +81,7 @@ func (g *JavaGenerator) Ignore(data string) string {
 int start = parserData.pos;
 ` + g.Call(data) + `
 if (accept && start != parserData.pos) {
-	if (start < ignoreRange.start || ignoreRange.start == 0) {
+	if (start < ignoreRange.start || start > ignoreRange.end || ignoreRange.start == 0) {
 		ignoreRange.start = start;
 	}
 	ignoreRange.end = parserData.pos;
@@ -81,15 +90,22 @@ if (accept && start != parserData.pos) {
 }
 func (g *JavaGenerator) MakeParserFunction(node *Node) error {
 	id := node.Children[0]
-	exp := node.Children[len(node.Children)-1]
+	exp := DefinitionExpression(node)
 	defName := helper(g, id)
 	g.currentName = defName
 	g.saveCount = 0
 	data := helper(g, exp)
+	if levels := DefinitionPrecedence(node); levels != nil {
+		data = g.Precedence(data, precedenceLevels(g, levels))
+	}
 
 	if !g.havefunctions {
 		g.havefunctions = true
-		g.output += "private boolean realParse() {\n\treturn " + defName + "();\n}\n"
+		if g.cut {
+			g.output += "private boolean realParse() {\n\ttry {\n\t\treturn " + defName + "();\n\t} catch (CutFailure e) {\n\t\treturn false;\n\t}\n}\n"
+		} else {
+			g.output += "private boolean realParse() {\n\treturn " + defName + "();\n}\n"
+		}
 	}
 
 	indenter := CodeFormatter{}
@@ -129,87 +145,56 @@ func (g *JavaGenerator) MakeParserCall(value string) string {
 }
 
 func (g *JavaGenerator) CheckInRange(a, b string) string {
-	return `if (parserData.pos >= parserData.data.length) {
-	accept = false;
-} else {
-	byte c = parserData.data[parserData.pos];
-	if (c >= '` + a + `' && c <= '` + b + `') {
-		parserData.pos++;
-		accept = true;
-	} else {
-		accept = false;
-	}
-}`
+	lo, _ := Unescape(a)
+	hi, _ := Unescape(b)
+	return g.CheckClass(&CharClass{Ranges: []RuneRange{{lo[0], hi[0]}}})
 }
 
 func (g *JavaGenerator) CheckInSet(a string) string {
-	a = strings.Replace(a, "\\[", "[", -1)
-	a = strings.Replace(a, "\\]", "]", -1)
-	tests := ""
-	for i := 0; i < len(a); i++ {
-		if len(tests) > 0 {
-			tests += " || "
-		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-
-		tests += "c == '" + c2 + "'"
-	}
-	return `{
-	accept = false;
-	if (parserData.pos < parserData.data.length) {
-		byte c =  parserData.data[parserData.pos];
-		if (` + tests + `) {
-			parserData.pos++;
-			accept = true;
-		}
+	runes, _ := Unescape(a)
+	class := &CharClass{}
+	for _, r := range runes {
+		class.Ranges = append(class.Ranges, RuneRange{r, r})
 	}
-}`
+	return g.CheckClass(class)
 }
 
 func (g *JavaGenerator) CheckAnyChar() string {
-	return `if (parserData.pos >= parserData.data.length) {
-	accept = false;
-} else {
-	parserData.pos++;
-	accept = true;
+	g.table(nil)
+	return `{
+	decodeRune(parserData.pos);
+	accept = runeSize > 0;
+	parserData.pos += runeSize;
 }`
 }
 
 func (g *JavaGenerator) CheckNext(a string) string {
-	if a[0] == '\'' {
-		return `if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != ` + a + `) {
+	runes, _ := Unescape(a[1 : len(a)-1])
+	for _, r := range runes {
+		if r >= utf8.RuneSelf {
+			// The characters are decoded from the input and compared
+			// as code points, as for a literal ignoring case
+			sets := make([][]rune, len(runes))
+			for i, r := range runes {
+				sets[i] = []rune{r}
+			}
+			return g.checkRunes(sets)
+		}
+	}
+	if len(runes) == 1 {
+		return `if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != ` + cRune(runes[0]) + `) {
 	accept = false;
 } else {
 	parserData.pos++;
 	accept = true;
 }`
 	}
-	a = a[1 : len(a)-1]
 	tests := ""
-	pos := 0
-	for i := 0; i < len(a); i, pos = i+1, pos+1 {
+	for i, r := range runes {
 		if len(tests) > 0 {
 			tests += " || "
 		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "\\\"" {
-			c2 = "\""
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-		tests += fmt.Sprintf("parserData.data[parserData.pos + %d] != '%s'", pos, c2)
+		tests += fmt.Sprintf("parserData.data[parserData.pos + %d] != %s", i, cRune(r))
 	}
 	mysave := fmt.Sprintf("save%d", g.saveCount)
 	g.saveCount++
@@ -230,7 +215,271 @@ func (g *JavaGenerator) CheckNext(a string) string {
 	if (accept) {
 		parserData.pos += %d;
 	}
-}`, pos, tests, pos)
+}`, len(runes), tests, len(runes))
+}
+
+// javaUnicode is the code decoding the UTF-8 characters of the input,
+// added to the parsers with negated or Unicode classes or literals
+// ignoring case.
+const javaUnicode = `
+    int runeSize;
+
+    // Decodes the character at "pos", setting runeSize to its length,
+    // which is 1 for 0xFFFD if it isn't valid UTF-8. At the end of the
+    // data, -1 is returned and runeSize is 0.
+    int decodeRune(int pos) {
+        byte data[] = parserData.data;
+        if (pos >= data.length) {
+            runeSize = 0;
+            return -1;
+        }
+        int c = data[pos] & 0xFF;
+        int n;
+        runeSize = 1;
+        if (c < 0x80) {
+            return c;
+        } else if ((c & 0xE0) == 0xC0) {
+            n = 2;
+            c &= 0x1F;
+        } else if ((c & 0xF0) == 0xE0) {
+            n = 3;
+            c &= 0x0F;
+        } else if ((c & 0xF8) == 0xF0) {
+            n = 4;
+            c &= 0x07;
+        } else {
+            return 0xFFFD;
+        }
+        if (data.length - pos < n) {
+            return 0xFFFD;
+        }
+        for (int i = 1; i < n; i++) {
+            if ((data[pos+i] & 0xC0) != 0x80) {
+                return 0xFFFD;
+            }
+            c = (c << 6) | (data[pos+i] & 0x3F);
+        }
+        if (c < (n == 2 ? 0x80 : n == 3 ? 0x800 : 0x10000) || c > 0x10FFFF || (c >= 0xD800 && c <= 0xDFFF)) {
+            return 0xFFFD;
+        }
+        runeSize = n;
+        return c;
+    }
+
+    // Returns whether "c" is in the sorted ranges of "table".
+    static boolean inTable(int table[], int c) {
+        int lo = 0, hi = table.length/2;
+        while (lo < hi) {
+            int m = (lo+hi)/2;
+            if (c < table[2*m]) {
+                hi = m;
+            } else if (c > table[2*m+1]) {
+                lo = m+1;
+            } else {
+                return true;
+            }
+        }
+        return false;
+    }
+`
+
+// table adds the runtime code for characters to the output, along with
+// the table of the Unicode properties of "class" if it has any, returning
+// the name of the table.
+func (g *JavaGenerator) table(class *CharClass) string {
+	if g.tables == nil {
+		g.tables = make(map[string]bool)
+		g.output += javaUnicode
+	}
+	if class == nil || len(class.Properties) == 0 {
+		return ""
+	}
+	name := propertyTableName(class)
+	if !g.tables[name] {
+		g.tables[name] = true
+		g.output += "\n    static final int " + name + "[] = {" + formatTable(class.PropertyTable(), "        ") + "\n    };\n"
+	}
+	return name
+}
+
+func (g *JavaGenerator) CheckClass(class *CharClass) string {
+	var tests []string
+	for _, r := range class.Ranges {
+		if r.Lo == r.Hi {
+			tests = append(tests, "c == "+cRune(r.Lo))
+		} else {
+			tests = append(tests, "(c >= "+cRune(r.Lo)+" && c <= "+cRune(r.Hi)+")")
+		}
+	}
+	if table := g.table(class); table != "" {
+		tests = append(tests, "inTable("+table+", c)")
+	}
+	test := "(" + strings.Join(tests, " || ") + ")"
+	if class.Negated {
+		test = "!" + test
+	}
+	return `{
+	int c = decodeRune(parserData.pos);
+	accept = runeSize > 0 && ` + test + `;
+	if (accept) {
+		parserData.pos += runeSize;
+	}
+}`
+}
+
+func (g *JavaGenerator) CheckNextFold(a []rune) string {
+	sets := make([][]rune, len(a))
+	for i, r := range a {
+		sets[i] = foldOrbit(r)
+	}
+	return g.checkRunes(sets)
+}
+
+// checkRunes returns the code accepting the characters following if each
+// of them is one of the characters in its set of "sets".
+func (g *JavaGenerator) checkRunes(sets [][]rune) string {
+	g.table(nil)
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add("int s = parserData.pos;\nint c;\nruneSize = 0;\naccept = true;\n")
+	for _, set := range sets {
+		var tests []string
+		for _, r := range set {
+			tests = append(tests, "c == "+cRune(r))
+		}
+		cf.Add("if (accept) {\n\ts += runeSize;\n\tc = decodeRune(s);\n\taccept = " + strings.Join(tests, " || ") + ";\n}\n")
+	}
+	cf.Add("if (accept) {\n\tparserData.pos = s + runeSize;\n}")
+	cf.Dec()
+	cf.Add("\n}")
+	return cf.String()
+}
+
+func (g *JavaGenerator) Cut(data string) string {
+	var cf CodeFormatter
+	cf.Add("{\n")
+	cf.Inc()
+	cf.Add(g.Call(data) + "\nif (!accept) {\n\tthrow new CutFailure();\n}\n")
+	cf.Dec()
+	cf.Add("}")
+	return cf.String()
+}
+
+func (g *JavaGenerator) wrap(start, name string) string {
+	return `Node node = Root.Cleanup(` + start + `, parserData.pos);
+node.Name = "` + name + `";
+node.Range.Clip(ignoreRange);
+Root.Append(node);`
+}
+
+func (g *JavaGenerator) Precedence(operand string, levels []PrecedenceLevel) string {
+	name := "precedence_" + g.currentName
+	var cf CodeFormatter
+	cf.Inc()
+	cf.Add("private boolean " + name + `(int min) {
+`)
+	cf.Inc()
+	cf.Add(`// Applies the operators of ` + g.currentName + ` binding at least as tightly
+// as the level "min" to its operands.
+boolean accept = false;
+int start = parserData.pos;
+`)
+	for _, l := range levels {
+		if l.Associativity == "NONE" {
+			// The operators of a non-associative level can't follow
+			// the ones it has just applied
+			cf.Add("int last = 0;\n")
+			break
+		}
+	}
+	// The operator is matched within a block creating a Node for it
+	operator := func(code string) {
+		cf.Add("{\n")
+		cf.Inc()
+		cf.Add("int pos = parserData.pos;\n" + g.Call(code) + "\nif (accept) {\n\t" + strings.Replace(g.wrap("pos", "Operator"), "\n", "\n\t", -1) + "\n} else {\n\tRoot.Discard(pos);\n}\n")
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	prefix, binary := false, false
+	for i, l := range levels {
+		if l.Associativity != "PREFIX" {
+			binary = binary || l.Associativity != "POSTFIX"
+			continue
+		}
+		prefix = true
+		// A prefix operator applies wherever an operand is expected,
+		// binding its operand at least as tightly as its own level
+		cf.Add("if (!accept) {\n")
+		cf.Inc()
+		operator(l.Operator)
+		cf.Add(`if (accept) {
+	int level = ` + fmt.Sprint(i+1) + `;
+	if (level < min) {
+		level = min;
+	}
+	if ((accept = ` + name + `(level))) {
+		` + strings.Replace(g.wrap("start", "PrefixOp"), "\n", "\n\t\t", -1) + `
+	} else {
+		parserData.pos = start;
+		Root.Discard(start);
+	}
+}
+`)
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	if prefix {
+		cf.Add("if (!accept) {\n")
+		cf.Inc()
+	}
+	cf.Add(g.Call(operand) + "\nif (!accept) {\n\treturn false;\n}\n")
+	if prefix {
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	cf.Add("for (;;) {\n")
+	cf.Inc()
+	if binary {
+		cf.Add("int save = parserData.pos;\n")
+	}
+	for i, l := range levels {
+		level := fmt.Sprint(i + 1)
+		switch l.Associativity {
+		case "PREFIX":
+			continue
+		case "NONE":
+			cf.Add("if (min <= " + level + " && last != " + level + ") {\n")
+		default:
+			cf.Add("if (min <= " + level + ") {\n")
+		}
+		cf.Inc()
+		operator(l.Operator)
+		if l.Associativity == "POSTFIX" {
+			cf.Add("if (accept) {\n\t" + strings.Replace(g.wrap("start", "PostfixOp"), "\n", "\n\t", -1) + "\n\tcontinue;\n}\n")
+		} else {
+			// The right operand of a right associative operator may
+			// apply it again, while for the others it binds tighter
+			next := fmt.Sprint(i + 2)
+			if l.Associativity == "RIGHT" {
+				next = level
+			}
+			cf.Add("if (accept) {\n\tif ((accept = " + name + "(" + next + "))) {\n\t\t" + strings.Replace(g.wrap("start", "BinaryOp"), "\n", "\n\t\t", -1) + "\n")
+			if l.Associativity == "NONE" {
+				cf.Add("\t\tlast = " + level + ";\n")
+			}
+			cf.Add("\t\tcontinue;\n\t}\n\tparserData.pos = save;\n\tRoot.Discard(save);\n}\n")
+		}
+		cf.Dec()
+		cf.Add("}\n")
+	}
+	cf.Add("return true;\n")
+	cf.Dec()
+	cf.Add("}\n")
+	cf.Dec()
+	cf.Add("}\n\n")
+	g.output += cf.String()
+	return "accept = " + name + "(1);"
 }
 
 func (g *JavaGenerator) AssertNot(a string) string {
@@ -413,7 +662,15 @@ func (g *JavaGenerator) Begin(s GeneratorSettings) error {
 		"int LastError;")
 	g.realOutput += g.s.Header + `
 import java.io.*;
-
+`
+	if g.cut {
+		g.realOutput += `
+// Thrown when an expression following a cut fails
+class CutFailure extends RuntimeException {
+}
+`
+	}
+	g.realOutput += `
 class NodeContainer {
     public NodeContainer()
     {
@@ -606,6 +863,8 @@ func (g *JavaGenerator) Finish() error {
 	}
 	g.realOutput = ""
 	g.output = ""
+	g.tables = nil
+	g.cut = false
 
 	if err := g.s.WriteFile(g.s.Name+".java", ret); err != nil {
 		return err
@@ -253,78 +253,78 @@ true, false, true, true, null, false
 `}
 
 var invalid = map[string]string{`['
-`: `1,2: Unexpected '`,
+`: `1,2: expected Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found "'"`,
 	`aå
-`: `1,1: Unexpected a`,
+`: `1,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found "a"`,
 	`{,
-`: `1,2: Unexpected ,`,
+`: `1,2: expected KeyValuePair or "}" but found ","`,
 	`[,
-`: `1,2: Unexpected ,`,
+`: `1,2: expected Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found ","`,
 	`[1,
-`: `2,1: Unexpected EOF`,
-	``: `1,1: Unexpected EOF`,
+`: `2,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found EOF`,
+	``: `1,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found EOF`,
 	`[1,]
-`: `1,4: Unexpected ]`,
+`: `1,4: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found "]"`,
 	`[1,
 2,
 3,
 4,
 5,
 ]
-`: `6,1: Unexpected ]`,
+`: `6,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found "]"`,
 	`[1,2,3]
 foo
-`: `2,1: Unexpected f`,
+`: `2,1: expected "," or EndOfFile but found "f"`,
 	`[1,2,3]foo
-`: `1,8: Unexpected f`,
+`: `1,8: expected "," or EndOfFile but found "f"`,
 	`[012]
-`: `1,5: Unexpected ]`,
+`: `1,5: expected [0-9], "." or [Ee] but found "]"`,
 	`[troo
-`: `1,2: Unexpected t`,
+`: `1,2: expected Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found "t"`,
 	`[-123foo]
-`: `1,6: Unexpected f`,
+`: `1,6: expected [0-9], ".", [Ee], ",", Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found "f"`,
 	`[-123.123foo]
-`: `1,10: Unexpected f`,
+`: `1,10: expected [0-9], [Ee], ",", Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found "f"`,
 	`{
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected KeyValuePair but found EOF`,
 	`[
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found EOF`,
 	`[-foo]
-`: `1,3: Unexpected f`,
+`: `1,3: expected [0-9], ".", "0" or [1-9] but found "f"`,
 	`[-012]
-`: `1,6: Unexpected ]`,
+`: `1,6: expected [0-9], "." or [Ee] but found "]"`,
 	`{'a'
-`: `1,2: Unexpected '`,
+`: `1,2: expected KeyValuePair or "}" but found "'"`,
 	`{"a":"a" 123}
-`: `1,10: Unexpected 1`,
+`: `1,10: expected ",", KeyValuePair or "}" but found "1"`,
 	`[{}
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected ",", Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found EOF`,
 	`{"a"
-`: `1,5: Unexpected new line`,
+`: `1,5: expected ":" but found "\n"`,
 	`{"a":
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found EOF`,
 	`{"a":"a
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected "\\", any character or "\"" but found EOF`,
 	`[1ea]
-`: `1,4: Unexpected a`,
+`: `1,4: expected [-+] or [0-9] but found "a"`,
 	`[1e]
-`: `1,4: Unexpected ]`,
+`: `1,4: expected [-+] or [0-9] but found "]"`,
 	`[1.]
-`: `1,4: Unexpected ]`,
+`: `1,4: expected [0-9] but found "]"`,
 	`å
-`: `1,1: Unexpected å`,
+`: `1,1: expected Dictionary, Array, "\"", Float, Integer, Boolean or Null but found "å"`,
 	`["a"
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected ",", Dictionary, Array, "\"", Float, Integer, Boolean, Null or "]" but found EOF`,
 	`[{
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected KeyValuePair but found EOF`,
 	`{"
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected "\\", any character or "\"" but found EOF`,
 	`{"a
-`: `2,1: Unexpected EOF`,
+`: `2,1: expected "\\", any character or "\"" but found EOF`,
 	`{[
-`: `1,2: Unexpected [`,
+`: `1,2: expected KeyValuePair or "}" but found "["`,
 	`["a
-`: `2,1: Unexpected EOF`}
+`: `2,1: expected "\\", any character or "\"" but found EOF`}
 
 func TestParserComprehensive(t *testing.T) {
 	for k, v := range tests {
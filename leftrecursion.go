@@ -0,0 +1,165 @@
+package parser
+
+// Nullable reports whether the expression "node" can match without
+// consuming any input, given the nullable definitions in "rules".
+func Nullable(node *Node, rules map[string]bool) bool {
+	switch node.Name {
+	case "Expression":
+		for _, child := range node.Children {
+			if Nullable(child, rules) {
+				return true
+			}
+		}
+		return false
+	case "Sequence":
+		for _, child := range node.Children {
+			if !Nullable(child, rules) {
+				return false
+			}
+		}
+		return true
+	case "Prefix":
+		if PrefixPredicate(node) != nil {
+			return true
+		}
+		return Nullable(node.Children[len(node.Children)-1], rules)
+	case "Suffix":
+		if back := node.Children[len(node.Children)-1]; back.Name == "STAR" || back.Name == "QUESTION" {
+			return true
+		}
+		return Nullable(node.Children[0], rules)
+	case "Primary":
+		if front := node.Children[0]; front.Name == "Identifier" {
+			return rules[front.Data()]
+		}
+		return Nullable(node.Children[0], rules)
+	case "Action", "CUT":
+		return true
+	}
+	return false
+}
+
+// leftCalls adds the rules "node" might call without having consumed
+// any input first to "calls".
+func leftCalls(node *Node, rules map[string]bool, calls map[string]bool) {
+	switch node.Name {
+	case "Sequence":
+		for _, child := range node.Children {
+			leftCalls(child, rules, calls)
+			if !Nullable(child, rules) {
+				break
+			}
+		}
+	case "Expression":
+		for _, child := range node.Children {
+			leftCalls(child, rules, calls)
+		}
+	case "Prefix", "Suffix":
+		for _, child := range node.Children {
+			if child.Name == "Primary" || child.Name == "Suffix" {
+				leftCalls(child, rules, calls)
+			}
+		}
+	case "Primary":
+		if front := node.Children[0]; front.Name == "Identifier" {
+			calls[front.Data()] = true
+		} else {
+			leftCalls(front, rules, calls)
+		}
+	}
+}
+
+// NullableRules finds the definitions of the grammar in "rootNode"
+// that can match without consuming any input.
+func NullableRules(rootNode *Node) map[string]bool {
+	var (
+		names []string
+		exps  = make(map[string]*Node)
+		rules = make(map[string]bool)
+	)
+	for _, node := range rootNode.Children {
+		if node.Name == "Definition" {
+			name := node.Children[0].Data()
+			names = append(names, name)
+			exps[name] = DefinitionExpression(node)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			if !rules[name] && Nullable(exps[name], rules) {
+				rules[name] = true
+				changed = true
+			}
+		}
+	}
+	return rules
+}
+
+// LeftRecursion finds the left recursive definitions of the grammar
+// in "rootNode".
+//
+// "heads" are the definitions that need to grow a seed for the grammar to
+// be parsed, picked so that every left recursive cycle contains at least
+// one of them. "involved" are the remaining definitions taking part in left
+// recursion, whose outcome must not be memoized while a seed is growing.
+func LeftRecursion(rootNode *Node) (heads, involved []string) {
+	var (
+		names []string
+		exps  = make(map[string][]*Node)
+		rules = NullableRules(rootNode)
+		calls = make(map[string]map[string]bool)
+	)
+	for _, node := range rootNode.Children {
+		if node.Name == "Definition" {
+			name := node.Children[0].Data()
+			names = append(names, name)
+			exps[name] = []*Node{DefinitionExpression(node)}
+			for _, level := range DefinitionPrecedence(node) {
+				if level.Children[0].Name == "PREFIX" {
+					// Prefix operators are matched in place of an operand
+					exps[name] = append(exps[name], level.Children[1])
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		calls[name] = make(map[string]bool)
+		for _, exp := range exps[name] {
+			leftCalls(exp, rules, calls[name])
+		}
+	}
+
+	// Whether "name" can reach itself without passing through
+	// any of the definitions in "skip"
+	cyclic := func(name string, skip map[string]bool) bool {
+		visited := make(map[string]bool)
+		stack := []string{name}
+		for len(stack) > 0 {
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for call := range calls[curr] {
+				if call == name {
+					return true
+				} else if !visited[call] && !skip[call] {
+					visited[call] = true
+					stack = append(stack, call)
+				}
+			}
+		}
+		return false
+	}
+	isHead := make(map[string]bool)
+	for _, name := range names {
+		if cyclic(name, isHead) {
+			isHead[name] = true
+			heads = append(heads, name)
+		}
+	}
+	for _, name := range names {
+		if !isHead[name] && cyclic(name, nil) {
+			involved = append(involved, name)
+		}
+	}
+	return
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ColumnUnit selects what the columns of a PositionReader count.
+type ColumnUnit int
+
+const (
+	// Columns count runes, as LineCol does
+	ColumnRunes ColumnUnit = iota
+	// Columns count bytes
+	ColumnBytes
+	// Columns count UTF-16 code units, as positions in the
+	// Language Server Protocol do
+	ColumnUTF16
+)
+
+type (
+	// lineIndex maps offsets to lines and columns and back from what
+	// it has seen of the data, so that the data itself needn't be kept.
+	lineIndex struct {
+		// The offsets at which each line starts
+		lines []int
+		// The runes encoded in more than one byte
+		wide []wideRune
+		// The offset up to which the data has been scanned
+		scanned int
+	}
+
+	wideRune struct {
+		// The offset of the rune, and the number of bytes and
+		// UTF-16 code units encoding it
+		offset, size, units int
+		// The number of bytes more than one used to encode the
+		// rune and the runes preceding it
+		extra int
+		// The number of runes up to and including this one
+		// that are encoded as two UTF-16 code units
+		pairs int
+	}
+)
+
+// scan indexes "data", which is the data following the offset
+// scanned so far. A rune split at the end of "data" is left to be
+// scanned with the data following it, unless "eof" tells that
+// there's none.
+func (li *lineIndex) scan(data string, eof bool) {
+	if len(li.lines) == 0 {
+		li.lines = append(li.lines, 0)
+	}
+	for i := 0; i < len(data); {
+		if !eof && !utf8.FullRuneInString(data[i:]) {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(data[i:])
+		if r == '\n' {
+			li.lines = append(li.lines, li.scanned+size)
+		} else if size > 1 {
+			w := wideRune{offset: li.scanned, size: size, units: utf16.RuneLen(r), extra: size - 1}
+			if n := len(li.wide); n > 0 {
+				w.extra += li.wide[n-1].extra
+				w.pairs = li.wide[n-1].pairs
+			}
+			if w.units == 2 {
+				w.pairs++
+			}
+			li.wide = append(li.wide, w)
+		}
+		i += size
+		li.scanned += size
+	}
+}
+
+// before returns the number of wide runes ending at or before "offset".
+func (li *lineIndex) before(offset int) int {
+	return sort.Search(len(li.wide), func(i int) bool {
+		return li.wide[i].offset+li.wide[i].size > offset
+	})
+}
+
+// columns returns the number of columns in "unit" between the
+// start of the data and "offset".
+func (li *lineIndex) columns(offset int, unit ColumnUnit) int {
+	if unit == ColumnBytes {
+		return offset
+	}
+	i := li.before(offset)
+	if i == 0 {
+		return offset
+	}
+	w := li.wide[i-1]
+	if unit == ColumnUTF16 {
+		return offset - w.extra + w.pairs
+	}
+	return offset - w.extra
+}
+
+// lineCol returns the line and column of "offset", counting columns
+// in "unit".
+func (li *lineIndex) lineCol(offset int, unit ColumnUnit) (line, column int) {
+	if len(li.lines) == 0 {
+		return 1, offset + 1
+	}
+	line = sort.Search(len(li.lines), func(i int) bool { return li.lines[i] > offset })
+	start := li.lines[line-1]
+	return line, li.columns(offset, unit) - li.columns(start, unit) + 1
+}
+
+// offset returns the offset of "column" on "line", counting columns
+// in "unit". Lines and columns past the ones scanned are clamped to
+// the last offset scanned and the end of the line respectively.
+func (li *lineIndex) offset(line, column int, unit ColumnUnit) int {
+	if line < 1 || len(li.lines) == 0 {
+		return 0
+	} else if line > len(li.lines) {
+		return li.scanned
+	}
+	start, end := li.lines[line-1], li.scanned
+	if line < len(li.lines) {
+		// Not past the new line ending it
+		end = li.lines[line] - 1
+	}
+	offset := start + column - 1
+	if unit != ColumnBytes {
+		// Every wide rune before the offset moves it
+		// by the number of bytes more it takes up
+		for i := li.before(start); i < len(li.wide) && li.wide[i].offset < offset && offset < end; i++ {
+			if unit == ColumnUTF16 {
+				offset += li.wide[i].size - li.wide[i].units
+			} else {
+				offset += li.wide[i].size - 1
+			}
+		}
+	}
+	if offset < start {
+		return start
+	} else if offset > end {
+		return end
+	}
+	return offset
+}
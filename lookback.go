@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"unicode/utf8"
+)
+
+// The length returned by lookback.reach when it isn't bounded
+const unbounded = -1
+
+type lookback struct {
+	exps        map[string]*Node
+	recoveries  map[string]*Node
+	precedences map[string][]*Node
+	// The reach of the definitions, with the ones being computed
+	// marked unbounded to catch recursion
+	reaches map[string]int
+	// The definitions whose failure is followed by reading input again
+	retried map[string]bool
+	queue   []string
+	max     int
+	rule    string
+}
+
+func addReach(a, b int) int {
+	if a == unbounded || b == unbounded {
+		return unbounded
+	}
+	return a + b
+}
+
+func maxReach(a, b int) int {
+	if a == unbounded || b == unbounded {
+		return unbounded
+	} else if a > b {
+		return a
+	}
+	return b
+}
+
+// reach returns how many bytes past its start the expression "node" might
+// read, or unbounded.
+func (l *lookback) reach(node *Node) int {
+	switch node.Name {
+	case "Expression":
+		ret := 0
+		for _, child := range node.Children {
+			ret = maxReach(ret, l.reach(child))
+		}
+		return ret
+	case "Sequence":
+		ret := 0
+		for _, child := range node.Children {
+			ret = addReach(ret, l.reach(child))
+		}
+		return ret
+	case "Prefix":
+		return l.reach(node.Children[len(node.Children)-1])
+	case "Suffix":
+		if back := node.Children[len(node.Children)-1]; back.Name == "STAR" || back.Name == "PLUS" {
+			return unbounded
+		}
+		return l.reach(node.Children[0])
+	case "Primary":
+		front := node.Children[0]
+		if front.Name != "Identifier" {
+			return l.reach(front)
+		}
+		name := front.Data()
+		if r, ok := l.reaches[name]; ok {
+			return r
+		}
+		exp := l.exps[name]
+		if exp == nil || l.precedences[name] != nil {
+			// Any number of operators might follow an operand
+			return unbounded
+		}
+		l.reaches[name] = unbounded
+		r := l.reach(exp)
+		l.reaches[name] = r
+		return r
+	case "Literal":
+		data := node.Data()
+		if LiteralIgnoresCase(node) {
+			// The characters it matches might be longer than its own
+			runes, err := Unescape(data[1 : len(data)-2])
+			if err != nil {
+				return len(data) * utf8.UTFMax
+			}
+			return len(runes) * utf8.UTFMax
+		}
+		runes, err := Unescape(data[1 : len(data)-1])
+		if err != nil {
+			return len(data)
+		}
+		return len(string(runes))
+	case "Class", "DOT":
+		return utf8.UTFMax
+	}
+	return 0
+}
+
+// visit finds the expressions in "node" which the parser might go back
+// to the start of after having read past it. That's the case when they
+// are "retried" on failing, and always for predicates.
+func (l *lookback) visit(rule string, node *Node, retried bool) {
+	if retried {
+		if r := l.reach(node); l.max != unbounded && (r == unbounded || r > l.max) {
+			l.max, l.rule = r, rule
+		}
+	}
+	switch node.Name {
+	case "Expression":
+		for i, child := range node.Children {
+			l.visit(rule, child, retried || i < len(node.Children)-1)
+		}
+	case "Sequence":
+		for _, child := range node.Children {
+			l.visit(rule, child, retried)
+		}
+	case "Prefix":
+		l.visit(rule, node.Children[len(node.Children)-1], retried || PrefixPredicate(node) != nil)
+	case "Suffix":
+		l.visit(rule, node.Children[0], retried || len(node.Children) > 1)
+	case "Primary":
+		front := node.Children[0]
+		if front.Name != "Identifier" {
+			l.visit(rule, front, retried)
+		} else if name := front.Data(); retried && !l.retried[name] && l.exps[name] != nil {
+			l.retried[name] = true
+			l.queue = append(l.queue, name)
+		}
+	}
+}
+
+// define visits the expressions of the definition "name".
+func (l *lookback) define(name string, retried bool) {
+	for _, level := range l.precedences[name] {
+		// The operators are tried in turn, and the parser goes back to
+		// the start of a binary or prefix operator when the operand
+		// following it fails
+		l.visit(name, level.Children[1], true)
+		if level.Children[0].Name != "POSTFIX" && l.max != unbounded {
+			l.max, l.rule = unbounded, name
+		}
+	}
+	if recovery := l.recoveries[name]; recovery != nil {
+		// A failing definition is recovered from
+		// by reading from its start again
+		l.visit(name, l.exps[name], true)
+		l.visit(name, recovery, retried)
+	} else {
+		l.visit(name, l.exps[name], retried)
+	}
+}
+
+// Lookback returns the maximum number of bytes a parser for the grammar
+// in "rootNode" might have to go back from the furthest offset it has
+// read, in order to read the data again after backtracking.
+//
+// If that isn't bounded, -1 is returned along with the definition
+// responsible for it.
+func Lookback(rootNode *Node) (max int, rule string) {
+	l := lookback{
+		exps:        make(map[string]*Node),
+		recoveries:  make(map[string]*Node),
+		precedences: make(map[string][]*Node),
+		reaches:     make(map[string]int),
+		retried:     make(map[string]bool),
+	}
+	var defs []*Node
+	for _, node := range rootNode.Children {
+		if node.Name == "Definition" {
+			defs = append(defs, node)
+			l.exps[node.Children[0].Data()] = DefinitionExpression(node)
+			l.recoveries[node.Children[0].Data()] = DefinitionRecovery(node)
+			l.precedences[node.Children[0].Data()] = DefinitionPrecedence(node)
+		}
+	}
+	// Seeds of left recursion are grown by evaluating their
+	// definitions again from the start
+	heads, _ := LeftRecursion(rootNode)
+	for _, name := range heads {
+		l.retried[name] = true
+		l.queue = append(l.queue, name)
+	}
+	for _, def := range defs {
+		name := def.Children[0].Data()
+		l.define(name, l.retried[name])
+	}
+	for len(l.queue) > 0 {
+		name := l.queue[0]
+		l.queue = l.queue[1:]
+		l.define(name, true)
+	}
+	return l.max, l.rule
+}
@@ -0,0 +1,286 @@
+package parser
+
+import (
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// MemoKey identifies the evaluation of a rule at a specific offset.
+	MemoKey struct {
+		Rule, Pos int
+	}
+
+	// MemoEntry is the recorded outcome of evaluating a rule at an offset.
+	MemoEntry struct {
+		// Whether the rule accepted the input
+		Accept bool
+		// The offset the rule stopped at
+		End int
+		// The offset following the furthest rune the rule read,
+		// including the ones read by attempts that failed
+		Examined int
+		// The nodes the rule left at the end of the root Node's Children
+		Nodes []*Node
+		// The IgnoreRange when the rule was entered and when it returned
+		IgnoreIn, IgnoreOut text.Region
+		// The LastError the rule raised the parser's to, or 0 if it didn't
+		LastError int
+		// What the rule recorded as Expected
+		Expected Expected
+		// The trivia the rule left at the end of the Memo's Trivia
+		Trivia []*Node
+
+		start, keep, trivia, outer int
+		// The parser's LastError and Expected when the rule was entered
+		lastError int
+		expected  Expected
+	}
+
+	// Memo is a packrat memoization table, making sure each rule of
+	// a generated parser is evaluated at most once per input offset.
+	//
+	// The Memo points to the state of the parser it belongs to, which
+	// it records when a rule returns and restores when it's replayed.
+	Memo struct {
+		Data        Reader
+		Root        *Node
+		IgnoreRange *text.Region
+		LastError   *int
+		Expected    *Expected
+		// The Node holding the trivia as its Children, or nil
+		// if the parser doesn't keep trivia
+		Trivia  *Node
+		Entries map[MemoKey]*MemoEntry
+	}
+)
+
+// tail returns the number of Children of "n" that end before "pos",
+// which are kept when the Children after them are replaced.
+func tail(n *Node, pos int) int {
+	if n == nil {
+		return 0
+	}
+	keep := len(n.Children)
+	for keep > 0 && n.Children[keep-1].Range.End() > pos {
+		keep--
+	}
+	return keep
+}
+
+// recovered reports whether "nodes" hold Error Nodes recovered from.
+func recovered(nodes []*Node) bool {
+	return len(RecoveredErrors(&Node{Children: nodes})) > 0
+}
+
+// examined returns the offset following the furthest rune read from
+// the parser's Reader. Readers that don't keep track of it are assumed
+// to have been read completely.
+func (m *Memo) examined() int {
+	if t, ok := m.Data.(TrackingReader); ok {
+		return t.Examined()
+	}
+	return m.Data.Len() + 1
+}
+
+func (m *Memo) track(offset int) {
+	if t, ok := m.Data.(TrackingReader); ok {
+		t.Track(offset)
+	}
+}
+
+// Enter looks up the outcome of evaluating "rule" at the current position.
+// If it has been recorded before with the same ignore range, the entry is
+// returned along with true and should be applied with Replay.
+//
+// Otherwise a new entry is returned along with false, and the rule should
+// be evaluated and its outcome recorded with Leave. Until then, entering
+// the same rule at the same offset again replays a failure.
+func (m *Memo) Enter(rule int) (*MemoEntry, bool) {
+	key := MemoKey{rule, m.Data.Pos()}
+	if e := m.Entries[key]; e != nil && e.IgnoreIn == *m.IgnoreRange {
+		return e, true
+	}
+	e := &MemoEntry{
+		End:       key.Pos,
+		Examined:  key.Pos,
+		IgnoreIn:  *m.IgnoreRange,
+		IgnoreOut: *m.IgnoreRange,
+		start:     key.Pos,
+		keep:      tail(m.Root, key.Pos),
+		trivia:    tail(m.Trivia, key.Pos),
+		outer:     m.examined(),
+		lastError: *m.LastError,
+		expected:  *m.Expected,
+	}
+	m.Entries[key] = e
+	m.track(key.Pos)
+	// The rule records its attempts on its own, to be merged
+	// with the ones recorded before it when it returns
+	*m.Expected = Expected{}
+	return e, false
+}
+
+// record stores the current parser state as the outcome of "e". Only
+// the errors and attempts the rule added itself are recorded, so that
+// replaying it doesn't depend on what was evaluated before it.
+func (m *Memo) record(e *MemoEntry, accept bool) {
+	e.Accept = accept
+	e.End = m.Data.Pos()
+	e.Examined = m.examined()
+	e.IgnoreOut = *m.IgnoreRange
+	e.LastError = 0
+	if *m.LastError > e.lastError {
+		e.LastError = *m.LastError
+	}
+	e.Expected = Expected{Pos: m.Expected.Pos, Items: append([]string(nil), m.Expected.Items...)}
+	e.Nodes = nil
+	if e.keep < len(m.Root.Children) {
+		e.Nodes = make([]*Node, len(m.Root.Children)-e.keep)
+		copy(e.Nodes, m.Root.Children[e.keep:])
+	}
+	e.Trivia = nil
+	if m.Trivia != nil && e.trivia < len(m.Trivia.Children) {
+		e.Trivia = make([]*Node, len(m.Trivia.Children)-e.trivia)
+		copy(e.Trivia, m.Trivia.Children[e.trivia:])
+	}
+}
+
+// Leave records the outcome of the evaluation of "e" started with Enter,
+// and returns "accept".
+func (m *Memo) Leave(e *MemoEntry, accept bool) bool {
+	m.record(e, accept)
+	m.restore(e)
+	return accept
+}
+
+// restore merges the state from before "e" was entered back
+// into the parser state.
+func (m *Memo) restore(e *MemoEntry) {
+	if e.Examined < e.outer {
+		m.track(e.outer)
+	}
+	*m.Expected, e.expected = e.expected, Expected{}
+	m.Expected.Merge(&e.Expected)
+}
+
+// Grow is used to evaluate left recursive rules by growing a seed.
+// Starting out from the failure planted by Enter, the rule is evaluated
+// repeatedly with recursive invocations replaying the previous outcome,
+// until an evaluation doesn't consume more input than the one before it.
+//
+// Grow records the outcome of one such evaluation if it grew the seed and
+// rewinds the parser state to where the rule was entered. It returns
+// whether the rule should be evaluated again; when it doesn't, the final
+// outcome is applied with Replay.
+func (m *Memo) Grow(e *MemoEntry, accept bool) bool {
+	grew := accept && (!e.Accept || m.Data.Pos() > e.End)
+	if grew {
+		m.record(e, accept)
+	} else {
+		// The attempt that didn't grow the seed still
+		// examined the input and recorded its failures
+		if examined := m.examined(); examined > e.Examined {
+			e.Examined = examined
+		}
+		if *m.LastError > e.lastError && *m.LastError > e.LastError {
+			e.LastError = *m.LastError
+		}
+		e.Expected = Expected{Pos: m.Expected.Pos, Items: append([]string(nil), m.Expected.Items...)}
+	}
+	m.Data.Seek(e.start)
+	if e.keep < len(m.Root.Children) {
+		m.Root.Children = m.Root.Children[:e.keep]
+	}
+	if m.Trivia != nil && e.trivia < len(m.Trivia.Children) {
+		m.Trivia.Children = m.Trivia.Children[:e.trivia]
+	}
+	*m.IgnoreRange = e.IgnoreIn
+	if !grew {
+		m.restore(e)
+	}
+	return grew
+}
+
+// Replay applies the recorded outcome of "e" to the parser state
+// as if the rule had been evaluated again, and returns whether
+// the rule accepted the input.
+func (m *Memo) Replay(e *MemoEntry) bool {
+	m.Root.Discard(e.start)
+	m.Root.Children = append(m.Root.Children, e.Nodes...)
+	if m.Trivia != nil {
+		m.Trivia.Discard(e.start)
+		m.Trivia.Children = append(m.Trivia.Children, e.Trivia...)
+	}
+	m.Data.Seek(e.End)
+	*m.IgnoreRange = e.IgnoreOut
+	if *m.LastError < e.LastError {
+		*m.LastError = e.LastError
+	}
+	m.Expected.Merge(&e.Expected)
+	if m.examined() < e.Examined {
+		m.track(e.Examined)
+	}
+	return e.Accept
+}
+
+// Edit prepares the recorded outcomes for parsing the input again after
+// the data in "region" has been replaced with "length" bytes of new data.
+//
+// The outcomes of evaluations that examined the replaced data are dropped,
+// and those of evaluations starting after it are moved along with their
+// Nodes. The returned entries are to be used as the Entries of the Memo
+// once it points to the edited input.
+func (m *Memo) Edit(region text.Region, length int) map[MemoKey]*MemoEntry {
+	var (
+		a, b    = region.Begin(), region.End()
+		delta   = length - region.Size()
+		entries = make(map[MemoKey]*MemoEntry, len(m.Entries))
+		moved   = make(map[*Node]bool)
+		move    func(n *Node)
+	)
+	move = func(n *Node) {
+		if moved[n] {
+			return
+		}
+		moved[n] = true
+		n.Range.Adjust(b, delta)
+		for _, child := range n.Children {
+			move(child)
+		}
+	}
+	for key, e := range m.Entries {
+		if e.start < a {
+			// Having examined up to the end of the data means that
+			// the end was tested for, which appending data changes
+			if e.Examined < a || e.Examined == a && a < m.Data.Len() {
+				entries[key] = e
+			}
+			continue
+		} else if e.start < b || recovered(e.Nodes) {
+			// Recovered errors would have to be recreated
+			// with their new lines and columns
+			continue
+		} else if delta != 0 {
+			key.Pos += delta
+			e.start += delta
+			e.End += delta
+			e.Examined += delta
+			e.IgnoreIn.Adjust(b, delta)
+			e.IgnoreOut.Adjust(b, delta)
+			if e.LastError >= b {
+				e.LastError += delta
+			}
+			if e.Expected.Pos >= b {
+				e.Expected.Pos += delta
+			}
+			for _, n := range e.Nodes {
+				move(n)
+			}
+			for _, n := range e.Trivia {
+				move(n)
+			}
+		}
+		entries[key] = e
+	}
+	return entries
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"github.com/jxo/lime/text"
+	"testing"
+)
+
+func newMemo(r Reader, root *Node) (*Memo, *text.Region, *int, *Expected) {
+	m := &Memo{
+		Data:        r,
+		Root:        root,
+		IgnoreRange: &text.Region{},
+		LastError:   new(int),
+		Expected:    &Expected{},
+		Entries:     make(map[MemoKey]*MemoEntry),
+	}
+	return m, m.IgnoreRange, m.LastError, m.Expected
+}
+
+func TestMemo(t *testing.T) {
+	var (
+		s    ds
+		root = Node{P: s}
+		r    = NewReader("abcdef")
+	)
+	m, ignore, lastError, expected := newMemo(r, &root)
+	root.Append(&Node{Name: "before", Range: text.Region{A: 0, B: 1}, P: s})
+	r.Seek(1)
+	e, done := m.Enter(0)
+	if done {
+		t.Fatal("Nothing should have been memoized yet")
+	}
+	if e2, done := m.Enter(0); !done || e2 != e {
+		t.Error("Expected the pending entry to be returned")
+	} else if m.Replay(e2) {
+		t.Error("A pending entry should replay as a failure")
+	}
+	r.Seek(4)
+	root.Append(&Node{Name: "rule", Range: text.Region{A: 1, B: 4}, P: s})
+	expected.Add(4, "Other")
+	*ignore = text.Region{A: 3, B: 4}
+	*lastError = 2
+	if !m.Leave(e, true) {
+		t.Error("Leave should return the accept status")
+	}
+	want := root.String()
+
+	// Evaluating something else from the same offset
+	r.Seek(1)
+	root.Discard(1)
+	*ignore = text.Region{}
+	if e2, done := m.Enter(1); done || e2 == e {
+		t.Error("A different rule shouldn't share the entry")
+	}
+	root.Append(&Node{Name: "stale", Range: text.Region{A: 1, B: 2}, P: s})
+
+	*ignore = text.Region{A: 0, B: 1}
+	if _, done := m.Enter(0); done {
+		t.Error("Entries recorded with a different ignore range shouldn't be reused")
+	}
+	m.Entries[MemoKey{0, 1}] = e
+	*ignore = text.Region{}
+	*lastError = 0
+	*expected = Expected{}
+	if e2, done := m.Enter(0); !done || e2 != e {
+		t.Fatal("Expected the recorded entry to be returned")
+	}
+	if !m.Replay(e) {
+		t.Error("Expected the replayed rule to accept")
+	}
+	if r.Pos() != 4 || *ignore != (text.Region{A: 3, B: 4}) || *lastError != 2 {
+		t.Errorf("Unexpected state after replay: %d, %v, %d", r.Pos(), *ignore, *lastError)
+	}
+	if expected.Pos != 4 || len(expected.Items) != 1 || expected.Items[0] != "Other" {
+		t.Errorf("Expected the recorded attempts to be replayed, got %v", *expected)
+	}
+	if got := root.String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestMemoEdit(t *testing.T) {
+	var (
+		s    ds
+		root = Node{P: s}
+		r    = NewReader("ab cd ef")
+	)
+	m, _, _, _ := newMemo(r, &root)
+	// Records a rule matching the word at "start", reading one rune past it
+	word := func(rule, start int) *Node {
+		r.Seek(start)
+		e, _ := m.Enter(rule)
+		n := &Node{Name: "word", Range: text.Region{A: start, B: start + 2}, P: s}
+		n.Append(&Node{Name: "char", Range: text.Region{A: start + 1, B: start + 2}, P: s})
+		root.Append(n)
+		r.Seek(start + 2)
+		r.Read()
+		r.UnRead()
+		m.Leave(e, true)
+		return n
+	}
+	word(0, 0)
+	word(0, 3)
+	last := word(0, 6)
+	shared := last.Children[0]
+	r.Seek(6)
+	e, _ := m.Enter(1)
+	root.Append(shared)
+	m.Leave(e, true)
+
+	if r.(TrackingReader).Examined() != r.Len()+1 {
+		t.Errorf("Expected the reads to be tracked, got %d", r.(TrackingReader).Examined())
+	}
+	entries := m.Edit(text.Region{A: 3, B: 5}, 3)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries to be kept, got %v", entries)
+	}
+	if entries[MemoKey{0, 0}] == nil {
+		t.Error("The entry before the edit should be kept")
+	}
+	if entries[MemoKey{0, 3}] != nil {
+		t.Error("The entry for the replaced data should be dropped")
+	}
+	e = entries[MemoKey{0, 7}]
+	if e == nil || e.End != 9 || e.Examined != 10 {
+		t.Fatalf("Expected the entry after the edit to be moved, got %v", entries)
+	}
+	if last.Range != (text.Region{A: 7, B: 9}) || shared.Range != (text.Region{A: 8, B: 9}) {
+		t.Errorf("Expected the nodes to be moved once, got %v and %v", last.Range, shared.Range)
+	}
+	if entries[MemoKey{1, 7}] == nil {
+		t.Error("Expected the second rule to be moved as well")
+	}
+}
@@ -48,6 +48,18 @@ type (
 		Children []*Node
 		// The DataSource to query when Node.Data is called.
 		P DataSource
+		// The Value computed by the action blocks of the
+		// Definition that created this Node, if any. The Error
+		// Nodes of %recover expressions hold the Error they
+		// recovered from.
+		Value interface{}
+		// The Label of the expression that created this Node,
+		// if any.
+		Label string
+		// The ignored text, such as spacing and comments, before
+		// and after this Node that belongs to it. Only set by
+		// parsers keeping trivia, see AttachTrivia.
+		Leading, Trailing text.Region
 	}
 )
 
@@ -56,7 +68,12 @@ type (
 func (n *Node) format(buf *bytes.Buffer, indent string) {
 	buf.WriteString(indent)
 	buf.WriteString(fmt.Sprintf("%d-%d", n.Range.Begin(), n.Range.End()))
-	buf.WriteString(": \"")
+	buf.WriteString(": ")
+	if n.Label != "" {
+		buf.WriteString(n.Label)
+		buf.WriteString(":")
+	}
+	buf.WriteString("\"")
 	buf.WriteString(n.Name)
 	buf.WriteString("\"")
 	if len(n.Children) == 0 {
@@ -149,6 +166,62 @@ func (n *Node) Cleanup(pos, end int) *Node {
 	return &popped
 }
 
+// Tail returns a copy of the Children that Cleanup would move to the
+// Node it returns if called with "pos", leaving this Node as it is.
+func (n *Node) Tail(pos int) []*Node {
+	i := len(n.Children)
+	if pos == 0 {
+		i = 0
+	}
+	for i > 0 && n.Children[i-1].Range.End() > pos {
+		i--
+	}
+	c := make([]*Node, len(n.Children)-i)
+	copy(c, n.Children[i:])
+	return c
+}
+
+// AddLabel labels the Children that Cleanup would move to the Node it
+// returns if called with "pos" and "end". A single such Child is replaced
+// by a copy of it with the Label "label", as it might be shared with a
+// memoized tree. Otherwise they are replaced by a new Node named after
+// the label, so that there's a Node with the label even when none or
+// several were created.
+func (n *Node) AddLabel(pos, end int, label string) {
+	if tail := n.Tail(pos); len(tail) == 1 {
+		labeled := *tail[0]
+		labeled.Label = label
+		n.Children[len(n.Children)-1] = &labeled
+		return
+	}
+	node := n.Cleanup(pos, end)
+	node.Name = label
+	node.Label = label
+	node.P = n.P
+	n.Append(node)
+}
+
+// Labeled returns the first of the Children with the Label "label",
+// or nil if there's none.
+func (n *Node) Labeled(label string) *Node {
+	for _, child := range n.Children {
+		if child.Label == label {
+			return child
+		}
+	}
+	return nil
+}
+
+// AllLabeled returns the Children with the Label "label".
+func (n *Node) AllLabeled(label string) (ret []*Node) {
+	for _, child := range n.Children {
+		if child.Label == label {
+			ret = append(ret, child)
+		}
+	}
+	return
+}
+
 // Clones this node-sub tree
 func (n *Node) Clone() *Node {
 	ret := *n
@@ -25,6 +25,7 @@ SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package parser
 
 import (
+	"github.com/jxo/lime/text"
 	"testing"
 )
 
@@ -46,3 +47,53 @@ func TestNodeClone(t *testing.T) {
 		t.Error("Should be equal", a, b)
 	}
 }
+
+func TestNodeTail(t *testing.T) {
+	var s ds
+	for _, pos := range []int{0, 2, 3, 5, 10} {
+		n := Node{P: s}
+		for i := 0; i < 5; i++ {
+			n.Append(&Node{Range: text.Region{A: 2 * i, B: 2*i + 2}, P: s})
+		}
+		tail := n.Tail(pos)
+		if len(n.Children) != 5 {
+			t.Fatalf("Expected Tail to leave the Children alone, got %d", len(n.Children))
+		}
+		popped := n.Cleanup(pos, 10)
+		if len(tail) != len(popped.Children) {
+			t.Fatalf("Expected Tail(%d) to return what Cleanup would, got %d rather than %d Nodes", pos, len(tail), len(popped.Children))
+		}
+		for i := range tail {
+			if tail[i] != popped.Children[i] {
+				t.Errorf("Expected Tail(%d) to return what Cleanup would, got %v at %d", pos, tail[i].Range, i)
+			}
+		}
+	}
+}
+
+func TestNodeLabels(t *testing.T) {
+	var s ds
+	shared := &Node{Name: "A", Range: text.Region{A: 0, B: 1}, P: s}
+	n := Node{P: s}
+	n.Append(shared)
+	n.AddLabel(0, 1, "a")
+	if shared.Label != "" || n.Children[0].Label != "a" || n.Children[0].Name != "A" {
+		t.Errorf("Expected a labeled copy of the single Node, got %v", n.Children[0])
+	}
+	n.Append(&Node{Name: "B", Range: text.Region{A: 1, B: 2}, P: s})
+	n.Append(&Node{Name: "C", Range: text.Region{A: 2, B: 3}, P: s})
+	n.AddLabel(1, 3, "b")
+	n.AddLabel(3, 3, "c")
+	if len(n.Children) != 3 {
+		t.Fatalf("Expected the Nodes to be labeled in place, got %v", n.String())
+	}
+	if b := n.Labeled("b"); b == nil || b.Name != "b" || len(b.Children) != 2 {
+		t.Errorf("Expected a new Node holding the Nodes labeled b, got %v", b)
+	}
+	if c := n.Labeled("c"); c == nil || c.Range != (text.Region{A: 3, B: 3}) || len(c.Children) != 0 {
+		t.Errorf("Expected an empty Node labeled c, got %v", c)
+	}
+	if n.Labeled("d") != nil || len(n.AllLabeled("a")) != 1 {
+		t.Error("Expected the lookup to only return the labeled Nodes")
+	}
+}
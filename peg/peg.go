@@ -28,6 +28,7 @@ package peg
 import (
 	"github.com/jxo/lime/text"
 	. "github.com/jxo/parser"
+	"io"
 )
 
 type Peg struct {
@@ -35,6 +36,8 @@ type Peg struct {
 	IgnoreRange text.Region
 	Root        Node
 	LastError   int
+	Expected    Expected
+	Backtrack   Backtrack
 }
 
 func (p *Peg) RootNode() *Node {
@@ -42,10 +45,17 @@ func (p *Peg) RootNode() *Node {
 }
 
 func (p *Peg) SetData(data string) {
-	p.ParserData = NewReader(data)
+	p.SetReader(NewReader(data))
+}
+
+// SetReader prepares the parser for parsing the data of "r".
+func (p *Peg) SetReader(r Reader) {
+	p.ParserData = r
 	p.Root = Node{Name: "Peg", P: p}
 	p.IgnoreRange = text.Region{}
 	p.LastError = 0
+	p.Expected = Expected{}
+	p.Backtrack.Reset()
 }
 
 func (p *Peg) Parse(data string) bool {
@@ -55,32 +65,36 @@ func (p *Peg) Parse(data string) bool {
 	return ret
 }
 
+// ParseReader parses the data read from "r", keeping no more of it
+// in memory than what the parser might still backtrack over and the
+// data of the Nodes parsed.
+func (p *Peg) ParseReader(r io.Reader) bool {
+	s := NewStreamReader(r)
+	s.Live = &p.Root
+	s.Backtrack = &p.Backtrack
+	p.SetReader(s)
+	ret := p.realParse()
+	p.Root.UpdateRange()
+	return ret
+}
+
 func (p *Peg) Data(start, end int) string {
 	return p.ParserData.Substring(start, end)
 }
 
 func (p *Peg) Error() Error {
-	errstr := ""
-	line, column := p.ParserData.LineCol(p.LastError)
+	return p.Expected.Error(p.ParserData, p.LastError)
+}
 
-	if p.LastError == p.ParserData.Len() {
-		errstr = "Unexpected EOF"
-	} else {
-		p.ParserData.Seek(p.LastError)
-		if r := p.ParserData.Read(); r == '\r' || r == '\n' {
-			errstr = "Unexpected new line"
-		} else {
-			errstr = "Unexpected " + string(r)
-		}
-	}
-	return NewError(line, column, errstr)
+func (p *Peg) Errors() []Error {
+	return RecoveredErrors(&p.Root)
 }
 
 func (p *Peg) realParse() bool {
 	return p.Grammar()
 }
 func (p *Peg) Grammar() bool {
-	// Grammar       <- Spacing Definition+ EndOfFile?
+	// Grammar       <- Spacing Import* Definition+ EndOfFile?
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
@@ -89,21 +103,46 @@ func (p *Peg) Grammar() bool {
 		accept = p.Spacing()
 		if accept {
 			{
-				save := p.ParserData.Pos()
-				accept = p.Definition()
-				if !accept {
-					p.ParserData.Seek(save)
-				} else {
-					for accept {
-						accept = p.Definition()
+				accept = true
+				for accept {
+					pos := p.ParserData.Pos()
+					saved := p.Backtrack.Save(pos)
+					accept = p.Import()
+					p.Backtrack.Release(saved)
+					if pos == p.ParserData.Pos() {
+						break
 					}
-					accept = true
 				}
+				accept = true
 			}
 			if accept {
-				accept = p.EndOfFile()
-				accept = true
+				{
+					save := p.ParserData.Pos()
+					accept = p.Definition()
+					if !accept {
+						p.ParserData.Seek(save)
+					} else {
+						for accept {
+							pos := p.ParserData.Pos()
+							saved := p.Backtrack.Save(pos)
+							accept = p.Definition()
+							p.Backtrack.Release(saved)
+							if pos == p.ParserData.Pos() {
+								break
+							}
+						}
+						accept = true
+					}
+				}
 				if accept {
+					{
+						saved := p.Backtrack.Save(p.ParserData.Pos())
+						accept = p.EndOfFile()
+						p.Backtrack.Release(saved)
+						accept = true
+					}
+					if accept {
+					}
 				}
 			}
 		}
@@ -115,7 +154,7 @@ func (p *Peg) Grammar() bool {
 		}
 	}
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -123,20 +162,19 @@ func (p *Peg) Grammar() bool {
 	return accept
 }
 
-func (p *Peg) Definition() bool {
-	// Definition    <- Identifier LEFTARROW Expression
+func (p *Peg) Import() bool {
+	// Import        <- IMPORT Literal
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		accept = p.Identifier()
+		accept = p.IMPORT()
 		if accept {
-			accept = p.LEFTARROW()
+			accept = p.Literal()
 			if accept {
-				accept = p.Expression()
-				if accept {
-				}
 			}
 		}
 		if !accept {
@@ -147,52 +185,63 @@ func (p *Peg) Definition() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Import")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Definition"
+		node.Name = "Import"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Expression() bool {
-	// Expression    <- Sequence (SLASH Sequence)*
+func (p *Peg) Definition() bool {
+	// Definition    <- Identifier Parameters? LEFTARROW Expression Precedence? Recovery?
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		accept = p.Sequence()
+		accept = p.Identifier()
 		if accept {
 			{
+				saved := p.Backtrack.Save(p.ParserData.Pos())
+				accept = p.Parameters()
+				p.Backtrack.Release(saved)
 				accept = true
-				for accept {
-					{
-						save := p.ParserData.Pos()
-						accept = p.SLASH()
+			}
+			if accept {
+				accept = p.LEFTARROW()
+				if accept {
+					accept = p.Expression()
+					if accept {
+						{
+							saved := p.Backtrack.Save(p.ParserData.Pos())
+							accept = p.Precedence()
+							p.Backtrack.Release(saved)
+							accept = true
+						}
 						if accept {
-							accept = p.Sequence()
-							if accept {
+							{
+								saved := p.Backtrack.Save(p.ParserData.Pos())
+								accept = p.Recovery()
+								p.Backtrack.Release(saved)
+								accept = true
 							}
-						}
-						if !accept {
-							if p.LastError < p.ParserData.Pos() {
-								p.LastError = p.ParserData.Pos()
+							if accept {
 							}
-							p.ParserData.Seek(save)
 						}
 					}
 				}
-				accept = true
-			}
-			if accept {
 			}
 		}
 		if !accept {
@@ -203,127 +252,172 @@ func (p *Peg) Expression() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Definition")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Expression"
+		node.Name = "Definition"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Sequence() bool {
-	// Sequence      <- Prefix+
+func (p *Peg) Parameters() bool {
+	// Parameters    <- OPEN Identifier (COMMA Identifier)* CLOSE
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		accept = p.Prefix()
+		accept = p.OPEN()
+		if accept {
+			accept = p.Identifier()
+			if accept {
+				{
+					accept = true
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
+						{
+							save := p.ParserData.Pos()
+							accept = p.COMMA()
+							if accept {
+								accept = p.Identifier()
+								if accept {
+								}
+							}
+							if !accept {
+								if p.LastError < p.ParserData.Pos() {
+									p.LastError = p.ParserData.Pos()
+								}
+								p.ParserData.Seek(save)
+							}
+						}
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
+						}
+					}
+					accept = true
+				}
+				if accept {
+					accept = p.CLOSE()
+					if accept {
+					}
+				}
+			}
+		}
 		if !accept {
-			p.ParserData.Seek(save)
-		} else {
-			for accept {
-				accept = p.Prefix()
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
 			}
-			accept = true
+			p.ParserData.Seek(save)
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Parameters")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Sequence"
+		node.Name = "Parameters"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Prefix() bool {
-	// Prefix        <- (AND / NOT)? Suffix
+func (p *Peg) Precedence() bool {
+	// Precedence    <- Level+
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		{
-			save := p.ParserData.Pos()
-			accept = p.AND()
-			if !accept {
-				accept = p.NOT()
-				if !accept {
-				}
-			}
-			if !accept {
-				p.ParserData.Seek(save)
-			}
-		}
-		accept = true
-		if accept {
-			accept = p.Suffix()
-			if accept {
-			}
-		}
+		accept = p.Level()
 		if !accept {
-			if p.LastError < p.ParserData.Pos() {
-				p.LastError = p.ParserData.Pos()
-			}
 			p.ParserData.Seek(save)
+		} else {
+			for accept {
+				pos := p.ParserData.Pos()
+				saved := p.Backtrack.Save(pos)
+				accept = p.Level()
+				p.Backtrack.Release(saved)
+				if pos == p.ParserData.Pos() {
+					break
+				}
+			}
+			accept = true
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Precedence")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Prefix"
+		node.Name = "Precedence"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Suffix() bool {
-	// Suffix        <- Primary (QUESTION / STAR / PLUS)?
+func (p *Peg) Level() bool {
+	// Level         <- (LEFT / RIGHT / NONE / PREFIX / POSTFIX) Expression
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		accept = p.Primary()
-		if accept {
-			{
-				save := p.ParserData.Pos()
-				accept = p.QUESTION()
+		{
+			save := p.ParserData.Pos()
+			saved := p.Backtrack.Save(save)
+			accept = p.LEFT()
+			if !accept {
+				accept = p.RIGHT()
 				if !accept {
-					accept = p.STAR()
+					accept = p.NONE()
 					if !accept {
-						accept = p.PLUS()
+						accept = p.PREFIX()
 						if !accept {
+							accept = p.POSTFIX()
+							if !accept {
+							}
 						}
 					}
 				}
-				if !accept {
-					p.ParserData.Seek(save)
-				}
 			}
-			accept = true
+			p.Backtrack.Release(saved)
+			if !accept {
+				p.ParserData.Seek(save)
+			}
+		}
+		if accept {
+			accept = p.Expression()
 			if accept {
 			}
 		}
@@ -335,121 +429,102 @@ func (p *Peg) Suffix() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Level")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Suffix"
+		node.Name = "Level"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Primary() bool {
-	// Primary       <- Identifier !LEFTARROW
-	//                / OPEN Expression CLOSE
-	//                / Literal / Class / DOT
-	// # Lexical syntax
+func (p *Peg) Recovery() bool {
+	// Recovery      <- RECOVER Expression
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		{
-			save := p.ParserData.Pos()
-			accept = p.Identifier()
+		accept = p.RECOVER()
+		if accept {
+			accept = p.Expression()
 			if accept {
-				s := p.ParserData.Pos()
-				accept = p.LEFTARROW()
-				p.ParserData.Seek(s)
-				p.Root.Discard(s)
-				accept = !accept
-				if accept {
-				}
-			}
-			if !accept {
-				if p.LastError < p.ParserData.Pos() {
-					p.LastError = p.ParserData.Pos()
-				}
-				p.ParserData.Seek(save)
 			}
 		}
 		if !accept {
-			{
-				save := p.ParserData.Pos()
-				accept = p.OPEN()
-				if accept {
-					accept = p.Expression()
-					if accept {
-						accept = p.CLOSE()
-						if accept {
-						}
-					}
-				}
-				if !accept {
-					if p.LastError < p.ParserData.Pos() {
-						p.LastError = p.ParserData.Pos()
-					}
-					p.ParserData.Seek(save)
-				}
-			}
-			if !accept {
-				accept = p.Literal()
-				if !accept {
-					accept = p.Class()
-					if !accept {
-						accept = p.DOT()
-						if !accept {
-						}
-					}
-				}
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
 			}
-		}
-		if !accept {
 			p.ParserData.Seek(save)
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Recovery")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Primary"
+		node.Name = "Recovery"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Identifier() bool {
-	// Identifier    <- IdentStart IdentCont* Spacing
+func (p *Peg) Expression() bool {
+	// Expression    <- Sequence (SLASH Sequence)*
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		accept = p.IdentStart()
+		accept = p.Sequence()
 		if accept {
 			{
 				accept = true
 				for accept {
-					accept = p.IdentCont()
+					pos := p.ParserData.Pos()
+					saved := p.Backtrack.Save(pos)
+					{
+						save := p.ParserData.Pos()
+						accept = p.SLASH()
+						if accept {
+							accept = p.Sequence()
+							if accept {
+							}
+						}
+						if !accept {
+							if p.LastError < p.ParserData.Pos() {
+								p.LastError = p.ParserData.Pos()
+							}
+							p.ParserData.Seek(save)
+						}
+					}
+					p.Backtrack.Release(saved)
+					if pos == p.ParserData.Pos() {
+						break
+					}
 				}
 				accept = true
 			}
 			if accept {
-				accept = p.Spacing()
-				if accept {
-				}
 			}
 		}
 		if !accept {
@@ -460,343 +535,149 @@ func (p *Peg) Identifier() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Expression")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Identifier"
+		node.Name = "Expression"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) IdentStart() bool {
-	// IdentStart    <- [a-zA-Z_]
+func (p *Peg) Sequence() bool {
+	// Sequence      <- CUT* Prefix (CUT / Prefix)* Action?
 	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		c := p.ParserData.Read()
-		if c >= 'a' && c <= 'z' {
+		{
 			accept = true
-		} else {
-			p.ParserData.UnRead()
-			accept = false
-		}
-		if !accept {
-			c := p.ParserData.Read()
-			if c >= 'A' && c <= 'Z' {
-				accept = true
-			} else {
-				p.ParserData.UnRead()
-				accept = false
+			for accept {
+				pos := p.ParserData.Pos()
+				saved := p.Backtrack.Save(pos)
+				accept = p.CUT()
+				p.Backtrack.Release(saved)
+				if pos == p.ParserData.Pos() {
+					break
+				}
 			}
-			if !accept {
-				{
-					accept = false
-					c := p.ParserData.Read()
-					if c == '_' {
-						accept = true
-					} else {
-						p.ParserData.UnRead()
-					}
-				}
-				if !accept {
-				}
-			}
-		}
-		if !accept {
-			p.ParserData.Seek(save)
-		}
-	}
-	return accept
-}
-
-func (p *Peg) IdentCont() bool {
-	// IdentCont     <- IdentStart / [0-9]
-	accept := false
-	{
-		save := p.ParserData.Pos()
-		accept = p.IdentStart()
-		if !accept {
-			c := p.ParserData.Read()
-			if c >= '0' && c <= '9' {
-				accept = true
-			} else {
-				p.ParserData.UnRead()
-				accept = false
-			}
-			if !accept {
-			}
-		}
-		if !accept {
-			p.ParserData.Seek(save)
+			accept = true
 		}
-	}
-	return accept
-}
-
-func (p *Peg) Literal() bool {
-	// Literal       <- '\'' (!'\'' Char) '\'' Spacing
-	//                / '"' (!'"' Char)+ '"' Spacing
-	accept := false
-	accept = true
-	start := p.ParserData.Pos()
-	{
-		save := p.ParserData.Pos()
-		{
-			save := p.ParserData.Pos()
-			if p.ParserData.Read() != '\'' {
-				p.ParserData.UnRead()
-				accept = false
-			} else {
-				accept = true
-			}
+		if accept {
+			accept = p.Prefix()
 			if accept {
 				{
-					save := p.ParserData.Pos()
-					s := p.ParserData.Pos()
-					if p.ParserData.Read() != '\'' {
-						p.ParserData.UnRead()
-						accept = false
-					} else {
-						accept = true
-					}
-					p.ParserData.Seek(s)
-					p.Root.Discard(s)
-					accept = !accept
-					if accept {
-						accept = p.Char()
-						if accept {
-						}
-					}
-					if !accept {
-						if p.LastError < p.ParserData.Pos() {
-							p.LastError = p.ParserData.Pos()
-						}
-						p.ParserData.Seek(save)
-					}
-				}
-				if accept {
-					if p.ParserData.Read() != '\'' {
-						p.ParserData.UnRead()
-						accept = false
-					} else {
-						accept = true
-					}
-					if accept {
-						accept = p.Spacing()
-						if accept {
-						}
-					}
-				}
-			}
-			if !accept {
-				if p.LastError < p.ParserData.Pos() {
-					p.LastError = p.ParserData.Pos()
-				}
-				p.ParserData.Seek(save)
-			}
-		}
-		if !accept {
-			{
-				save := p.ParserData.Pos()
-				if p.ParserData.Read() != '"' {
-					p.ParserData.UnRead()
-					accept = false
-				} else {
 					accept = true
-				}
-				if accept {
-					{
-						save := p.ParserData.Pos()
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
 						{
 							save := p.ParserData.Pos()
-							s := p.ParserData.Pos()
-							if p.ParserData.Read() != '"' {
-								p.ParserData.UnRead()
-								accept = false
-							} else {
-								accept = true
-							}
-							p.ParserData.Seek(s)
-							p.Root.Discard(s)
-							accept = !accept
-							if accept {
-								accept = p.Char()
-								if accept {
+							saved := p.Backtrack.Save(save)
+							accept = p.CUT()
+							if !accept {
+								accept = p.Prefix()
+								if !accept {
 								}
 							}
+							p.Backtrack.Release(saved)
 							if !accept {
-								if p.LastError < p.ParserData.Pos() {
-									p.LastError = p.ParserData.Pos()
-								}
 								p.ParserData.Seek(save)
 							}
 						}
-						if !accept {
-							p.ParserData.Seek(save)
-						} else {
-							for accept {
-								{
-									save := p.ParserData.Pos()
-									s := p.ParserData.Pos()
-									if p.ParserData.Read() != '"' {
-										p.ParserData.UnRead()
-										accept = false
-									} else {
-										accept = true
-									}
-									p.ParserData.Seek(s)
-									p.Root.Discard(s)
-									accept = !accept
-									if accept {
-										accept = p.Char()
-										if accept {
-										}
-									}
-									if !accept {
-										if p.LastError < p.ParserData.Pos() {
-											p.LastError = p.ParserData.Pos()
-										}
-										p.ParserData.Seek(save)
-									}
-								}
-							}
-							accept = true
-						}
-					}
-					if accept {
-						if p.ParserData.Read() != '"' {
-							p.ParserData.UnRead()
-							accept = false
-						} else {
-							accept = true
-						}
-						if accept {
-							accept = p.Spacing()
-							if accept {
-							}
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
 						}
 					}
+					accept = true
 				}
-				if !accept {
-					if p.LastError < p.ParserData.Pos() {
-						p.LastError = p.ParserData.Pos()
+				if accept {
+					{
+						saved := p.Backtrack.Save(p.ParserData.Pos())
+						accept = p.Action()
+						p.Backtrack.Release(saved)
+						accept = true
+					}
+					if accept {
 					}
-					p.ParserData.Seek(save)
 				}
 			}
-			if !accept {
-			}
 		}
 		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
 			p.ParserData.Seek(save)
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Sequence")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Literal"
+		node.Name = "Sequence"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Class() bool {
-	// Class         <- '[' (!']' Range)+ ']' Spacing
+func (p *Peg) Prefix() bool {
+	// Prefix        <- (AND / NOT)? Label? Suffix
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '[' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
-		}
-		if accept {
+		{
+			saved := p.Backtrack.Save(p.ParserData.Pos())
 			{
 				save := p.ParserData.Pos()
-				{
-					save := p.ParserData.Pos()
-					s := p.ParserData.Pos()
-					if p.ParserData.Read() != ']' {
-						p.ParserData.UnRead()
-						accept = false
-					} else {
-						accept = true
-					}
-					p.ParserData.Seek(s)
-					p.Root.Discard(s)
-					accept = !accept
-					if accept {
-						accept = p.Range()
-						if accept {
-						}
-					}
+				saved := p.Backtrack.Save(save)
+				accept = p.AND()
+				if !accept {
+					accept = p.NOT()
 					if !accept {
-						if p.LastError < p.ParserData.Pos() {
-							p.LastError = p.ParserData.Pos()
-						}
-						p.ParserData.Seek(save)
 					}
 				}
+				p.Backtrack.Release(saved)
 				if !accept {
 					p.ParserData.Seek(save)
-				} else {
-					for accept {
-						{
-							save := p.ParserData.Pos()
-							s := p.ParserData.Pos()
-							if p.ParserData.Read() != ']' {
-								p.ParserData.UnRead()
-								accept = false
-							} else {
-								accept = true
-							}
-							p.ParserData.Seek(s)
-							p.Root.Discard(s)
-							accept = !accept
-							if accept {
-								accept = p.Range()
-								if accept {
-								}
-							}
-							if !accept {
-								if p.LastError < p.ParserData.Pos() {
-									p.LastError = p.ParserData.Pos()
-								}
-								p.ParserData.Seek(save)
-							}
-						}
-					}
-					accept = true
 				}
 			}
+			p.Backtrack.Release(saved)
+			accept = true
+		}
+		if accept {
+			{
+				saved := p.Backtrack.Save(p.ParserData.Pos())
+				accept = p.Label()
+				p.Backtrack.Release(saved)
+				accept = true
+			}
 			if accept {
-				if p.ParserData.Read() != ']' {
-					p.ParserData.UnRead()
-					accept = false
-				} else {
-					accept = true
-				}
+				accept = p.Suffix()
 				if accept {
-					accept = p.Spacing()
-					if accept {
-					}
 				}
 			}
 		}
@@ -808,152 +689,174 @@ func (p *Peg) Class() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Prefix")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Class"
+		node.Name = "Prefix"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Range() bool {
-	// Range         <- Char '-' Char / Char
+func (p *Peg) Label() bool {
+	// Label         <- Identifier COLON
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		{
-			save := p.ParserData.Pos()
-			accept = p.Char()
+		accept = p.Identifier()
+		if accept {
+			accept = p.COLON()
 			if accept {
-				if p.ParserData.Read() != '-' {
-					p.ParserData.UnRead()
-					accept = false
-				} else {
-					accept = true
-				}
-				if accept {
-					accept = p.Char()
-					if accept {
-					}
-				}
-			}
-			if !accept {
-				if p.LastError < p.ParserData.Pos() {
-					p.LastError = p.ParserData.Pos()
-				}
-				p.ParserData.Seek(save)
 			}
 		}
 		if !accept {
-			accept = p.Char()
-			if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
 			}
-		}
-		if !accept {
 			p.ParserData.Seek(save)
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Label")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Range"
+		node.Name = "Label"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Char() bool {
-	// Char          <- '\\' [nrt'"\[\]\\]
-	//                / '\\' [0-2][0-7][0-7]
-	//                / '\\' [0-7][0-7]?
-	//                / "\\u" Hex Hex Hex Hex
-	//                / "\\U" Hex Hex Hex Hex Hex Hex Hex Hex
-	//                / !'\\' .
+func (p *Peg) Suffix() bool {
+	// Suffix        <- Primary (QUESTION / STAR / PLUS)?
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		{
-			save := p.ParserData.Pos()
-			if p.ParserData.Read() != '\\' {
-				p.ParserData.UnRead()
-				accept = false
-			} else {
-				accept = true
-			}
-			if accept {
+		accept = p.Primary()
+		if accept {
+			{
+				saved := p.Backtrack.Save(p.ParserData.Pos())
 				{
-					accept = false
-					c := p.ParserData.Read()
-					if c == 'n' || c == 'r' || c == 't' || c == '\'' || c == '"' || c == '[' || c == ']' || c == '\\' {
-						accept = true
-					} else {
-						p.ParserData.UnRead()
+					save := p.ParserData.Pos()
+					saved := p.Backtrack.Save(save)
+					accept = p.QUESTION()
+					if !accept {
+						accept = p.STAR()
+						if !accept {
+							accept = p.PLUS()
+							if !accept {
+							}
+						}
+					}
+					p.Backtrack.Release(saved)
+					if !accept {
+						p.ParserData.Seek(save)
 					}
 				}
-				if accept {
-				}
+				p.Backtrack.Release(saved)
+				accept = true
 			}
-			if !accept {
-				if p.LastError < p.ParserData.Pos() {
-					p.LastError = p.ParserData.Pos()
-				}
-				p.ParserData.Seek(save)
+			if accept {
 			}
 		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Suffix")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Suffix"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Primary() bool {
+	// Primary       <- Call
+	//                / Identifier !(Parameters? LEFTARROW)
+	//                / OPEN Expression CLOSE
+	//                / Literal / Class / DOT
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		accept = p.Call()
 		if !accept {
 			{
 				save := p.ParserData.Pos()
-				if p.ParserData.Read() != '\\' {
-					p.ParserData.UnRead()
-					accept = false
-				} else {
-					accept = true
-				}
+				accept = p.Identifier()
 				if accept {
-					c := p.ParserData.Read()
-					if c >= '0' && c <= '2' {
-						accept = true
-					} else {
-						p.ParserData.UnRead()
-						accept = false
-					}
-					if accept {
-						c := p.ParserData.Read()
-						if c >= '0' && c <= '7' {
-							accept = true
-						} else {
-							p.ParserData.UnRead()
-							accept = false
-						}
-						if accept {
-							c := p.ParserData.Read()
-							if c >= '0' && c <= '7' {
+					{
+						s := p.ParserData.Pos()
+						saved := p.Backtrack.Save(s)
+						p.Expected.Mute()
+						{
+							save := p.ParserData.Pos()
+							{
+								saved := p.Backtrack.Save(p.ParserData.Pos())
+								accept = p.Parameters()
+								p.Backtrack.Release(saved)
 								accept = true
-							} else {
-								p.ParserData.UnRead()
-								accept = false
 							}
 							if accept {
+								accept = p.LEFTARROW()
+								if accept {
+								}
+							}
+							if !accept {
+								if p.LastError < p.ParserData.Pos() {
+									p.LastError = p.ParserData.Pos()
+								}
+								p.ParserData.Seek(save)
 							}
 						}
+						p.Expected.Unmute()
+						p.Backtrack.Release(saved)
+						p.ParserData.Seek(s)
+						p.Root.Discard(s)
+						accept = !accept
+					}
+					if accept {
 					}
 				}
 				if !accept {
@@ -966,29 +869,11 @@ func (p *Peg) Char() bool {
 			if !accept {
 				{
 					save := p.ParserData.Pos()
-					if p.ParserData.Read() != '\\' {
-						p.ParserData.UnRead()
-						accept = false
-					} else {
-						accept = true
-					}
+					accept = p.OPEN()
 					if accept {
-						c := p.ParserData.Read()
-						if c >= '0' && c <= '7' {
-							accept = true
-						} else {
-							p.ParserData.UnRead()
-							accept = false
-						}
+						accept = p.Expression()
 						if accept {
-							c := p.ParserData.Read()
-							if c >= '0' && c <= '7' {
-								accept = true
-							} else {
-								p.ParserData.UnRead()
-								accept = false
-							}
-							accept = true
+							accept = p.CLOSE()
 							if accept {
 							}
 						}
@@ -1001,72 +886,2133 @@ func (p *Peg) Char() bool {
 					}
 				}
 				if !accept {
+					accept = p.Literal()
+					if !accept {
+						accept = p.Class()
+						if !accept {
+							accept = p.DOT()
+							if !accept {
+							}
+						}
+					}
+				}
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Primary")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Primary"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Call() bool {
+	// Call          <- &(IdentStart IdentCont* '(') Identifier
+	//                  OPEN Expression (COMMA Expression)* CLOSE !LEFTARROW
+	// # Lexical syntax
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			s := p.ParserData.Pos()
+			saved := p.Backtrack.Save(s)
+			p.Expected.Mute()
+			{
+				save := p.ParserData.Pos()
+				accept = p.IdentStart()
+				if accept {
 					{
-						save := p.ParserData.Pos()
+						accept = true
+						for accept {
+							pos := p.ParserData.Pos()
+							saved := p.Backtrack.Save(pos)
+							accept = p.IdentCont()
+							p.Backtrack.Release(saved)
+							if pos == p.ParserData.Pos() {
+								break
+							}
+						}
+						accept = true
+					}
+					if accept {
 						{
-							accept = true
-							s := p.ParserData.Pos()
-							if p.ParserData.Read() != '\\' || p.ParserData.Read() != 'u' {
-								p.ParserData.Seek(s)
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '(' {
+								p.ParserData.UnRead()
 								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"(\"")
 							}
 						}
 						if accept {
-							accept = p.Hex()
-							if accept {
-								accept = p.Hex()
-								if accept {
-									accept = p.Hex()
+						}
+					}
+				}
+				if !accept {
+					if p.LastError < p.ParserData.Pos() {
+						p.LastError = p.ParserData.Pos()
+					}
+					p.ParserData.Seek(save)
+				}
+			}
+			p.Expected.Unmute()
+			p.Backtrack.Release(saved)
+			p.ParserData.Seek(s)
+			p.Root.Discard(s)
+		}
+		if accept {
+			accept = p.Identifier()
+			if accept {
+				accept = p.OPEN()
+				if accept {
+					accept = p.Expression()
+					if accept {
+						{
+							accept = true
+							for accept {
+								pos := p.ParserData.Pos()
+								saved := p.Backtrack.Save(pos)
+								{
+									save := p.ParserData.Pos()
+									accept = p.COMMA()
 									if accept {
-										accept = p.Hex()
+										accept = p.Expression()
 										if accept {
 										}
 									}
+									if !accept {
+										if p.LastError < p.ParserData.Pos() {
+											p.LastError = p.ParserData.Pos()
+										}
+										p.ParserData.Seek(save)
+									}
+								}
+								p.Backtrack.Release(saved)
+								if pos == p.ParserData.Pos() {
+									break
 								}
 							}
+							accept = true
 						}
-						if !accept {
-							if p.LastError < p.ParserData.Pos() {
-								p.LastError = p.ParserData.Pos()
-							}
-							p.ParserData.Seek(save)
-						}
+						if accept {
+							accept = p.CLOSE()
+							if accept {
+								{
+									s := p.ParserData.Pos()
+									saved := p.Backtrack.Save(s)
+									p.Expected.Mute()
+									accept = p.LEFTARROW()
+									p.Expected.Unmute()
+									p.Backtrack.Release(saved)
+									p.ParserData.Seek(s)
+									p.Root.Discard(s)
+									accept = !accept
+								}
+								if accept {
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Call")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Call"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Identifier() bool {
+	// Identifier    <- IdentStart IdentCont* Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		accept = p.IdentStart()
+		if accept {
+			{
+				accept = true
+				for accept {
+					pos := p.ParserData.Pos()
+					saved := p.Backtrack.Save(pos)
+					accept = p.IdentCont()
+					p.Backtrack.Release(saved)
+					if pos == p.ParserData.Pos() {
+						break
+					}
+				}
+				accept = true
+			}
+			if accept {
+				accept = p.Spacing()
+				if accept {
+				}
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Identifier")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Identifier"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) IdentStart() bool {
+	// IdentStart    <- [a-zA-Z_]
+	accept := false
+	{
+		pos := p.ParserData.Pos()
+		{
+			save := p.ParserData.Pos()
+			saved := p.Backtrack.Save(save)
+			c := p.ParserData.Read()
+			if c >= 'a' && c <= 'z' {
+				accept = true
+			} else {
+				p.ParserData.UnRead()
+				accept = false
+			}
+			if !accept {
+				c := p.ParserData.Read()
+				if c >= 'A' && c <= 'Z' {
+					accept = true
+				} else {
+					p.ParserData.UnRead()
+					accept = false
+				}
+				if !accept {
+					{
+						accept = false
+						c := p.ParserData.Read()
+						if c == '_' {
+							accept = true
+						} else {
+							p.ParserData.UnRead()
+						}
+					}
+					if !accept {
+					}
+				}
+			}
+			p.Backtrack.Release(saved)
+			if !accept {
+				p.ParserData.Seek(save)
+			}
+		}
+		if !accept {
+			p.Expected.Add(pos, "[a-zA-Z_]")
+		}
+	}
+	return accept
+}
+
+func (p *Peg) IdentCont() bool {
+	// IdentCont     <- IdentStart / [0-9]
+	accept := false
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		accept = p.IdentStart()
+		if !accept {
+			{
+				pos := p.ParserData.Pos()
+				c := p.ParserData.Read()
+				if c >= '0' && c <= '9' {
+					accept = true
+				} else {
+					p.ParserData.UnRead()
+					accept = false
+				}
+				if !accept {
+					p.Expected.Add(pos, "[0-9]")
+				}
+			}
+			if !accept {
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	return accept
+}
+
+func (p *Peg) Literal() bool {
+	// Literal       <- '\'' (!'\'' Char) '\'' IgnoreCase? Spacing
+	//                / '"' (!'"' Char)+ '"' IgnoreCase? Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '\'' {
+					p.ParserData.UnRead()
+					accept = false
+				} else {
+					accept = true
+				}
+				if !accept {
+					p.Expected.Add(pos, "\"'\"")
+				}
+			}
+			if accept {
+				{
+					save := p.ParserData.Pos()
+					{
+						s := p.ParserData.Pos()
+						saved := p.Backtrack.Save(s)
+						p.Expected.Mute()
+						{
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '\'' {
+								p.ParserData.UnRead()
+								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"'\"")
+							}
+						}
+						p.Expected.Unmute()
+						p.Backtrack.Release(saved)
+						p.ParserData.Seek(s)
+						p.Root.Discard(s)
+						accept = !accept
+					}
+					if accept {
+						accept = p.Char()
+						if accept {
+						}
+					}
+					if !accept {
+						if p.LastError < p.ParserData.Pos() {
+							p.LastError = p.ParserData.Pos()
+						}
+						p.ParserData.Seek(save)
+					}
+				}
+				if accept {
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != '\'' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"'\"")
+						}
+					}
+					if accept {
+						{
+							saved := p.Backtrack.Save(p.ParserData.Pos())
+							accept = p.IgnoreCase()
+							p.Backtrack.Release(saved)
+							accept = true
+						}
+						if accept {
+							accept = p.Spacing()
+							if accept {
+							}
+						}
+					}
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+			}
+		}
+		if !accept {
+			{
+				save := p.ParserData.Pos()
+				{
+					pos := p.ParserData.Pos()
+					if p.ParserData.Read() != '"' {
+						p.ParserData.UnRead()
+						accept = false
+					} else {
+						accept = true
 					}
 					if !accept {
+						p.Expected.Add(pos, "\"\\\"\"")
+					}
+				}
+				if accept {
+					{
+						save := p.ParserData.Pos()
 						{
 							save := p.ParserData.Pos()
 							{
-								accept = true
 								s := p.ParserData.Pos()
-								if p.ParserData.Read() != '\\' || p.ParserData.Read() != 'U' {
-									p.ParserData.Seek(s)
-									accept = false
+								saved := p.Backtrack.Save(s)
+								p.Expected.Mute()
+								{
+									pos := p.ParserData.Pos()
+									if p.ParserData.Read() != '"' {
+										p.ParserData.UnRead()
+										accept = false
+									} else {
+										accept = true
+									}
+									if !accept {
+										p.Expected.Add(pos, "\"\\\"\"")
+									}
+								}
+								p.Expected.Unmute()
+								p.Backtrack.Release(saved)
+								p.ParserData.Seek(s)
+								p.Root.Discard(s)
+								accept = !accept
+							}
+							if accept {
+								accept = p.Char()
+								if accept {
+								}
+							}
+							if !accept {
+								if p.LastError < p.ParserData.Pos() {
+									p.LastError = p.ParserData.Pos()
+								}
+								p.ParserData.Seek(save)
+							}
+						}
+						if !accept {
+							p.ParserData.Seek(save)
+						} else {
+							for accept {
+								pos := p.ParserData.Pos()
+								saved := p.Backtrack.Save(pos)
+								{
+									save := p.ParserData.Pos()
+									{
+										s := p.ParserData.Pos()
+										saved := p.Backtrack.Save(s)
+										p.Expected.Mute()
+										{
+											pos := p.ParserData.Pos()
+											if p.ParserData.Read() != '"' {
+												p.ParserData.UnRead()
+												accept = false
+											} else {
+												accept = true
+											}
+											if !accept {
+												p.Expected.Add(pos, "\"\\\"\"")
+											}
+										}
+										p.Expected.Unmute()
+										p.Backtrack.Release(saved)
+										p.ParserData.Seek(s)
+										p.Root.Discard(s)
+										accept = !accept
+									}
+									if accept {
+										accept = p.Char()
+										if accept {
+										}
+									}
+									if !accept {
+										if p.LastError < p.ParserData.Pos() {
+											p.LastError = p.ParserData.Pos()
+										}
+										p.ParserData.Seek(save)
+									}
+								}
+								p.Backtrack.Release(saved)
+								if pos == p.ParserData.Pos() {
+									break
+								}
+							}
+							accept = true
+						}
+					}
+					if accept {
+						{
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '"' {
+								p.ParserData.UnRead()
+								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"\\\"\"")
+							}
+						}
+						if accept {
+							{
+								saved := p.Backtrack.Save(p.ParserData.Pos())
+								accept = p.IgnoreCase()
+								p.Backtrack.Release(saved)
+								accept = true
+							}
+							if accept {
+								accept = p.Spacing()
+								if accept {
+								}
+							}
+						}
+					}
+				}
+				if !accept {
+					if p.LastError < p.ParserData.Pos() {
+						p.LastError = p.ParserData.Pos()
+					}
+					p.ParserData.Seek(save)
+				}
+			}
+			if !accept {
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Literal")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Literal"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) IgnoreCase() bool {
+	// IgnoreCase    <- 'i' !IdentCont
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != 'i' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"i\"")
+			}
+		}
+		if accept {
+			{
+				s := p.ParserData.Pos()
+				saved := p.Backtrack.Save(s)
+				p.Expected.Mute()
+				accept = p.IdentCont()
+				p.Expected.Unmute()
+				p.Backtrack.Release(saved)
+				p.ParserData.Seek(s)
+				p.Root.Discard(s)
+				accept = !accept
+			}
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "IgnoreCase")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "IgnoreCase"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Class() bool {
+	// Class         <- '[' Negation? (!']' Range)+ ']' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '[' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"[\"")
+			}
+		}
+		if accept {
+			{
+				saved := p.Backtrack.Save(p.ParserData.Pos())
+				accept = p.Negation()
+				p.Backtrack.Release(saved)
+				accept = true
+			}
+			if accept {
+				{
+					save := p.ParserData.Pos()
+					{
+						save := p.ParserData.Pos()
+						{
+							s := p.ParserData.Pos()
+							saved := p.Backtrack.Save(s)
+							p.Expected.Mute()
+							{
+								pos := p.ParserData.Pos()
+								if p.ParserData.Read() != ']' {
+									p.ParserData.UnRead()
+									accept = false
+								} else {
+									accept = true
+								}
+								if !accept {
+									p.Expected.Add(pos, "\"]\"")
+								}
+							}
+							p.Expected.Unmute()
+							p.Backtrack.Release(saved)
+							p.ParserData.Seek(s)
+							p.Root.Discard(s)
+							accept = !accept
+						}
+						if accept {
+							accept = p.Range()
+							if accept {
+							}
+						}
+						if !accept {
+							if p.LastError < p.ParserData.Pos() {
+								p.LastError = p.ParserData.Pos()
+							}
+							p.ParserData.Seek(save)
+						}
+					}
+					if !accept {
+						p.ParserData.Seek(save)
+					} else {
+						for accept {
+							pos := p.ParserData.Pos()
+							saved := p.Backtrack.Save(pos)
+							{
+								save := p.ParserData.Pos()
+								{
+									s := p.ParserData.Pos()
+									saved := p.Backtrack.Save(s)
+									p.Expected.Mute()
+									{
+										pos := p.ParserData.Pos()
+										if p.ParserData.Read() != ']' {
+											p.ParserData.UnRead()
+											accept = false
+										} else {
+											accept = true
+										}
+										if !accept {
+											p.Expected.Add(pos, "\"]\"")
+										}
+									}
+									p.Expected.Unmute()
+									p.Backtrack.Release(saved)
+									p.ParserData.Seek(s)
+									p.Root.Discard(s)
+									accept = !accept
+								}
+								if accept {
+									accept = p.Range()
+									if accept {
+									}
+								}
+								if !accept {
+									if p.LastError < p.ParserData.Pos() {
+										p.LastError = p.ParserData.Pos()
+									}
+									p.ParserData.Seek(save)
+								}
+							}
+							p.Backtrack.Release(saved)
+							if pos == p.ParserData.Pos() {
+								break
+							}
+						}
+						accept = true
+					}
+				}
+				if accept {
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != ']' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"]\"")
+						}
+					}
+					if accept {
+						accept = p.Spacing()
+						if accept {
+						}
+					}
+				}
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Class")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Class"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Negation() bool {
+	// Negation      <- '^'
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		pos := p.ParserData.Pos()
+		if p.ParserData.Read() != '^' {
+			p.ParserData.UnRead()
+			accept = false
+		} else {
+			accept = true
+		}
+		if !accept {
+			p.Expected.Add(pos, "\"^\"")
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Negation")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Negation"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Range() bool {
+	// Range         <- Property / Char '-' Char / Char
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		accept = p.Property()
+		if !accept {
+			{
+				save := p.ParserData.Pos()
+				accept = p.Char()
+				if accept {
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != '-' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"-\"")
+						}
+					}
+					if accept {
+						accept = p.Char()
+						if accept {
+						}
+					}
+				}
+				if !accept {
+					if p.LastError < p.ParserData.Pos() {
+						p.LastError = p.ParserData.Pos()
+					}
+					p.ParserData.Seek(save)
+				}
+			}
+			if !accept {
+				accept = p.Char()
+				if !accept {
+				}
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Range")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Range"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Property() bool {
+	// Property      <- "\\p{" [a-zA-Z_]+ '}'
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '\\' || p.ParserData.Read() != 'p' || p.ParserData.Read() != '{' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"\\\\p{\"")
+			}
+		}
+		if accept {
+			{
+				save := p.ParserData.Pos()
+				{
+					pos := p.ParserData.Pos()
+					{
+						save := p.ParserData.Pos()
+						saved := p.Backtrack.Save(save)
+						c := p.ParserData.Read()
+						if c >= 'a' && c <= 'z' {
+							accept = true
+						} else {
+							p.ParserData.UnRead()
+							accept = false
+						}
+						if !accept {
+							c := p.ParserData.Read()
+							if c >= 'A' && c <= 'Z' {
+								accept = true
+							} else {
+								p.ParserData.UnRead()
+								accept = false
+							}
+							if !accept {
+								{
+									accept = false
+									c := p.ParserData.Read()
+									if c == '_' {
+										accept = true
+									} else {
+										p.ParserData.UnRead()
+									}
+								}
+								if !accept {
+								}
+							}
+						}
+						p.Backtrack.Release(saved)
+						if !accept {
+							p.ParserData.Seek(save)
+						}
+					}
+					if !accept {
+						p.Expected.Add(pos, "[a-zA-Z_]")
+					}
+				}
+				if !accept {
+					p.ParserData.Seek(save)
+				} else {
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
+						{
+							pos := p.ParserData.Pos()
+							{
+								save := p.ParserData.Pos()
+								saved := p.Backtrack.Save(save)
+								c := p.ParserData.Read()
+								if c >= 'a' && c <= 'z' {
+									accept = true
+								} else {
+									p.ParserData.UnRead()
+									accept = false
+								}
+								if !accept {
+									c := p.ParserData.Read()
+									if c >= 'A' && c <= 'Z' {
+										accept = true
+									} else {
+										p.ParserData.UnRead()
+										accept = false
+									}
+									if !accept {
+										{
+											accept = false
+											c := p.ParserData.Read()
+											if c == '_' {
+												accept = true
+											} else {
+												p.ParserData.UnRead()
+											}
+										}
+										if !accept {
+										}
+									}
+								}
+								p.Backtrack.Release(saved)
+								if !accept {
+									p.ParserData.Seek(save)
+								}
+							}
+							if !accept {
+								p.Expected.Add(pos, "[a-zA-Z_]")
+							}
+						}
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
+						}
+					}
+					accept = true
+				}
+			}
+			if accept {
+				{
+					pos := p.ParserData.Pos()
+					if p.ParserData.Read() != '}' {
+						p.ParserData.UnRead()
+						accept = false
+					} else {
+						accept = true
+					}
+					if !accept {
+						p.Expected.Add(pos, "\"}\"")
+					}
+				}
+				if accept {
+				}
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Property")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Property"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Char() bool {
+	// Char          <- '\\' [nrt'"\[\]\\]
+	//                / '\\' [0-2][0-7][0-7]
+	//                / '\\' [0-7][0-7]?
+	//                / "\\u" Hex Hex Hex Hex
+	//                / "\\U" Hex Hex Hex Hex Hex Hex Hex Hex
+	//                / !'\\' .
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '\\' {
+					p.ParserData.UnRead()
+					accept = false
+				} else {
+					accept = true
+				}
+				if !accept {
+					p.Expected.Add(pos, "\"\\\\\"")
+				}
+			}
+			if accept {
+				{
+					pos := p.ParserData.Pos()
+					{
+						accept = false
+						c := p.ParserData.Read()
+						if c == 'n' || c == 'r' || c == 't' || c == '\'' || c == '"' || c == '[' || c == ']' || c == '\\' {
+							accept = true
+						} else {
+							p.ParserData.UnRead()
+						}
+					}
+					if !accept {
+						p.Expected.Add(pos, "[nrt'\"\\[\\]\\\\]")
+					}
+				}
+				if accept {
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+			}
+		}
+		if !accept {
+			{
+				save := p.ParserData.Pos()
+				{
+					pos := p.ParserData.Pos()
+					if p.ParserData.Read() != '\\' {
+						p.ParserData.UnRead()
+						accept = false
+					} else {
+						accept = true
+					}
+					if !accept {
+						p.Expected.Add(pos, "\"\\\\\"")
+					}
+				}
+				if accept {
+					{
+						pos := p.ParserData.Pos()
+						c := p.ParserData.Read()
+						if c >= '0' && c <= '2' {
+							accept = true
+						} else {
+							p.ParserData.UnRead()
+							accept = false
+						}
+						if !accept {
+							p.Expected.Add(pos, "[0-2]")
+						}
+					}
+					if accept {
+						{
+							pos := p.ParserData.Pos()
+							c := p.ParserData.Read()
+							if c >= '0' && c <= '7' {
+								accept = true
+							} else {
+								p.ParserData.UnRead()
+								accept = false
+							}
+							if !accept {
+								p.Expected.Add(pos, "[0-7]")
+							}
+						}
+						if accept {
+							{
+								pos := p.ParserData.Pos()
+								c := p.ParserData.Read()
+								if c >= '0' && c <= '7' {
+									accept = true
+								} else {
+									p.ParserData.UnRead()
+									accept = false
+								}
+								if !accept {
+									p.Expected.Add(pos, "[0-7]")
+								}
+							}
+							if accept {
+							}
+						}
+					}
+				}
+				if !accept {
+					if p.LastError < p.ParserData.Pos() {
+						p.LastError = p.ParserData.Pos()
+					}
+					p.ParserData.Seek(save)
+				}
+			}
+			if !accept {
+				{
+					save := p.ParserData.Pos()
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != '\\' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"\\\\\"")
+						}
+					}
+					if accept {
+						{
+							pos := p.ParserData.Pos()
+							c := p.ParserData.Read()
+							if c >= '0' && c <= '7' {
+								accept = true
+							} else {
+								p.ParserData.UnRead()
+								accept = false
+							}
+							if !accept {
+								p.Expected.Add(pos, "[0-7]")
+							}
+						}
+						if accept {
+							{
+								saved := p.Backtrack.Save(p.ParserData.Pos())
+								{
+									pos := p.ParserData.Pos()
+									c := p.ParserData.Read()
+									if c >= '0' && c <= '7' {
+										accept = true
+									} else {
+										p.ParserData.UnRead()
+										accept = false
+									}
+									if !accept {
+										p.Expected.Add(pos, "[0-7]")
+									}
+								}
+								p.Backtrack.Release(saved)
+								accept = true
+							}
+							if accept {
+							}
+						}
+					}
+					if !accept {
+						if p.LastError < p.ParserData.Pos() {
+							p.LastError = p.ParserData.Pos()
+						}
+						p.ParserData.Seek(save)
+					}
+				}
+				if !accept {
+					{
+						save := p.ParserData.Pos()
+						{
+							pos := p.ParserData.Pos()
+							{
+								accept = true
+								s := p.ParserData.Pos()
+								if p.ParserData.Read() != '\\' || p.ParserData.Read() != 'u' {
+									p.ParserData.Seek(s)
+									accept = false
+								}
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"\\\\u\"")
+							}
+						}
+						if accept {
+							accept = p.Hex()
+							if accept {
+								accept = p.Hex()
+								if accept {
+									accept = p.Hex()
+									if accept {
+										accept = p.Hex()
+										if accept {
+										}
+									}
+								}
+							}
+						}
+						if !accept {
+							if p.LastError < p.ParserData.Pos() {
+								p.LastError = p.ParserData.Pos()
+							}
+							p.ParserData.Seek(save)
+						}
+					}
+					if !accept {
+						{
+							save := p.ParserData.Pos()
+							{
+								pos := p.ParserData.Pos()
+								{
+									accept = true
+									s := p.ParserData.Pos()
+									if p.ParserData.Read() != '\\' || p.ParserData.Read() != 'U' {
+										p.ParserData.Seek(s)
+										accept = false
+									}
+								}
+								if !accept {
+									p.Expected.Add(pos, "\"\\\\U\"")
+								}
+							}
+							if accept {
+								accept = p.Hex()
+								if accept {
+									accept = p.Hex()
+									if accept {
+										accept = p.Hex()
+										if accept {
+											accept = p.Hex()
+											if accept {
+												accept = p.Hex()
+												if accept {
+													accept = p.Hex()
+													if accept {
+														accept = p.Hex()
+														if accept {
+															accept = p.Hex()
+															if accept {
+															}
+														}
+													}
+												}
+											}
+										}
+									}
+								}
+							}
+							if !accept {
+								if p.LastError < p.ParserData.Pos() {
+									p.LastError = p.ParserData.Pos()
+								}
+								p.ParserData.Seek(save)
+							}
+						}
+						if !accept {
+							{
+								save := p.ParserData.Pos()
+								{
+									s := p.ParserData.Pos()
+									saved := p.Backtrack.Save(s)
+									p.Expected.Mute()
+									{
+										pos := p.ParserData.Pos()
+										if p.ParserData.Read() != '\\' {
+											p.ParserData.UnRead()
+											accept = false
+										} else {
+											accept = true
+										}
+										if !accept {
+											p.Expected.Add(pos, "\"\\\\\"")
+										}
+									}
+									p.Expected.Unmute()
+									p.Backtrack.Release(saved)
+									p.ParserData.Seek(s)
+									p.Root.Discard(s)
+									accept = !accept
+								}
+								if accept {
+									{
+										pos := p.ParserData.Pos()
+										if p.ParserData.Pos() >= p.ParserData.Len() {
+											accept = false
+										} else {
+											p.ParserData.Read()
+											accept = true
+										}
+										if !accept {
+											p.Expected.Add(pos, "any character")
+										}
+									}
+									if accept {
+									}
+								}
+								if !accept {
+									if p.LastError < p.ParserData.Pos() {
+										p.LastError = p.ParserData.Pos()
+									}
+									p.ParserData.Seek(save)
+								}
+							}
+							if !accept {
+							}
+						}
+					}
+				}
+			}
+		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Char")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Char"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Hex() bool {
+	// Hex           <- [A-Fa-f0-9]
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		pos := p.ParserData.Pos()
+		{
+			save := p.ParserData.Pos()
+			saved := p.Backtrack.Save(save)
+			c := p.ParserData.Read()
+			if c >= 'A' && c <= 'F' {
+				accept = true
+			} else {
+				p.ParserData.UnRead()
+				accept = false
+			}
+			if !accept {
+				c := p.ParserData.Read()
+				if c >= 'a' && c <= 'f' {
+					accept = true
+				} else {
+					p.ParserData.UnRead()
+					accept = false
+				}
+				if !accept {
+					c := p.ParserData.Read()
+					if c >= '0' && c <= '9' {
+						accept = true
+					} else {
+						p.ParserData.UnRead()
+						accept = false
+					}
+					if !accept {
+					}
+				}
+			}
+			p.Backtrack.Release(saved)
+			if !accept {
+				p.ParserData.Seek(save)
+			}
+		}
+		if !accept {
+			p.Expected.Add(pos, "[A-Fa-f0-9]")
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Hex")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Hex"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Action() bool {
+	// Action        <- '{' Code '}' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '{' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"{\"")
+			}
+		}
+		if accept {
+			accept = p.Code()
+			if accept {
+				{
+					pos := p.ParserData.Pos()
+					if p.ParserData.Read() != '}' {
+						p.ParserData.UnRead()
+						accept = false
+					} else {
+						accept = true
+					}
+					if !accept {
+						p.Expected.Add(pos, "\"}\"")
+					}
+				}
+				if accept {
+					accept = p.Spacing()
+					if accept {
+					}
+				}
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Action")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Action"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) Code() bool {
+	// Code          <- CodeChunk*
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		accept = true
+		for accept {
+			pos := p.ParserData.Pos()
+			saved := p.Backtrack.Save(pos)
+			accept = p.CodeChunk()
+			p.Backtrack.Release(saved)
+			if pos == p.ParserData.Pos() {
+				break
+			}
+		}
+		accept = true
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "Code")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "Code"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) CodeChunk() bool {
+	// CodeChunk     <- '{' CodeChunk* '}'
+	//                / '"' ('\\' . / ![\\"\n] .)* '"'
+	//                / '\'' ('\\' . / ![\\'\n] .)* '\''
+	//                / '`' (!'`' .)* '`'
+	//                / "//" (!EndOfLine .)*
+	//                / "/*" (!"*/" .)* "*/"
+	//                / ![{}] .
+	accept := false
+	{
+		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
+		{
+			save := p.ParserData.Pos()
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '{' {
+					p.ParserData.UnRead()
+					accept = false
+				} else {
+					accept = true
+				}
+				if !accept {
+					p.Expected.Add(pos, "\"{\"")
+				}
+			}
+			if accept {
+				{
+					accept = true
+					for accept {
+						pos := p.ParserData.Pos()
+						saved := p.Backtrack.Save(pos)
+						accept = p.CodeChunk()
+						p.Backtrack.Release(saved)
+						if pos == p.ParserData.Pos() {
+							break
+						}
+					}
+					accept = true
+				}
+				if accept {
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != '}' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"}\"")
+						}
+					}
+					if accept {
+					}
+				}
+			}
+			if !accept {
+				if p.LastError < p.ParserData.Pos() {
+					p.LastError = p.ParserData.Pos()
+				}
+				p.ParserData.Seek(save)
+			}
+		}
+		if !accept {
+			{
+				save := p.ParserData.Pos()
+				{
+					pos := p.ParserData.Pos()
+					if p.ParserData.Read() != '"' {
+						p.ParserData.UnRead()
+						accept = false
+					} else {
+						accept = true
+					}
+					if !accept {
+						p.Expected.Add(pos, "\"\\\"\"")
+					}
+				}
+				if accept {
+					{
+						accept = true
+						for accept {
+							pos := p.ParserData.Pos()
+							saved := p.Backtrack.Save(pos)
+							{
+								save := p.ParserData.Pos()
+								saved := p.Backtrack.Save(save)
+								{
+									save := p.ParserData.Pos()
+									{
+										pos := p.ParserData.Pos()
+										if p.ParserData.Read() != '\\' {
+											p.ParserData.UnRead()
+											accept = false
+										} else {
+											accept = true
+										}
+										if !accept {
+											p.Expected.Add(pos, "\"\\\\\"")
+										}
+									}
+									if accept {
+										{
+											pos := p.ParserData.Pos()
+											if p.ParserData.Pos() >= p.ParserData.Len() {
+												accept = false
+											} else {
+												p.ParserData.Read()
+												accept = true
+											}
+											if !accept {
+												p.Expected.Add(pos, "any character")
+											}
+										}
+										if accept {
+										}
+									}
+									if !accept {
+										if p.LastError < p.ParserData.Pos() {
+											p.LastError = p.ParserData.Pos()
+										}
+										p.ParserData.Seek(save)
+									}
+								}
+								if !accept {
+									{
+										save := p.ParserData.Pos()
+										{
+											s := p.ParserData.Pos()
+											saved := p.Backtrack.Save(s)
+											p.Expected.Mute()
+											{
+												pos := p.ParserData.Pos()
+												{
+													accept = false
+													c := p.ParserData.Read()
+													if c == '\\' || c == '"' || c == '\n' {
+														accept = true
+													} else {
+														p.ParserData.UnRead()
+													}
+												}
+												if !accept {
+													p.Expected.Add(pos, "[\\\\\"\\n]")
+												}
+											}
+											p.Expected.Unmute()
+											p.Backtrack.Release(saved)
+											p.ParserData.Seek(s)
+											p.Root.Discard(s)
+											accept = !accept
+										}
+										if accept {
+											{
+												pos := p.ParserData.Pos()
+												if p.ParserData.Pos() >= p.ParserData.Len() {
+													accept = false
+												} else {
+													p.ParserData.Read()
+													accept = true
+												}
+												if !accept {
+													p.Expected.Add(pos, "any character")
+												}
+											}
+											if accept {
+											}
+										}
+										if !accept {
+											if p.LastError < p.ParserData.Pos() {
+												p.LastError = p.ParserData.Pos()
+											}
+											p.ParserData.Seek(save)
+										}
+									}
+									if !accept {
+									}
+								}
+								p.Backtrack.Release(saved)
+								if !accept {
+									p.ParserData.Seek(save)
+								}
+							}
+							p.Backtrack.Release(saved)
+							if pos == p.ParserData.Pos() {
+								break
+							}
+						}
+						accept = true
+					}
+					if accept {
+						{
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '"' {
+								p.ParserData.UnRead()
+								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"\\\"\"")
+							}
+						}
+						if accept {
+						}
+					}
+				}
+				if !accept {
+					if p.LastError < p.ParserData.Pos() {
+						p.LastError = p.ParserData.Pos()
+					}
+					p.ParserData.Seek(save)
+				}
+			}
+			if !accept {
+				{
+					save := p.ParserData.Pos()
+					{
+						pos := p.ParserData.Pos()
+						if p.ParserData.Read() != '\'' {
+							p.ParserData.UnRead()
+							accept = false
+						} else {
+							accept = true
+						}
+						if !accept {
+							p.Expected.Add(pos, "\"'\"")
+						}
+					}
+					if accept {
+						{
+							accept = true
+							for accept {
+								pos := p.ParserData.Pos()
+								saved := p.Backtrack.Save(pos)
+								{
+									save := p.ParserData.Pos()
+									saved := p.Backtrack.Save(save)
+									{
+										save := p.ParserData.Pos()
+										{
+											pos := p.ParserData.Pos()
+											if p.ParserData.Read() != '\\' {
+												p.ParserData.UnRead()
+												accept = false
+											} else {
+												accept = true
+											}
+											if !accept {
+												p.Expected.Add(pos, "\"\\\\\"")
+											}
+										}
+										if accept {
+											{
+												pos := p.ParserData.Pos()
+												if p.ParserData.Pos() >= p.ParserData.Len() {
+													accept = false
+												} else {
+													p.ParserData.Read()
+													accept = true
+												}
+												if !accept {
+													p.Expected.Add(pos, "any character")
+												}
+											}
+											if accept {
+											}
+										}
+										if !accept {
+											if p.LastError < p.ParserData.Pos() {
+												p.LastError = p.ParserData.Pos()
+											}
+											p.ParserData.Seek(save)
+										}
+									}
+									if !accept {
+										{
+											save := p.ParserData.Pos()
+											{
+												s := p.ParserData.Pos()
+												saved := p.Backtrack.Save(s)
+												p.Expected.Mute()
+												{
+													pos := p.ParserData.Pos()
+													{
+														accept = false
+														c := p.ParserData.Read()
+														if c == '\\' || c == '\'' || c == '\n' {
+															accept = true
+														} else {
+															p.ParserData.UnRead()
+														}
+													}
+													if !accept {
+														p.Expected.Add(pos, "[\\\\'\\n]")
+													}
+												}
+												p.Expected.Unmute()
+												p.Backtrack.Release(saved)
+												p.ParserData.Seek(s)
+												p.Root.Discard(s)
+												accept = !accept
+											}
+											if accept {
+												{
+													pos := p.ParserData.Pos()
+													if p.ParserData.Pos() >= p.ParserData.Len() {
+														accept = false
+													} else {
+														p.ParserData.Read()
+														accept = true
+													}
+													if !accept {
+														p.Expected.Add(pos, "any character")
+													}
+												}
+												if accept {
+												}
+											}
+											if !accept {
+												if p.LastError < p.ParserData.Pos() {
+													p.LastError = p.ParserData.Pos()
+												}
+												p.ParserData.Seek(save)
+											}
+										}
+										if !accept {
+										}
+									}
+									p.Backtrack.Release(saved)
+									if !accept {
+										p.ParserData.Seek(save)
+									}
+								}
+								p.Backtrack.Release(saved)
+								if pos == p.ParserData.Pos() {
+									break
+								}
+							}
+							accept = true
+						}
+						if accept {
+							{
+								pos := p.ParserData.Pos()
+								if p.ParserData.Read() != '\'' {
+									p.ParserData.UnRead()
+									accept = false
+								} else {
+									accept = true
+								}
+								if !accept {
+									p.Expected.Add(pos, "\"'\"")
+								}
+							}
+							if accept {
+							}
+						}
+					}
+					if !accept {
+						if p.LastError < p.ParserData.Pos() {
+							p.LastError = p.ParserData.Pos()
+						}
+						p.ParserData.Seek(save)
+					}
+				}
+				if !accept {
+					{
+						save := p.ParserData.Pos()
+						{
+							pos := p.ParserData.Pos()
+							if p.ParserData.Read() != '`' {
+								p.ParserData.UnRead()
+								accept = false
+							} else {
+								accept = true
+							}
+							if !accept {
+								p.Expected.Add(pos, "\"`\"")
+							}
+						}
+						if accept {
+							{
+								accept = true
+								for accept {
+									pos := p.ParserData.Pos()
+									saved := p.Backtrack.Save(pos)
+									{
+										save := p.ParserData.Pos()
+										{
+											s := p.ParserData.Pos()
+											saved := p.Backtrack.Save(s)
+											p.Expected.Mute()
+											{
+												pos := p.ParserData.Pos()
+												if p.ParserData.Read() != '`' {
+													p.ParserData.UnRead()
+													accept = false
+												} else {
+													accept = true
+												}
+												if !accept {
+													p.Expected.Add(pos, "\"`\"")
+												}
+											}
+											p.Expected.Unmute()
+											p.Backtrack.Release(saved)
+											p.ParserData.Seek(s)
+											p.Root.Discard(s)
+											accept = !accept
+										}
+										if accept {
+											{
+												pos := p.ParserData.Pos()
+												if p.ParserData.Pos() >= p.ParserData.Len() {
+													accept = false
+												} else {
+													p.ParserData.Read()
+													accept = true
+												}
+												if !accept {
+													p.Expected.Add(pos, "any character")
+												}
+											}
+											if accept {
+											}
+										}
+										if !accept {
+											if p.LastError < p.ParserData.Pos() {
+												p.LastError = p.ParserData.Pos()
+											}
+											p.ParserData.Seek(save)
+										}
+									}
+									p.Backtrack.Release(saved)
+									if pos == p.ParserData.Pos() {
+										break
+									}
+								}
+								accept = true
+							}
+							if accept {
+								{
+									pos := p.ParserData.Pos()
+									if p.ParserData.Read() != '`' {
+										p.ParserData.UnRead()
+										accept = false
+									} else {
+										accept = true
+									}
+									if !accept {
+										p.Expected.Add(pos, "\"`\"")
+									}
+								}
+								if accept {
+								}
+							}
+						}
+						if !accept {
+							if p.LastError < p.ParserData.Pos() {
+								p.LastError = p.ParserData.Pos()
+							}
+							p.ParserData.Seek(save)
+						}
+					}
+					if !accept {
+						{
+							save := p.ParserData.Pos()
+							{
+								pos := p.ParserData.Pos()
+								{
+									accept = true
+									s := p.ParserData.Pos()
+									if p.ParserData.Read() != '/' || p.ParserData.Read() != '/' {
+										p.ParserData.Seek(s)
+										accept = false
+									}
+								}
+								if !accept {
+									p.Expected.Add(pos, "\"//\"")
 								}
 							}
 							if accept {
-								accept = p.Hex()
-								if accept {
-									accept = p.Hex()
-									if accept {
-										accept = p.Hex()
-										if accept {
-											accept = p.Hex()
+								{
+									accept = true
+									for accept {
+										pos := p.ParserData.Pos()
+										saved := p.Backtrack.Save(pos)
+										{
+											save := p.ParserData.Pos()
+											{
+												s := p.ParserData.Pos()
+												saved := p.Backtrack.Save(s)
+												p.Expected.Mute()
+												accept = p.EndOfLine()
+												p.Expected.Unmute()
+												p.Backtrack.Release(saved)
+												p.ParserData.Seek(s)
+												p.Root.Discard(s)
+												accept = !accept
+											}
 											if accept {
-												accept = p.Hex()
-												if accept {
-													accept = p.Hex()
-													if accept {
-														accept = p.Hex()
-														if accept {
-															accept = p.Hex()
-															if accept {
-															}
-														}
+												{
+													pos := p.ParserData.Pos()
+													if p.ParserData.Pos() >= p.ParserData.Len() {
+														accept = false
+													} else {
+														p.ParserData.Read()
+														accept = true
+													}
+													if !accept {
+														p.Expected.Add(pos, "any character")
 													}
 												}
+												if accept {
+												}
+											}
+											if !accept {
+												if p.LastError < p.ParserData.Pos() {
+													p.LastError = p.ParserData.Pos()
+												}
+												p.ParserData.Seek(save)
 											}
 										}
+										p.Backtrack.Release(saved)
+										if pos == p.ParserData.Pos() {
+											break
+										}
 									}
+									accept = true
+								}
+								if accept {
 								}
 							}
 							if !accept {
@@ -1079,24 +3025,99 @@ func (p *Peg) Char() bool {
 						if !accept {
 							{
 								save := p.ParserData.Pos()
-								s := p.ParserData.Pos()
-								if p.ParserData.Read() != '\\' {
-									p.ParserData.UnRead()
-									accept = false
-								} else {
-									accept = true
+								{
+									pos := p.ParserData.Pos()
+									{
+										accept = true
+										s := p.ParserData.Pos()
+										if p.ParserData.Read() != '/' || p.ParserData.Read() != '*' {
+											p.ParserData.Seek(s)
+											accept = false
+										}
+									}
+									if !accept {
+										p.Expected.Add(pos, "\"/*\"")
+									}
 								}
-								p.ParserData.Seek(s)
-								p.Root.Discard(s)
-								accept = !accept
 								if accept {
-									if p.ParserData.Pos() >= p.ParserData.Len() {
-										accept = false
-									} else {
-										p.ParserData.Read()
+									{
+										accept = true
+										for accept {
+											pos := p.ParserData.Pos()
+											saved := p.Backtrack.Save(pos)
+											{
+												save := p.ParserData.Pos()
+												{
+													s := p.ParserData.Pos()
+													saved := p.Backtrack.Save(s)
+													p.Expected.Mute()
+													{
+														pos := p.ParserData.Pos()
+														{
+															accept = true
+															s := p.ParserData.Pos()
+															if p.ParserData.Read() != '*' || p.ParserData.Read() != '/' {
+																p.ParserData.Seek(s)
+																accept = false
+															}
+														}
+														if !accept {
+															p.Expected.Add(pos, "\"*/\"")
+														}
+													}
+													p.Expected.Unmute()
+													p.Backtrack.Release(saved)
+													p.ParserData.Seek(s)
+													p.Root.Discard(s)
+													accept = !accept
+												}
+												if accept {
+													{
+														pos := p.ParserData.Pos()
+														if p.ParserData.Pos() >= p.ParserData.Len() {
+															accept = false
+														} else {
+															p.ParserData.Read()
+															accept = true
+														}
+														if !accept {
+															p.Expected.Add(pos, "any character")
+														}
+													}
+													if accept {
+													}
+												}
+												if !accept {
+													if p.LastError < p.ParserData.Pos() {
+														p.LastError = p.ParserData.Pos()
+													}
+													p.ParserData.Seek(save)
+												}
+											}
+											p.Backtrack.Release(saved)
+											if pos == p.ParserData.Pos() {
+												break
+											}
+										}
 										accept = true
 									}
 									if accept {
+										{
+											pos := p.ParserData.Pos()
+											{
+												accept = true
+												s := p.ParserData.Pos()
+												if p.ParserData.Read() != '*' || p.ParserData.Read() != '/' {
+													p.ParserData.Seek(s)
+													accept = false
+												}
+											}
+											if !accept {
+												p.Expected.Add(pos, "\"*/\"")
+											}
+										}
+										if accept {
+										}
 									}
 								}
 								if !accept {
@@ -1107,99 +3128,557 @@ func (p *Peg) Char() bool {
 								}
 							}
 							if !accept {
+								{
+									save := p.ParserData.Pos()
+									{
+										s := p.ParserData.Pos()
+										saved := p.Backtrack.Save(s)
+										p.Expected.Mute()
+										{
+											pos := p.ParserData.Pos()
+											{
+												accept = false
+												c := p.ParserData.Read()
+												if c == '{' || c == '}' {
+													accept = true
+												} else {
+													p.ParserData.UnRead()
+												}
+											}
+											if !accept {
+												p.Expected.Add(pos, "[{}]")
+											}
+										}
+										p.Expected.Unmute()
+										p.Backtrack.Release(saved)
+										p.ParserData.Seek(s)
+										p.Root.Discard(s)
+										accept = !accept
+									}
+									if accept {
+										{
+											pos := p.ParserData.Pos()
+											if p.ParserData.Pos() >= p.ParserData.Len() {
+												accept = false
+											} else {
+												p.ParserData.Read()
+												accept = true
+											}
+											if !accept {
+												p.Expected.Add(pos, "any character")
+											}
+										}
+										if accept {
+										}
+									}
+									if !accept {
+										if p.LastError < p.ParserData.Pos() {
+											p.LastError = p.ParserData.Pos()
+										}
+										p.ParserData.Seek(save)
+									}
+								}
+								if !accept {
+								}
 							}
 						}
 					}
 				}
 			}
 		}
+		p.Backtrack.Release(saved)
+		if !accept {
+			p.ParserData.Seek(save)
+		}
+	}
+	return accept
+}
+
+func (p *Peg) LEFTARROW() bool {
+	// LEFTARROW     <- "<-" Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '<' || p.ParserData.Read() != '-' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"<-\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	if accept && start != p.ParserData.Pos() {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
+			p.IgnoreRange.A = start
+		}
+		p.IgnoreRange.B = p.ParserData.Pos()
+	}
+	return accept
+}
+
+func (p *Peg) SLASH() bool {
+	// SLASH         <- '/' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '/' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"/\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	if accept && start != p.ParserData.Pos() {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
+			p.IgnoreRange.A = start
+		}
+		p.IgnoreRange.B = p.ParserData.Pos()
+	}
+	return accept
+}
+
+func (p *Peg) COLON() bool {
+	// COLON         <- ':' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != ':' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\":\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	if accept && start != p.ParserData.Pos() {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
+			p.IgnoreRange.A = start
+		}
+		p.IgnoreRange.B = p.ParserData.Pos()
+	}
+	return accept
+}
+
+func (p *Peg) COMMA() bool {
+	// COMMA         <- ',' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != ',' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\",\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	if accept && start != p.ParserData.Pos() {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
+			p.IgnoreRange.A = start
+		}
+		p.IgnoreRange.B = p.ParserData.Pos()
+	}
+	return accept
+}
+
+func (p *Peg) AND() bool {
+	// AND           <- '&' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '&' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"&\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
 		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
 			p.ParserData.Seek(save)
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "AND")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Char"
+		node.Name = "AND"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) Hex() bool {
-	// Hex           <- [A-Fa-f0-9]
+func (p *Peg) NOT() bool {
+	// NOT           <- '!' Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		c := p.ParserData.Read()
-		if c >= 'A' && c <= 'F' {
-			accept = true
-		} else {
-			p.ParserData.UnRead()
-			accept = false
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '!' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"!\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
 		}
 		if !accept {
-			c := p.ParserData.Read()
-			if c >= 'a' && c <= 'f' {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "NOT")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "NOT"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) QUESTION() bool {
+	// QUESTION      <- '?' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '?' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"?\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "QUESTION")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "QUESTION"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) STAR() bool {
+	// STAR          <- '*' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '*' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
 				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"*\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "STAR")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "STAR"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) PLUS() bool {
+	// PLUS          <- '+' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '+' {
+				p.ParserData.UnRead()
+				accept = false
 			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"+\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
+			}
+		}
+		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
+			p.ParserData.Seek(save)
+		}
+	}
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "PLUS")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "PLUS"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
+	}
+	return accept
+}
+
+func (p *Peg) CUT() bool {
+	// CUT           <- '~' Spacing
+	accept := false
+	accept = true
+	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '~' {
 				p.ParserData.UnRead()
 				accept = false
+			} else {
+				accept = true
 			}
 			if !accept {
-				c := p.ParserData.Read()
-				if c >= '0' && c <= '9' {
-					accept = true
-				} else {
-					p.ParserData.UnRead()
-					accept = false
-				}
-				if !accept {
-				}
+				p.Expected.Add(pos, "\"~\"")
+			}
+		}
+		if accept {
+			accept = p.Spacing()
+			if accept {
 			}
 		}
 		if !accept {
+			if p.LastError < p.ParserData.Pos() {
+				p.LastError = p.ParserData.Pos()
+			}
 			p.ParserData.Seek(save)
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "CUT")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "Hex"
+		node.Name = "CUT"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) LEFTARROW() bool {
-	// LEFTARROW     <- "<-" Spacing
+func (p *Peg) OPEN() bool {
+	// OPEN          <- '(' Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
 	{
 		save := p.ParserData.Pos()
 		{
-			accept = true
-			s := p.ParserData.Pos()
-			if p.ParserData.Read() != '<' || p.ParserData.Read() != '-' {
-				p.ParserData.Seek(s)
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '(' {
+				p.ParserData.UnRead()
 				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"(\"")
 			}
 		}
 		if accept {
@@ -1215,7 +3694,7 @@ func (p *Peg) LEFTARROW() bool {
 		}
 	}
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -1223,18 +3702,24 @@ func (p *Peg) LEFTARROW() bool {
 	return accept
 }
 
-func (p *Peg) SLASH() bool {
-	// SLASH         <- '/' Spacing
+func (p *Peg) CLOSE() bool {
+	// CLOSE         <- ')' Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '/' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != ')' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\")\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1249,7 +3734,7 @@ func (p *Peg) SLASH() bool {
 		}
 	}
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -1257,18 +3742,26 @@ func (p *Peg) SLASH() bool {
 	return accept
 }
 
-func (p *Peg) AND() bool {
-	// AND           <- '&' Spacing
+func (p *Peg) DOT() bool {
+	// DOT           <- '.' Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
-	{
-		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '&' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		save := p.ParserData.Pos()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '.' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\".\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1283,33 +3776,45 @@ func (p *Peg) AND() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "DOT")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "AND"
+		node.Name = "DOT"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) NOT() bool {
-	// NOT           <- '!' Spacing
+func (p *Peg) RECOVER() bool {
+	// RECOVER       <- "%recover" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '!' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'r' || p.ParserData.Read() != 'e' || p.ParserData.Read() != 'c' || p.ParserData.Read() != 'o' || p.ParserData.Read() != 'v' || p.ParserData.Read() != 'e' || p.ParserData.Read() != 'r' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%recover\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1324,33 +3829,45 @@ func (p *Peg) NOT() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "RECOVER")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "NOT"
+		node.Name = "RECOVER"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) QUESTION() bool {
-	// QUESTION      <- '?' Spacing
+func (p *Peg) IMPORT() bool {
+	// IMPORT        <- "%import" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '?' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'i' || p.ParserData.Read() != 'm' || p.ParserData.Read() != 'p' || p.ParserData.Read() != 'o' || p.ParserData.Read() != 'r' || p.ParserData.Read() != 't' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%import\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1365,33 +3882,45 @@ func (p *Peg) QUESTION() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "IMPORT")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "QUESTION"
+		node.Name = "IMPORT"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) STAR() bool {
-	// STAR          <- '*' Spacing
+func (p *Peg) LEFT() bool {
+	// LEFT          <- "%left" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '*' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'l' || p.ParserData.Read() != 'e' || p.ParserData.Read() != 'f' || p.ParserData.Read() != 't' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%left\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1406,33 +3935,45 @@ func (p *Peg) STAR() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "LEFT")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "STAR"
+		node.Name = "LEFT"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) PLUS() bool {
-	// PLUS          <- '+' Spacing
+func (p *Peg) RIGHT() bool {
+	// RIGHT         <- "%right" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '+' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'r' || p.ParserData.Read() != 'i' || p.ParserData.Read() != 'g' || p.ParserData.Read() != 'h' || p.ParserData.Read() != 't' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%right\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1447,33 +3988,45 @@ func (p *Peg) PLUS() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "RIGHT")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "PLUS"
+		node.Name = "RIGHT"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) OPEN() bool {
-	// OPEN          <- '(' Spacing
+func (p *Peg) NONE() bool {
+	// NONE          <- "%none" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '(' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'n' || p.ParserData.Read() != 'o' || p.ParserData.Read() != 'n' || p.ParserData.Read() != 'e' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%none\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1487,27 +4040,46 @@ func (p *Peg) OPEN() bool {
 			p.ParserData.Seek(save)
 		}
 	}
-	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
-			p.IgnoreRange.A = start
-		}
-		p.IgnoreRange.B = p.ParserData.Pos()
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "NONE")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "NONE"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) CLOSE() bool {
-	// CLOSE         <- ')' Spacing
+func (p *Peg) PREFIX() bool {
+	// PREFIX        <- "%prefix" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != ')' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'p' || p.ParserData.Read() != 'r' || p.ParserData.Read() != 'e' || p.ParserData.Read() != 'f' || p.ParserData.Read() != 'i' || p.ParserData.Read() != 'x' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%prefix\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1521,27 +4093,46 @@ func (p *Peg) CLOSE() bool {
 			p.ParserData.Seek(save)
 		}
 	}
-	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
-			p.IgnoreRange.A = start
-		}
-		p.IgnoreRange.B = p.ParserData.Pos()
+	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "PREFIX")
+	if accept {
+		node := p.Root.Cleanup(start, end)
+		node.Name = "PREFIX"
+		node.P = p
+		node.Range = node.Range.Clip(p.IgnoreRange)
+		p.Root.Append(node)
+	} else {
+		p.Root.Discard(start)
+	}
+	p.Backtrack.Release(entered)
+	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
+		p.IgnoreRange = text.Region{}
 	}
 	return accept
 }
 
-func (p *Peg) DOT() bool {
-	// DOT           <- '.' Spacing
+func (p *Peg) POSTFIX() bool {
+	// POSTFIX       <- "%postfix" Spacing
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '.' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			{
+				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '%' || p.ParserData.Read() != 'p' || p.ParserData.Read() != 'o' || p.ParserData.Read() != 's' || p.ParserData.Read() != 't' || p.ParserData.Read() != 'f' || p.ParserData.Read() != 'i' || p.ParserData.Read() != 'x' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"%postfix\"")
+			}
 		}
 		if accept {
 			accept = p.Spacing()
@@ -1556,15 +4147,17 @@ func (p *Peg) DOT() bool {
 		}
 	}
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "POSTFIX")
 	if accept {
 		node := p.Root.Cleanup(start, end)
-		node.Name = "DOT"
+		node.Name = "POSTFIX"
 		node.P = p
 		node.Range = node.Range.Clip(p.IgnoreRange)
 		p.Root.Append(node)
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
@@ -1576,26 +4169,36 @@ func (p *Peg) Spacing() bool {
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
+	p.Expected.Mute()
 	{
 		accept = true
 		for accept {
+			pos := p.ParserData.Pos()
+			saved := p.Backtrack.Save(pos)
 			{
 				save := p.ParserData.Pos()
+				saved := p.Backtrack.Save(save)
 				accept = p.Space()
 				if !accept {
 					accept = p.Comment()
 					if !accept {
 					}
 				}
+				p.Backtrack.Release(saved)
 				if !accept {
 					p.ParserData.Seek(save)
 				}
 			}
+			p.Backtrack.Release(saved)
+			if pos == p.ParserData.Pos() {
+				break
+			}
 		}
 		accept = true
 	}
+	p.Expected.Unmute()
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -1610,29 +4213,49 @@ func (p *Peg) Comment() bool {
 	start := p.ParserData.Pos()
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != '#' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != '#' {
+				p.ParserData.UnRead()
+				accept = false
+			} else {
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "\"#\"")
+			}
 		}
 		if accept {
 			{
 				accept = true
 				for accept {
+					pos := p.ParserData.Pos()
+					saved := p.Backtrack.Save(pos)
 					{
 						save := p.ParserData.Pos()
-						s := p.ParserData.Pos()
-						accept = p.EndOfLine()
-						p.ParserData.Seek(s)
-						p.Root.Discard(s)
-						accept = !accept
+						{
+							s := p.ParserData.Pos()
+							saved := p.Backtrack.Save(s)
+							p.Expected.Mute()
+							accept = p.EndOfLine()
+							p.Expected.Unmute()
+							p.Backtrack.Release(saved)
+							p.ParserData.Seek(s)
+							p.Root.Discard(s)
+							accept = !accept
+						}
 						if accept {
-							if p.ParserData.Pos() >= p.ParserData.Len() {
-								accept = false
-							} else {
-								p.ParserData.Read()
-								accept = true
+							{
+								pos := p.ParserData.Pos()
+								if p.ParserData.Pos() >= p.ParserData.Len() {
+									accept = false
+								} else {
+									p.ParserData.Read()
+									accept = true
+								}
+								if !accept {
+									p.Expected.Add(pos, "any character")
+								}
 							}
 							if accept {
 							}
@@ -1644,6 +4267,10 @@ func (p *Peg) Comment() bool {
 							p.ParserData.Seek(save)
 						}
 					}
+					p.Backtrack.Release(saved)
+					if pos == p.ParserData.Pos() {
+						break
+					}
 				}
 				accept = true
 			}
@@ -1661,7 +4288,7 @@ func (p *Peg) Comment() bool {
 		}
 	}
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -1676,31 +4303,45 @@ func (p *Peg) Space() bool {
 	start := p.ParserData.Pos()
 	{
 		save := p.ParserData.Pos()
-		if p.ParserData.Read() != ' ' {
-			p.ParserData.UnRead()
-			accept = false
-		} else {
-			accept = true
-		}
-		if !accept {
-			if p.ParserData.Read() != '\t' {
+		saved := p.Backtrack.Save(save)
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Read() != ' ' {
 				p.ParserData.UnRead()
 				accept = false
 			} else {
 				accept = true
 			}
+			if !accept {
+				p.Expected.Add(pos, "\" \"")
+			}
+		}
+		if !accept {
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '\t' {
+					p.ParserData.UnRead()
+					accept = false
+				} else {
+					accept = true
+				}
+				if !accept {
+					p.Expected.Add(pos, "\"\\t\"")
+				}
+			}
 			if !accept {
 				accept = p.EndOfLine()
 				if !accept {
 				}
 			}
 		}
+		p.Backtrack.Release(saved)
 		if !accept {
 			p.ParserData.Seek(save)
 		}
 	}
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -1715,38 +4356,58 @@ func (p *Peg) EndOfLine() bool {
 	start := p.ParserData.Pos()
 	{
 		save := p.ParserData.Pos()
+		saved := p.Backtrack.Save(save)
 		{
-			accept = true
-			s := p.ParserData.Pos()
-			if p.ParserData.Read() != '\r' || p.ParserData.Read() != '\n' {
-				p.ParserData.Seek(s)
-				accept = false
-			}
-		}
-		if !accept {
-			if p.ParserData.Read() != '\n' {
-				p.ParserData.UnRead()
-				accept = false
-			} else {
+			pos := p.ParserData.Pos()
+			{
 				accept = true
+				s := p.ParserData.Pos()
+				if p.ParserData.Read() != '\r' || p.ParserData.Read() != '\n' {
+					p.ParserData.Seek(s)
+					accept = false
+				}
 			}
 			if !accept {
-				if p.ParserData.Read() != '\r' {
+				p.Expected.Add(pos, "\"\\r\\n\"")
+			}
+		}
+		if !accept {
+			{
+				pos := p.ParserData.Pos()
+				if p.ParserData.Read() != '\n' {
 					p.ParserData.UnRead()
 					accept = false
 				} else {
 					accept = true
 				}
+				if !accept {
+					p.Expected.Add(pos, "\"\\n\"")
+				}
+			}
+			if !accept {
+				{
+					pos := p.ParserData.Pos()
+					if p.ParserData.Read() != '\r' {
+						p.ParserData.UnRead()
+						accept = false
+					} else {
+						accept = true
+					}
+					if !accept {
+						p.Expected.Add(pos, "\"\\r\"")
+					}
+				}
 				if !accept {
 				}
 			}
 		}
+		p.Backtrack.Release(saved)
 		if !accept {
 			p.ParserData.Seek(save)
 		}
 	}
 	if accept && start != p.ParserData.Pos() {
-		if start < p.IgnoreRange.A || p.IgnoreRange.A == 0 {
+		if start < p.IgnoreRange.A || start > p.IgnoreRange.B || p.IgnoreRange.A == 0 {
 			p.IgnoreRange.A = start
 		}
 		p.IgnoreRange.B = p.ParserData.Pos()
@@ -1759,17 +4420,32 @@ func (p *Peg) EndOfFile() bool {
 	accept := false
 	accept = true
 	start := p.ParserData.Pos()
-	s := p.ParserData.Pos()
-	if p.ParserData.Pos() >= p.ParserData.Len() {
-		accept = false
-	} else {
-		p.ParserData.Read()
-		accept = true
+	mark := p.Expected.Mark(start)
+	entered := p.Backtrack.Enter(start)
+	{
+		s := p.ParserData.Pos()
+		saved := p.Backtrack.Save(s)
+		p.Expected.Mute()
+		{
+			pos := p.ParserData.Pos()
+			if p.ParserData.Pos() >= p.ParserData.Len() {
+				accept = false
+			} else {
+				p.ParserData.Read()
+				accept = true
+			}
+			if !accept {
+				p.Expected.Add(pos, "any character")
+			}
+		}
+		p.Expected.Unmute()
+		p.Backtrack.Release(saved)
+		p.ParserData.Seek(s)
+		p.Root.Discard(s)
+		accept = !accept
 	}
-	p.ParserData.Seek(s)
-	p.Root.Discard(s)
-	accept = !accept
 	end := p.ParserData.Pos()
+	p.Expected.Collapse(start, mark, accept, "EndOfFile")
 	if accept {
 		node := p.Root.Cleanup(start, end)
 		node.Name = "EndOfFile"
@@ -1779,6 +4455,7 @@ func (p *Peg) EndOfFile() bool {
 	} else {
 		p.Root.Discard(start)
 	}
+	p.Backtrack.Release(entered)
 	if p.IgnoreRange.A >= end || p.IgnoreRange.B <= start {
 		p.IgnoreRange = text.Region{}
 	}
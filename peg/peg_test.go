@@ -67,7 +67,10 @@ func TestParser(t *testing.T) {
 						{"EndOfLine", ignore},
 						{"IdentStart", justcall},
 						{"IdentCont", justcall},
+						{"CodeChunk", justcall},
 						{"SLASH", ignore},
+						{"COLON", ignore},
+						{"COMMA", ignore},
 						{"LEFTARROW", ignore},
 						{"OPEN", ignore},
 						{"CLOSE", ignore},
@@ -0,0 +1,79 @@
+// Command pegfmt formats grammars written in the peg.Peg syntax, aligning
+// their definitions and normalising their spacing while keeping comments.
+//
+// With no files, the grammar is read from the standard input and written
+// formatted to the standard output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jxo/parser/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var (
+		write = false
+		list  = false
+		check = false
+		width = 80
+	)
+	flag.BoolVar(&write, "w", write, "Write the result to the files instead of the standard output")
+	flag.BoolVar(&list, "l", list, "List the files whose formatting differs")
+	flag.BoolVar(&check, "check", check, "List the files whose formatting differs, and exit with status 1 if there are any")
+	flag.IntVar(&width, "width", width, "The width to wrap ordered choices at, or 0 to only wrap them where they were")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file.peg...]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		out, err := format.Format(string(data), width)
+		if err != nil {
+			log.Fatalf("<standard input>:%s\n", err)
+		}
+		if check && out != string(data) {
+			os.Exit(1)
+		} else if !check {
+			fmt.Print(out)
+		}
+		return
+	}
+	unformatted := false
+	for _, file := range flag.Args() {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		out, err := format.Format(string(data), width)
+		if err != nil {
+			log.Fatalf("%s:%s\n", file, err)
+		}
+		if out == string(data) && (list || check || write) {
+			continue
+		}
+		unformatted = true
+		if list || check {
+			fmt.Println(file)
+		}
+		if write {
+			if err := ioutil.WriteFile(file, []byte(out), 0644); err != nil {
+				log.Fatalln(err)
+			}
+		} else if !list && !check {
+			fmt.Print(out)
+		}
+	}
+	if check && unformatted {
+		os.Exit(1)
+	}
+}
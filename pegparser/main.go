@@ -27,7 +27,9 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/jxo/parser"
+	"github.com/jxo/parser/analysis"
 	"github.com/jxo/parser/peg"
 	"io/ioutil"
 	"log"
@@ -38,6 +40,19 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			queryMain(os.Args[2:])
+			return
+		case "tree":
+			treeMain(os.Args[2:])
+			return
+		case "railroad":
+			railroadMain(os.Args[2:])
+			return
+		}
+	}
 	var (
 		pegfile    = ""
 		testfile   = ""
@@ -46,7 +61,12 @@ func main() {
 		dumptree   = false
 		notest     = false
 		heatmap    = false
+		memoize    = false
+		ast        = false
+		trivia     = false
+		lookback   = false
 		ignore     = ""
+		imports    = ""
 		generator  = "go"
 		outpath    = ""
 		outfile    = ""
@@ -55,6 +75,7 @@ func main() {
 		gogenerate = false
 	)
 	flag.StringVar(&ignore, "ignore", ignore, "List of definitions to ignore (not generate nodes for)")
+	flag.StringVar(&imports, "imports", imports, "List of packages imported by the Go code of action blocks")
 	flag.StringVar(&pegfile, "peg", pegfile, "Pegfile for which to generate a parser for")
 	flag.StringVar(&testfile, "testfile", testfile, "Test file to be used in testing")
 	flag.StringVar(&outpath, "outpath", outpath, "Destination directory path")
@@ -64,6 +85,10 @@ func main() {
 	flag.BoolVar(&dumptree, "dumptree", dumptree, "Whether to make the generated parser spit out the generated tree")
 	flag.BoolVar(&notest, "notest", notest, "Whether to test the generated parser")
 	flag.BoolVar(&heatmap, "heatmap", heatmap, "Whether to generate a heatmap or not")
+	flag.BoolVar(&memoize, "memoize", memoize, "Whether to generate a packrat parser memoizing the outcome of each rule, which can also Update its tree after an edit")
+	flag.BoolVar(&ast, "ast", ast, "Whether to also generate a Go type for each definition along with a function converting its nodes to it")
+	flag.BoolVar(&trivia, "trivia", trivia, "Whether the generated parser keeps the ignored text and attaches it to the nodes")
+	flag.BoolVar(&lookback, "lookback", lookback, "Whether to print how far back the parser might have to read again when parsing from a stream")
 	flag.StringVar(&generator, "generator", generator, "Which generator to use")
 	flag.StringVar(&header, "header", header, "Header to put at the top of the generated source code")
 	flag.StringVar(&typename, "name", typename, "Name of the generated type/namespace/package. By default it'll be based on the name of the .peg-file")
@@ -85,6 +110,27 @@ func main() {
 				log.Println(p.RootNode())
 				log.Println("File didn't finish parsing")
 			}
+			grammar, err := parser.ResolveImports(p.RootNode(), pegfile, load)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			diags := analysis.AnalyzeResolved(&p, grammar)
+			for _, d := range diags {
+				log.Printf("%s:%s\n", pegfile, d)
+			}
+			if analysis.HasFatal(diags) {
+				log.Fatalln("Not generating a parser for a grammar with errors")
+			}
+			if grammar, err = parser.ExpandTemplates(grammar); err != nil {
+				log.Fatalln(err)
+			}
+			if lookback {
+				if n, rule := parser.Lookback(grammar); n < 0 {
+					log.Printf("%s: the lookback isn't bounded, as %s might backtrack over a repetition or recursion\n", pegfile, rule)
+				} else {
+					log.Printf("%s: the maximum lookback is %d bytes\n", pegfile, n)
+				}
+			}
 			name := outfile
 			if name == "" {
 				name = filepath.Base(pegfile)
@@ -103,7 +149,13 @@ func main() {
 			var gen parser.Generator
 			switch generator {
 			case "go":
-				gen = &parser.GoGenerator{RootNode: p.RootNode()}
+				gg := &parser.GoGenerator{RootNode: grammar}
+				for _, imp := range strings.Split(imports, ",") {
+					if imp = strings.TrimSpace(imp); imp != "" {
+						gg.Imports = append(gg.Imports, imp)
+					}
+				}
+				gen = gg
 			case "c":
 				gen = &parser.CGenerator{}
 			case "cpp":
@@ -150,6 +202,9 @@ func main() {
 				DebugLevel: parser.DebugLevel(debug),
 				Bench:      bench,
 				Heatmap:    heatmap,
+				Memoize:    memoize,
+				AST:        ast,
+				Trivia:     trivia,
 				WriteFile: func(name, data string) error {
 					if err := os.Mkdir(root, 0755); err != nil && !os.IsExist(err) {
 						return err
@@ -160,7 +215,7 @@ func main() {
 					return nil
 				},
 			}
-			if err := parser.GenerateParser(p.RootNode(), gen, s); err != nil {
+			if err := parser.GenerateParser(grammar, gen, s); err != nil {
 				log.Fatalln(err)
 			} else if !notest {
 				cmd := gen.TestCommand()
@@ -172,3 +227,17 @@ func main() {
 		}
 	}
 }
+
+// load returns the peg.Peg Grammar node of the grammar in "file",
+// which is imported by another grammar.
+func load(file string) (*parser.Node, error) {
+	var p peg.Peg
+	if data, err := ioutil.ReadFile(file); err != nil {
+		return nil, err
+	} else if !p.Parse(string(data)) {
+		return nil, fmt.Errorf("%s:%s", file, p.Error())
+	} else if c := p.RootNode().Children; c[len(c)-1].Name != "EndOfFile" {
+		return nil, fmt.Errorf("%s:%s", file, p.Error())
+	}
+	return p.RootNode(), nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jxo/parser"
+	"github.com/jxo/parser/interpreter"
+	"github.com/jxo/parser/peg"
+	"github.com/jxo/parser/query"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// queryMain implements the "query" subcommand, which parses the input files
+// with an interpreter of the grammar and prints the Ranges of the Nodes
+// matched by a query.
+func queryMain(args []string) {
+	var (
+		fs       = flag.NewFlagSet("query", flag.ExitOnError)
+		pegfile  = ""
+		ignore   = ""
+		selector = ""
+		data     = false
+	)
+	fs.StringVar(&pegfile, "peg", pegfile, "Pegfile of the grammar to parse the input files with")
+	fs.StringVar(&ignore, "ignore", ignore, "List of definitions to ignore (not create nodes for)")
+	fs.StringVar(&selector, "q", selector, "The query selecting the nodes to print")
+	fs.BoolVar(&data, "data", data, "Whether to print the data of the matched nodes too")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s query -peg=file.peg -q=query [flags] file...\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if pegfile == "" || selector == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	q, err := query.Compile(selector)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	in := interpret(pegfile, ignore)
+	for _, file := range fs.Args() {
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if !in.Parse(string(input)) {
+			log.Fatalf("%s:%s\n", file, in.Error())
+		}
+		for _, n := range q.Match(in.RootNode()) {
+			if data {
+				fmt.Printf("%s:%d-%d: %s %q\n", file, n.Range.Begin(), n.Range.End(), n.Name, n.Data())
+			} else {
+				fmt.Printf("%s:%d-%d: %s\n", file, n.Range.Begin(), n.Range.End(), n.Name)
+			}
+		}
+	}
+}
+
+// interpret returns an Interpreter for the grammar in "pegfile", ignoring
+// the definitions in the comma separated list "ignore".
+func interpret(pegfile, ignore string) *interpreter.Interpreter {
+	var p peg.Peg
+	if grammar, err := ioutil.ReadFile(pegfile); err != nil {
+		log.Fatalln(err)
+	} else if !p.Parse(string(grammar)) {
+		log.Fatalf("%s: %s\n", pegfile, p.Error())
+	}
+	actions := make(map[string]interpreter.Action)
+	for _, action := range strings.Split(ignore, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			actions[action] = interpreter.Ignore
+		}
+	}
+	root, err := parser.ResolveImports(p.RootNode(), pegfile, load)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	name := filepath.Base(pegfile)
+	in, err := interpreter.New(name[:len(name)-len(filepath.Ext(name))], root, actions)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return in
+}
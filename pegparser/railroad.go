@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"github.com/jxo/parser/render"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// railroadMain implements the "railroad" subcommand, which writes an HTML
+// page with the railroad diagrams of the definitions of a grammar.
+func railroadMain(args []string) {
+	var (
+		fs  = flag.NewFlagSet("railroad", flag.ExitOnError)
+		out = ""
+	)
+	fs.StringVar(&out, "o", out, "The file to write the page to instead of the standard output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s railroad [flags] file.peg\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	var p peg.Peg
+	if grammar, err := ioutil.ReadFile(fs.Arg(0)); err != nil {
+		log.Fatalln(err)
+	} else if !p.Parse(string(grammar)) {
+		log.Fatalf("%s: %s\n", fs.Arg(0), p.Error())
+	}
+
+	grammar, err := parser.ResolveImports(p.RootNode(), fs.Arg(0), load)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	w := os.Stdout
+	if out != "" {
+		if w, err = os.Create(out); err != nil {
+			log.Fatalln(err)
+		}
+		defer w.Close()
+	}
+	if err := render.Railroad(w, grammar); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jxo/parser/render"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// treeMain implements the "tree" subcommand, which parses an input file
+// with an interpreter of the grammar and prints the tree of Nodes created.
+func treeMain(args []string) {
+	var (
+		fs      = flag.NewFlagSet("tree", flag.ExitOnError)
+		pegfile = ""
+		ignore  = ""
+		format  = "text"
+		out     = ""
+	)
+	fs.StringVar(&pegfile, "peg", pegfile, "Pegfile of the grammar to parse the input file with")
+	fs.StringVar(&ignore, "ignore", ignore, "List of definitions to ignore (not create nodes for)")
+	fs.StringVar(&format, "format", format, "The format to print the tree in: text, dot or html")
+	fs.StringVar(&out, "o", out, "The file to write the tree to instead of the standard output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s tree -peg=file.peg [flags] file\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if pegfile == "" || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	in := interpret(pegfile, ignore)
+	input, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !in.Parse(string(input)) {
+		log.Fatalf("%s:%s\n", fs.Arg(0), in.Error())
+	}
+
+	w := os.Stdout
+	if out != "" {
+		if w, err = os.Create(out); err != nil {
+			log.Fatalln(err)
+		}
+		defer w.Close()
+	}
+	switch format {
+	case "text":
+		_, err = fmt.Fprint(w, in.RootNode())
+	case "dot":
+		err = render.DOT(w, in.RootNode())
+	case "html":
+		err = render.HTML(w, in.RootNode(), string(input))
+	default:
+		log.Fatalf("Unknown format %q\n", format)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -28,6 +28,7 @@ package parser
 import (
 	"container/list"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -44,12 +45,20 @@ type PyGenerator struct {
 	inlineCount           int
 	calledP               bool
 	RootNode              *Node
+	// The property tables added to the output, along with the
+	// runtime code for characters once it's been added
+	tables map[string]bool
+	cut    bool
 }
 
 func (g *PyGenerator) SetCustomActions(actions []CustomAction) {
 	g.CustomActions = actions
 }
 
+func (g *PyGenerator) SetCut() {
+	g.cut = true
+}
+
 func (g *PyGenerator) AddNode(data, defName string) string {
 	ret := `accept = True
 start = p.ParserData.Pos
@@ -73,7 +82,7 @@ func (g *PyGenerator) Ignore(data string) string {
 start = p.ParserData.Pos
 ` + g.Call(data) + `
 if accept and start != p.ParserData.Pos:
-	if start < p.IgnoreRange.Start or p.IgnoreRange.Start == 0:
+	if start < p.IgnoreRange.Start or start > p.IgnoreRange.End or p.IgnoreRange.Start == 0:
 		p.IgnoreRange.Start = start
 	p.IgnoreRange.End = p.ParserData.Pos
 `
@@ -81,21 +90,28 @@ if accept and start != p.ParserData.Pos:
 func (g *PyGenerator) MakeParserFunction(node *Node) error {
 	g.calledP = false
 	id := node.Children[0]
-	exp := node.Children[len(node.Children)-1]
+	exp := DefinitionExpression(node)
 	defName := helper(g, id)
 	g.currentName = defName
 	data := helper(g, exp)
+	if levels := DefinitionPrecedence(node); levels != nil {
+		data = g.Precedence(data, precedenceLevels(g, levels))
+	}
 
 	if !g.havefunctions {
 		g.havefunctions = true
-		g.output += "\tdef realParse(p):\n\t\treturn p.p_" + defName + "()\n\n"
+		if g.cut {
+			g.output += "\tdef realParse(p):\n\t\ttry:\n\t\t\treturn p.p_" + defName + "()\n\t\texcept CutFailure:\n\t\t\treturn False\n\n"
+		} else {
+			g.output += "\tdef realParse(p):\n\t\treturn p.p_" + defName + "()\n\n"
+		}
 	}
 
 	indenter := CodeFormatter{}
 	indenter.Inc()
 	indenter.Add("\ndef p_" + defName + "(p):\n")
 	indenter.Inc()
-	indenter.Add("# " + strings.Replace(strings.TrimSpace(node.Data()), "\n", "\n// ", -1) + "\n")
+	indenter.Add("# " + strings.Replace(strings.TrimSpace(node.Data()), "\n", "\n# ", -1) + "\n")
 
 	defaultAction := true
 	for i := range g.CustomActions {
@@ -132,45 +148,18 @@ func (g *PyGenerator) MakeParserCall(value string) string {
 }
 
 func (g *PyGenerator) CheckInRange(a, b string) string {
-	return `if p.ParserData.Pos >= len(p.ParserData.Data):
-	accept = False
-else:
-	c = p.ParserData.Data[p.ParserData.Pos]
-	if c >= '` + a + `' and c <= '` + b + `':
-		p.ParserData.Pos += 1
-		accept = True
-	else:
-		accept = False
-`
+	lo, _ := Unescape(a)
+	hi, _ := Unescape(b)
+	return g.CheckClass(&CharClass{Ranges: []RuneRange{{lo[0], hi[0]}}})
 }
 
 func (g *PyGenerator) CheckInSet(a string) string {
-	a = strings.Replace(a, "\\[", "[", -1)
-	a = strings.Replace(a, "\\]", "]", -1)
-	tests := ""
-	for i := 0; i < len(a); i++ {
-		if len(tests) > 0 {
-			tests += " or "
-		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-
-		tests += "c == '" + c2 + "'"
+	runes, _ := Unescape(a)
+	class := &CharClass{}
+	for _, r := range runes {
+		class.Ranges = append(class.Ranges, RuneRange{r, r})
 	}
-	return `
-accept = False
-if p.ParserData.Pos < len(p.ParserData.Data):
-	c = p.ParserData.Data[p.ParserData.Pos]
-	if ` + tests + `:
-		p.ParserData.Pos += 1
-		accept = True
-`
+	return g.CheckClass(class)
 }
 
 func (g *PyGenerator) CheckAnyChar() string {
@@ -183,46 +172,215 @@ else:
 }
 
 func (g *PyGenerator) CheckNext(a string) string {
-	if a[0] == '\'' {
-		return `if p.ParserData.Pos >= len(p.ParserData.Data) or p.ParserData.Data[p.ParserData.Pos] != ` + a + `:
+	runes, _ := Unescape(a[1 : len(a)-1])
+	if len(runes) == 1 {
+		return `if p.ParserData.Pos >= len(p.ParserData.Data) or p.ParserData.Data[p.ParserData.Pos] != ` + pyString(runes) + `:
 	accept = False
 else:
 	p.ParserData.Pos += 1
 	accept = True
 `
 	}
-	a = a[1 : len(a)-1]
-	tests := ""
-	pos := 0
-	for i := 0; i < len(a); i, pos = i+1, pos+1 {
-		if len(tests) > 0 {
-			tests += " or "
-		}
-		c2 := string(a[i])
-		if c2[0] == '\\' {
-			i++
-			c2 += string(a[i])
-		}
-		if c2 == "\\\"" {
-			c2 = "\""
-		}
-		if c2 == "'" {
-			c2 = "\\'"
-		}
-		tests += fmt.Sprintf("p.ParserData.Data[s+%d] != '%s'", pos, c2)
-	}
 	return fmt.Sprintf(`
 accept = True
 s = p.ParserData.Pos
 e = s + %d
-if e > len(p.ParserData.Data):
+if e > len(p.ParserData.Data) or p.ParserData.Data[s:e] != %s:
 	accept = False
-else:
-	if %s:
-		accept = False
 if accept:
 	p.ParserData.Pos += %d
-`, pos, tests, pos)
+`, len(runes), pyString(runes), len(runes))
+}
+
+// pyString returns "runes" as a Python unicode string literal.
+func pyString(runes []rune) string {
+	return "u" + strconv.QuoteToASCII(string(runes))
+}
+
+// pyUnicode is the method looking up characters in property tables,
+// added to the parsers with Unicode classes.
+const pyUnicode = `	# Returns whether "c" is in the sorted ranges of "table".
+	def InTable(p, table, c):
+		lo, hi = 0, len(table)//2
+		while lo < hi:
+			m = (lo+hi)//2
+			if c < table[2*m]:
+				hi = m
+			elif c > table[2*m+1]:
+				lo = m+1
+			else:
+				return True
+		return False
+
+`
+
+// table adds the table of the Unicode properties of "class" to the output
+// if it has any, along with the method looking up characters in it,
+// returning the name of the table.
+func (g *PyGenerator) table(class *CharClass) string {
+	if len(class.Properties) == 0 {
+		return ""
+	}
+	if g.tables == nil {
+		g.tables = make(map[string]bool)
+		g.output += pyUnicode
+	}
+	name := propertyTableName(class)
+	if !g.tables[name] {
+		g.tables[name] = true
+		g.output += "\t" + name + " = (" + formatTable(class.PropertyTable(), "\t\t") + "\n\t)\n\n"
+	}
+	return name
+}
+
+func (g *PyGenerator) CheckClass(class *CharClass) string {
+	var tests []string
+	for _, r := range class.Ranges {
+		if r.Lo == r.Hi {
+			tests = append(tests, fmt.Sprintf("c == 0x%X", r.Lo))
+		} else {
+			tests = append(tests, fmt.Sprintf("0x%X <= c <= 0x%X", r.Lo, r.Hi))
+		}
+	}
+	if table := g.table(class); table != "" {
+		tests = append(tests, "p.InTable(p."+table+", c)")
+	}
+	test := strings.Join(tests, " or ")
+	if class.Negated {
+		test = "not (" + test + ")"
+	}
+	return `
+accept = False
+if p.ParserData.Pos < len(p.ParserData.Data):
+	c = ord(p.ParserData.Data[p.ParserData.Pos])
+	if ` + test + `:
+		p.ParserData.Pos += 1
+		accept = True
+`
+}
+
+func (g *PyGenerator) CheckNextFold(a []rune) string {
+	tests := []string{fmt.Sprintf("s + %d <= len(p.ParserData.Data)", len(a))}
+	for i, r := range a {
+		tests = append(tests, fmt.Sprintf("p.ParserData.Data[s+%d] in %s", i, pyString(foldOrbit(r))))
+	}
+	return fmt.Sprintf(`
+s = p.ParserData.Pos
+accept = %s
+if accept:
+	p.ParserData.Pos += %d
+`, strings.Join(tests, " and "), len(a))
+}
+
+func (g *PyGenerator) Cut(data string) string {
+	return g.Call(data) + `
+if not accept:
+	raise CutFailure()`
+}
+
+func (g *PyGenerator) wrap(start, name string) string {
+	return `node = p.Root.Cleanup(` + start + `, p.ParserData.Pos)
+node.Range.Clip(p.IgnoreRange)
+node.Name = "` + name + `"
+p.Root.Append(node)`
+}
+
+func (g *PyGenerator) Precedence(operand string, levels []PrecedenceLevel) string {
+	name := "precedence_" + g.currentName
+	var cf CodeFormatter
+	cf.Inc()
+	cf.Add("\ndef " + name + `(p, min):
+`)
+	cf.Inc()
+	// The locals are named so that the code of the operators and
+	// operands, sharing the function's scope, doesn't overwrite them
+	cf.Add(`# Applies the operators of ` + g.currentName + ` binding at least as tightly
+# as the level "min" to its operands.
+accept = False
+start = p.ParserData.Pos
+`)
+	for _, l := range levels {
+		if l.Associativity == "NONE" {
+			// The operators of a non-associative level can't follow
+			// the ones it has just applied
+			cf.Add("last = 0\n")
+			break
+		}
+	}
+	// The operator creates a Node for itself
+	operator := func(code string) {
+		cf.Add("pos = p.ParserData.Pos\n" + g.Call(code) + "\nif accept:\n\t" + strings.Replace(g.wrap("pos", "Operator"), "\n", "\n\t", -1) + "\nelse:\n\tp.Root.Discard(pos)\n")
+	}
+	prefix := false
+	for i, l := range levels {
+		if l.Associativity != "PREFIX" {
+			continue
+		}
+		prefix = true
+		// A prefix operator applies wherever an operand is expected,
+		// binding its operand at least as tightly as its own level
+		cf.Add("if not accept:\n")
+		cf.Inc()
+		operator(l.Operator)
+		cf.Add(`if accept:
+	level = ` + fmt.Sprint(i+1) + `
+	if level < min:
+		level = min
+	accept = p.` + name + `(level)
+	if accept:
+		` + strings.Replace(g.wrap("start", "PrefixOp"), "\n", "\n\t\t", -1) + `
+	else:
+		p.ParserData.Pos = start
+		p.Root.Discard(start)
+`)
+		cf.Dec()
+	}
+	if prefix {
+		cf.Add("if not accept:\n")
+		cf.Inc()
+	}
+	cf.Add(g.Call(operand) + "\nif not accept:\n\treturn False\n")
+	if prefix {
+		cf.Dec()
+	}
+	cf.Add("while True:\n")
+	cf.Inc()
+	cf.Add("before = p.ParserData.Pos\n")
+	for i, l := range levels {
+		level := fmt.Sprint(i + 1)
+		switch l.Associativity {
+		case "PREFIX":
+			continue
+		case "NONE":
+			cf.Add("if min <= " + level + " and last != " + level + ":\n")
+		default:
+			cf.Add("if min <= " + level + ":\n")
+		}
+		cf.Inc()
+		operator(l.Operator)
+		if l.Associativity == "POSTFIX" {
+			cf.Add("if accept:\n\t" + strings.Replace(g.wrap("start", "PostfixOp"), "\n", "\n\t", -1) + "\n\tcontinue\n")
+		} else {
+			// The right operand of a right associative operator may
+			// apply it again, while for the others it binds tighter
+			next := fmt.Sprint(i + 2)
+			if l.Associativity == "RIGHT" {
+				next = level
+			}
+			cf.Add("if accept:\n\taccept = p." + name + "(" + next + ")\n\tif accept:\n\t\t" + strings.Replace(g.wrap("start", "BinaryOp"), "\n", "\n\t\t", -1) + "\n")
+			if l.Associativity == "NONE" {
+				cf.Add("\t\tlast = " + level + "\n")
+			}
+			cf.Add("\t\tcontinue\n\tp.ParserData.Pos = before\n\tp.Root.Discard(before)\n")
+		}
+		cf.Dec()
+	}
+	cf.Add("return True\n")
+	cf.Dec()
+	cf.Dec()
+	cf.Add("\n")
+	g.currentFunctions += cf.String()
+	return "accept = p." + name + "(1)"
 }
 
 func (g *PyGenerator) AssertNot(a string) string {
@@ -360,8 +518,15 @@ func (g *PyGenerator) Call(value string) string {
 func (g *PyGenerator) Begin(s GeneratorSettings) error {
 	g.s = s
 
-	g.output = g.s.Header + `
-class Range:
+	g.output = g.s.Header + "\n"
+	if g.cut {
+		g.output += `# Raised when an expression following a cut fails
+class CutFailure(Exception):
+	pass
+
+`
+	}
+	g.output += `class Range:
 	def __init__(self, s=0, e=0):
 		self.Start = s
 		self.End = e
@@ -424,6 +589,7 @@ class Node:
 			if self.Children[i].Range.End <= pos:
 				break
 			self.Children.pop()
+			i -= 1
 
 class Pd:
 	def __init__(self):
@@ -466,13 +632,17 @@ class ` + g.s.Name + `:
 
 func (g *PyGenerator) Finish() error {
 	ret := g.output + `
+import io
+import sys
 import time
 
-f = open("` + g.s.Testname + `")
+f = io.open("` + g.s.Testname + `", encoding="utf-8", errors="replace")
 data = f.read()
 f.close()
 p = ` + g.s.Name + `()
-assert p.Parse(data)
+if not p.Parse(data):
+	print("Failed to parse")
+	sys.exit(1)
 `
 	if g.s.Debug {
 		ret += "print p.Root\n"
@@ -491,6 +661,8 @@ print "%f ns/op" % ((t2-t)*10e8/N)
 		ret = ret[:len(ret)-1]
 	}
 	g.output = ""
+	g.tables = nil
+	g.cut = false
 	ln := strings.ToLower(g.s.Name)
 	if err := g.s.WriteFile(ln+".py", ret); err != nil {
 		return err
@@ -0,0 +1,416 @@
+// Package query selects Nodes from the trees created by the parsers with
+// CSS-like selectors over the Name, position and Data of the Nodes.
+//
+// A query is a comma separated list of selectors, matching the Nodes
+// that any of them match. A selector is a list of compound selectors
+// separated by combinators, with "A B" selecting the B Nodes that are
+// descendants of an A Node and "A > B" the ones that are children of it.
+//
+// A compound selector starts with the Name of the Nodes it selects, or "*"
+// for any Name, which may be left out if it's followed by any of:
+//
+//	[data="x"]      the Data of the Node is x
+//	[data!="x"]     the Data of the Node isn't x
+//	[data^="x"]     the Data starts with x
+//	[data$="x"]     the Data ends with x
+//	[data*="x"]     the Data contains x
+//	[data~="x"]     the Data matches the regular expression x
+//	[label="x"]     the Label of the Node is x, with the same operators
+//	:first-child    the Node is the first of its siblings
+//	:last-child     the Node is the last of its siblings
+//	:nth-child(n)   the Node is the n:th of its siblings, counting from 1
+//	:empty          the Node has no Children
+//	:has(s)         a descendant matches the selector s, or a child if
+//	                s starts with ">"
+//	:not(s)         the Node doesn't match the compound selector s
+//
+// The values are quoted with either " or ' and may contain Go escapes.
+// For example, the KeyValuePair Nodes of a Dictionary with the key "a" are
+// selected by
+//
+//	Dictionary > KeyValuePair:has(> QuotedText[data='"a"'])
+package query
+
+import (
+	"fmt"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/walk"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Query is a compiled query, which is safe for concurrent use.
+	Query struct {
+		src       string
+		selectors []selector
+	}
+
+	// selector is a list of compound selectors, with the combinator
+	// of each one telling how it relates to the previous one.
+	selector []*compound
+
+	compound struct {
+		// Either ' ' for a descendant or '>' for a child
+		combinator byte
+		name       string
+		filters    []filter
+	}
+
+	filter func(c *walk.Cursor) bool
+)
+
+// Compile parses the query "src", returning an error describing
+// where it's malformed if it is.
+func Compile(src string) (*Query, error) {
+	p := compiler{src: src}
+	q := &Query{src: src}
+	for {
+		sel, err := p.selector(false)
+		if err != nil {
+			return nil, err
+		}
+		q.selectors = append(q.selectors, sel)
+		if p.pos == len(p.src) {
+			return q, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected a combinator or ','")
+		}
+	}
+}
+
+// MustCompile is like Compile, but panics if the query is malformed.
+func MustCompile(src string) *Query {
+	q, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Match returns the Nodes in the tree rooted at "root", including "root"
+// itself, that the query matches, in pre-order.
+func (q *Query) Match(root *Node) (ret []*Node) {
+	walk.Walk(root, func(c *walk.Cursor) walk.Action {
+		if q.matches(c) {
+			ret = append(ret, c.Node())
+		}
+		return walk.Continue
+	}, nil)
+	return
+}
+
+// First returns the first Node in pre-order in the tree rooted at
+// "root" that the query matches, or nil if there's none.
+func (q *Query) First(root *Node) (ret *Node) {
+	walk.Walk(root, func(c *walk.Cursor) walk.Action {
+		if q.matches(c) {
+			ret = c.Node()
+			return walk.Stop
+		}
+		return walk.Continue
+	}, nil)
+	return
+}
+
+func (q *Query) matches(c *walk.Cursor) bool {
+	for _, sel := range q.selectors {
+		if sel.matches(c, len(sel)-1, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the Node of "c" matches the compound selector
+// "i", and the ones before it match its ancestors as their combinators
+// require. The first compound selector relates to "scope" when given,
+// which is the case for the selectors of :has.
+func (s selector) matches(c *walk.Cursor, i int, scope *walk.Cursor) bool {
+	if !s[i].matches(c) {
+		return false
+	}
+	if i == 0 && scope == nil {
+		return true
+	}
+	for p := c.Parent(); p != nil; p = p.Parent() {
+		if i == 0 {
+			if p == scope {
+				return true
+			}
+		} else if s.matches(p, i-1, scope) {
+			return true
+		}
+		if s[i].combinator == '>' {
+			break
+		}
+	}
+	return false
+}
+
+func (cs *compound) matches(c *walk.Cursor) bool {
+	if cs.name != "*" && cs.name != c.Node().Name {
+		return false
+	}
+	for _, f := range cs.filters {
+		if !f(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// compiler compiles the query "src", with "pos" being
+// the offset of the next byte to parse.
+type compiler struct {
+	src string
+	pos int
+}
+
+func (p *compiler) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Malformed query %q at offset %d: %s", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *compiler) spacing() bool {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	return p.pos != start
+}
+
+// consume skips "s" and any spacing before it if it's
+// next, returning whether it was.
+func (p *compiler) consume(s string) bool {
+	start := p.pos
+	p.spacing()
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// selector parses a selector, which is allowed to start with a
+// combinator when it's "relative" to the Node of a :has.
+func (p *compiler) selector(relative bool) (selector, error) {
+	var ret selector
+	p.spacing()
+	combinator := byte(' ')
+	if relative && p.consume(">") {
+		combinator = '>'
+		p.spacing()
+	}
+	for {
+		cs, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		cs.combinator = combinator
+		ret = append(ret, cs)
+
+		if p.consume(">") {
+			combinator = '>'
+			p.spacing()
+		} else if p.spacing() && p.pos < len(p.src) && strings.IndexByte(",)", p.src[p.pos]) < 0 {
+			combinator = ' '
+		} else {
+			return ret, nil
+		}
+	}
+}
+
+func (p *compiler) name() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		if c := p.src[p.pos]; c != '_' && c != '-' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *compiler) compound() (*compound, error) {
+	cs := &compound{name: "*"}
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+	} else if p.pos < len(p.src) && (p.src[p.pos] == '[' || p.src[p.pos] == ':') {
+		// The name may be left out when followed by a filter
+	} else if cs.name = p.name(); cs.name == "" {
+		return nil, p.errorf("expected a name or '*'")
+	}
+	for p.pos < len(p.src) {
+		var (
+			f   filter
+			err error
+		)
+		switch p.src[p.pos] {
+		case '[':
+			p.pos++
+			f, err = p.attribute()
+		case ':':
+			p.pos++
+			f, err = p.pseudo()
+		default:
+			return cs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cs.filters = append(cs.filters, f)
+	}
+	return cs, nil
+}
+
+func (p *compiler) attribute() (filter, error) {
+	p.spacing()
+	var get func(n *Node) string
+	switch attr := p.name(); attr {
+	case "data":
+		get = (*Node).Data
+	case "label":
+		get = func(n *Node) string { return n.Label }
+	default:
+		return nil, p.errorf("unknown attribute %q", attr)
+	}
+	p.spacing()
+	var op string
+	for _, o := range []string{"=", "!=", "^=", "$=", "*=", "~="} {
+		if strings.HasPrefix(p.src[p.pos:], o) {
+			op = o
+		}
+	}
+	if op == "" {
+		return nil, p.errorf("expected an operator")
+	}
+	p.pos += len(op)
+	p.spacing()
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume("]") {
+		return nil, p.errorf("expected ']'")
+	}
+
+	var match func(s string) bool
+	switch op {
+	case "=":
+		match = func(s string) bool { return s == value }
+	case "!=":
+		match = func(s string) bool { return s != value }
+	case "^=":
+		match = func(s string) bool { return strings.HasPrefix(s, value) }
+	case "$=":
+		match = func(s string) bool { return strings.HasSuffix(s, value) }
+	case "*=":
+		match = func(s string) bool { return strings.Contains(s, value) }
+	case "~=":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+		match = re.MatchString
+	}
+	return func(c *walk.Cursor) bool { return match(get(c.Node())) }, nil
+}
+
+// value parses a quoted string.
+func (p *compiler) value() (string, error) {
+	if p.pos == len(p.src) || (p.src[p.pos] != '"' && p.src[p.pos] != '\'') {
+		return "", p.errorf("expected a quoted value")
+	}
+	quote := p.src[p.pos]
+	p.pos++
+	var ret []byte
+	for p.pos < len(p.src) && p.src[p.pos] != quote {
+		r, _, tail, err := strconv.UnquoteChar(p.src[p.pos:], quote)
+		if err != nil {
+			return "", p.errorf("%s", err)
+		}
+		ret = append(ret, string(r)...)
+		p.pos = len(p.src) - len(tail)
+	}
+	if p.pos == len(p.src) {
+		return "", p.errorf("unterminated value")
+	}
+	p.pos++
+	return string(ret), nil
+}
+
+func (p *compiler) pseudo() (filter, error) {
+	switch class := p.name(); class {
+	case "first-child":
+		return func(c *walk.Cursor) bool { return c.Index() == 0 }, nil
+	case "last-child":
+		return func(c *walk.Cursor) bool {
+			return c.Parent() != nil && c.Index() == len(c.Parent().Node().Children)-1
+		}, nil
+	case "empty":
+		return func(c *walk.Cursor) bool { return len(c.Node().Children) == 0 }, nil
+	case "nth-child":
+		if !p.consume("(") {
+			return nil, p.errorf("expected '('")
+		}
+		start := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		n, err := strconv.Atoi(p.src[start:p.pos])
+		if err != nil || n < 1 {
+			p.pos = start
+			return nil, p.errorf("expected a positive number")
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return func(c *walk.Cursor) bool { return c.Index() == n-1 }, nil
+	case "has":
+		if !p.consume("(") {
+			return nil, p.errorf("expected '('")
+		}
+		sel, err := p.selector(true)
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return func(c *walk.Cursor) bool {
+			// The descendants are walked on their own, with the
+			// Cursor at the Node being the root of the walk
+			var scope *walk.Cursor
+			return !walk.Walk(c.Node(), func(c2 *walk.Cursor) walk.Action {
+				if scope == nil {
+					scope = c2
+				} else if sel.matches(c2, len(sel)-1, scope) {
+					return walk.Stop
+				}
+				return walk.Continue
+			}, nil)
+		}, nil
+	case "not":
+		if !p.consume("(") {
+			return nil, p.errorf("expected '('")
+		}
+		cs, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return func(c *walk.Cursor) bool { return !cs.matches(c) }, nil
+	default:
+		return nil, p.errorf("unknown pseudo-class %q", class)
+	}
+}
@@ -0,0 +1,79 @@
+package query
+
+import (
+	"fmt"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	var p peg.Peg
+	grammar := "A <- B 'c' / \"d\"\nB <- [b] C?\nC <- 'c'\n"
+	if !p.Parse(grammar) {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	data := func(nodes []*Node) string {
+		var s []string
+		for _, n := range nodes {
+			s = append(s, strings.TrimSpace(n.Data()))
+		}
+		return strings.Join(s, " ")
+	}
+	tests := []struct {
+		query, out string
+	}{
+		{"Literal", `'c' "d" 'c'`},
+		{"Definition > Identifier", "A B C"},
+		{"Peg > Identifier", ""},
+		{"Peg Identifier", "A B B C C"},
+		{"Definition:first-child Literal", `'c' "d"`},
+		{"Literal[data^='\"']", `"d"`},
+		{`Literal[data="'c'"], Class`, "'c' [b] 'c'"},
+		{"Definition:has(> Identifier[data='B']) Identifier", "B C"},
+		{"Definition:has(Suffix > QUESTION) > Identifier", "B"},
+		{"Definition:not(:has(Literal)) > Identifier", "B"},
+		{"Sequence > Prefix:nth-child(2)", "'c' C?"},
+		{"Sequence > *:last-child", `'c' "d" C? 'c'`},
+		{"Identifier[data~='^[AB]$']:empty", "A B B"},
+		{"Definition Sequence:not(:first-child)", `"d"`},
+		{"Class *", "b b"},
+		{"* > Class > *", "b"},
+	}
+	for _, test := range tests {
+		q, err := Compile(test.query)
+		if err != nil {
+			t.Errorf("Couldn't compile %q: %s", test.query, err)
+			continue
+		}
+		if s := data(q.Match(p.RootNode())); s != test.out {
+			t.Errorf("Expected %q to match %s, got %s", test.query, test.out, s)
+		}
+	}
+	if n := MustCompile("Identifier[data='C']").First(p.RootNode()); n == nil || n.Range.Begin() != strings.Index(grammar, "C") {
+		t.Errorf("Expected the first C, got %v", n)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for _, test := range []string{
+		"",
+		"A >",
+		"A,",
+		"A[data]",
+		"A[name='a']",
+		"A[data='a]",
+		"A[data~='(']",
+		"A:nth-child(0)",
+		"A:has(B",
+		"A:visited",
+		"A)",
+	} {
+		if _, err := Compile(test); err == nil {
+			t.Errorf("Expected an error for %q", test)
+		} else if !strings.HasPrefix(err.Error(), fmt.Sprintf("Malformed query %q", test)) {
+			t.Errorf("Unexpected error for %q: %s", test, err)
+		}
+	}
+}
@@ -5,8 +5,11 @@ import (
 )
 
 type BasicReader struct {
-	pos  int
-	data string
+	pos      int
+	data     string
+	examined int
+	// Built the first time a position is looked up
+	index lineIndex
 }
 
 const nilrune = '\u0000'
@@ -29,16 +32,21 @@ func (p *BasicReader) Substring(start, end int) string {
 }
 
 func (p *BasicReader) LineCol(offset int) (line, column int) {
-	line = 1
-	column = 1
-	for _, r := range p.data[:offset] {
-		column++
-		if r == '\n' {
-			line++
-			column = 1
-		}
+	return p.Position(offset, ColumnRunes)
+}
+
+func (p *BasicReader) Position(offset int, unit ColumnUnit) (line, column int) {
+	if p.index.scanned < len(p.data) {
+		p.index.scan(p.data[p.index.scanned:], true)
+	}
+	return p.index.lineCol(offset, unit)
+}
+
+func (p *BasicReader) Offset(line, column int, unit ColumnUnit) int {
+	if p.index.scanned < len(p.data) {
+		p.index.scan(p.data[p.index.scanned:], true)
 	}
-	return
+	return p.index.offset(line, column, unit)
 }
 
 func (p *BasicReader) Len() int {
@@ -54,10 +62,12 @@ func (p *BasicReader) eof() bool {
 func (p *BasicReader) Read() rune {
 	if p.eof() {
 		p.pos++
+		p.examine()
 		return nilrune
 	}
 	r, s := utf8.DecodeRuneInString(p.data[p.pos:])
 	p.pos += s
+	p.examine()
 
 	return r
 }
@@ -73,6 +83,20 @@ func (p *BasicReader) Seek(n int) {
 	p.pos = n
 }
 
+func (p *BasicReader) examine() {
+	if p.pos > p.examined {
+		p.examined = p.pos
+	}
+}
+
+func (p *BasicReader) Examined() int {
+	return p.examined
+}
+
+func (p *BasicReader) Track(offset int) {
+	p.examined = offset
+}
+
 func NewReader(data string) Reader {
-	return &BasicReader{0, data}
+	return &BasicReader{data: data}
 }
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// position is the straightforward way of finding the line and column of "offset".
+func position(data string, offset int, unit ColumnUnit) (line, column int) {
+	line = strings.Count(data[:offset], "\n") + 1
+	text := data[strings.LastIndex(data[:offset], "\n")+1 : offset]
+	switch unit {
+	case ColumnBytes:
+		column = len(text)
+	case ColumnRunes:
+		column = utf8.RuneCountInString(text)
+	case ColumnUTF16:
+		column = len(utf16.Encode([]rune(text)))
+	}
+	return line, column + 1
+}
+
+func TestPosition(t *testing.T) {
+	data := "abc\nå⌘\U0001F600x\n\n\U0001F600\U0001F600 é\nend"
+	r := NewReader(data).(*BasicReader)
+	for _, unit := range []ColumnUnit{ColumnRunes, ColumnBytes, ColumnUTF16} {
+		for offset := 0; offset <= len(data); offset++ {
+			if offset < len(data) && !utf8.RuneStart(data[offset]) {
+				continue
+			}
+			l1, c1 := r.Position(offset, unit)
+			l2, c2 := position(data, offset, unit)
+			if l1 != l2 || c1 != c2 {
+				t.Errorf("Expected %d to be at %d,%d in unit %d, got %d,%d", offset, l2, c2, unit, l1, c1)
+			}
+			if o := r.Offset(l1, c1, unit); o != offset {
+				t.Errorf("Expected %d,%d in unit %d to be at %d, got %d", l1, c1, unit, offset, o)
+			}
+		}
+	}
+	if l, c := r.LineCol(9); l != 2 || c != 3 {
+		t.Errorf("Expected LineCol to count runes, got %d,%d", l, c)
+	}
+	for _, test := range []struct {
+		line, column, offset int
+	}{
+		{2, 100, 14},
+		{5, 100, len(data)},
+		{6, 1, len(data)},
+		{0, 1, 0},
+	} {
+		if o := r.Offset(test.line, test.column, ColumnRunes); o != test.offset {
+			t.Errorf("Expected %d,%d to be clamped to %d, got %d", test.line, test.column, test.offset, o)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package render draws trees of Nodes for debugging grammars, either as
+// Graphviz DOT or as an HTML page showing the Ranges of the Nodes in the
+// data they were parsed from.
+package render
+
+import (
+	"bufio"
+	"fmt"
+	. "github.com/jxo/parser"
+	"io"
+	"strings"
+)
+
+// The maximum number of bytes of the data of a leaf Node shown
+const maxData = 40
+
+// excerpt returns the data of the leaf Node "n", shortened to
+// about maxData bytes, or "" if it has Children.
+func excerpt(n *Node) string {
+	if len(n.Children) > 0 {
+		return ""
+	}
+	data := n.Data()
+	if len(data) > maxData {
+		i := maxData
+		for i > 0 && data[i]&0xc0 == 0x80 {
+			// Not cutting a rune in half
+			i--
+		}
+		data = data[:i] + "…"
+	}
+	return data
+}
+
+// dotQuote returns "s" as a quoted DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(s) + `"`
+}
+
+// DOT writes the tree rooted at "n" to "w" as a Graphviz digraph, with each
+// Node labeled with its Label, Name and Range, and the data of the leaves.
+func DOT(w io.Writer, n *Node) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph tree {\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	id := 0
+	var write func(n *Node) int
+	write = func(n *Node) int {
+		self := id
+		id++
+		label := fmt.Sprintf("%s\n%d-%d", n.Name, n.Range.Begin(), n.Range.End())
+		if n.Label != "" {
+			label = n.Label + ":" + label
+		}
+		if len(n.Children) == 0 {
+			label += "\n" + fmt.Sprintf("%q", excerpt(n))
+		}
+		fmt.Fprintf(bw, "\tn%d [label=%s];\n", self, dotQuote(label))
+		for _, child := range n.Children {
+			fmt.Fprintf(bw, "\tn%d -> n%d;\n", self, write(child))
+		}
+		return self
+	}
+	write(n)
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
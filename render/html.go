@@ -0,0 +1,124 @@
+package render
+
+import (
+	. "github.com/jxo/parser"
+	"html/template"
+	"io"
+	"sort"
+)
+
+type (
+	// htmlNode is what the page shows of a Node.
+	htmlNode struct {
+		Name, Label, Data string
+		Begin, End        int
+		Children          []*htmlNode
+	}
+
+	// segment is the data between two offsets where Nodes begin
+	// or end, which is highlighted as a whole.
+	segment struct {
+		Begin int
+		Data  string
+	}
+)
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; display: flex; height: 100vh; font-family: monospace; font-size: 13px; }
+#tree, #source { overflow: auto; padding: 8px; flex: 1; }
+#tree { border-right: 1px solid #ccc; }
+#source { margin: 0; white-space: pre-wrap; }
+details { margin-left: 1em; }
+summary, .leaf { cursor: pointer; }
+.leaf { margin-left: 2em; }
+.range { color: #888; }
+.label { color: #07a; }
+.data { color: #a50; }
+.hl { background: #ff8; }
+.selected > summary, .leaf.selected { background: #def; }
+</style>
+</head>
+<body>
+<div id="tree">{{template "node" .Root}}</div>
+<pre id="source">{{range .Source}}<span data-o="{{.Begin}}">{{.Data}}</span>{{end}}</pre>
+<script>
+var selected = null;
+function highlight(e, scroll) {
+	var a = +e.dataset.a, b = +e.dataset.b, first = null;
+	document.querySelectorAll("#source span").forEach(function(s) {
+		var o = +s.dataset.o, hl = o >= a && o < b;
+		s.classList.toggle("hl", hl);
+		if (hl && !first) {
+			first = s;
+		}
+	});
+	if (scroll && first) {
+		first.scrollIntoView({block: "center"});
+	}
+}
+document.querySelectorAll("[data-a]").forEach(function(e) {
+	e.addEventListener("mouseenter", function() { highlight(e, false); });
+	e.addEventListener("mouseleave", function() { highlight(selected || {dataset: {a: -1, b: -1}}, false); });
+	e.addEventListener("click", function() {
+		if (selected) (selected.parentNode.tagName == "DETAILS" ? selected.parentNode : selected).classList.remove("selected");
+		selected = e;
+		(e.parentNode.tagName == "DETAILS" ? e.parentNode : e).classList.add("selected");
+		highlight(e, true);
+	});
+});
+</script>
+</body>
+</html>
+{{define "title"}}{{if .Label}}<span class="label">{{.Label}}:</span>{{end}}{{.Name}} <span class="range">{{.Begin}}-{{.End}}</span>{{end}}
+{{define "node"}}{{if .Children}}<details open><summary data-a="{{.Begin}}" data-b="{{.End}}">{{template "title" .}}</summary>{{range .Children}}{{template "node" .}}{{end}}</details>{{else}}<div class="leaf" data-a="{{.Begin}}" data-b="{{.End}}">{{template "title" .}} <span class="data">{{printf "%q" .Data}}</span></div>{{end}}
+{{end}}`))
+
+func toHTML(n *Node, offsets map[int]bool) *htmlNode {
+	ret := &htmlNode{
+		Name:  n.Name,
+		Label: n.Label,
+		Data:  excerpt(n),
+		Begin: n.Range.Begin(),
+		End:   n.Range.End(),
+	}
+	offsets[ret.Begin] = true
+	offsets[ret.End] = true
+	for _, child := range n.Children {
+		ret.Children = append(ret.Children, toHTML(child, offsets))
+	}
+	return ret
+}
+
+// HTML writes a standalone HTML page to "w" showing the tree rooted at "n"
+// next to "data", which the tree was parsed from. The Nodes can be expanded
+// and collapsed, and their Ranges are highlighted in the data when pointed
+// at or clicked.
+func HTML(w io.Writer, n *Node, data string) error {
+	offsets := map[int]bool{0: true}
+	root := toHTML(n, offsets)
+	var sorted []int
+	for o := range offsets {
+		if o >= 0 && o < len(data) {
+			sorted = append(sorted, o)
+		}
+	}
+	sort.Ints(sorted)
+	var source []segment
+	for i, o := range sorted {
+		end := len(data)
+		if i+1 < len(sorted) {
+			end = sorted[i+1]
+		}
+		source = append(source, segment{o, data[o:end]})
+	}
+	return page.Execute(w, struct {
+		Title  string
+		Root   *htmlNode
+		Source []segment
+	}{n.Name, root, source})
+}
@@ -0,0 +1,376 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/jxo/parser"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The dimensions of railroad diagrams, in pixels
+const (
+	charWidth  = 8
+	labelWidth = 6
+	boxHeight  = 24
+	hGap       = 16
+	vGap       = 10
+	radius     = 10
+	pad        = 8
+	margin     = 10
+)
+
+type (
+	// railItem is a part of a railroad diagram, which is drawn with
+	// its line entering on the left and leaving on the right.
+	railItem interface {
+		// size returns the width of the item, and the space
+		// it needs above and below its line
+		size() (width, up, down int)
+		// draw writes the SVG of the item with its line
+		// entering at "x", "y"
+		draw(b *bytes.Buffer, x, y int)
+	}
+
+	// box is a terminal, or a reference to the rule "href" if set.
+	box struct {
+		text, href string
+	}
+
+	// skip is an empty line, for optional items.
+	skip struct{}
+
+	sequence []railItem
+
+	// choice has its first item on its line and the others below.
+	choice []railItem
+
+	// loop repeats its item by looping back below it.
+	loop struct {
+		item railItem
+	}
+
+	// group draws "item" under the caption "text",
+	// with a dashed border if "border" is set.
+	group struct {
+		item   railItem
+		text   string
+		border bool
+	}
+)
+
+func (b *box) size() (int, int, int) {
+	return utf8.RuneCountInString(b.text)*charWidth + 2*pad, boxHeight / 2, boxHeight / 2
+}
+
+func (b *box) draw(buf *bytes.Buffer, x, y int) {
+	w, _, _ := b.size()
+	if b.href != "" {
+		fmt.Fprintf(buf, `<a href="#%s">`, html.EscapeString(b.href))
+		fmt.Fprintf(buf, `<rect class="rule" x="%d" y="%d" width="%d" height="%d"/>`, x, y-boxHeight/2, w, boxHeight)
+	} else {
+		fmt.Fprintf(buf, `<rect class="terminal" x="%d" y="%d" width="%d" height="%d" rx="%d"/>`, x, y-boxHeight/2, w, boxHeight, boxHeight/2)
+	}
+	fmt.Fprintf(buf, `<text x="%d" y="%d">%s</text>`, x+w/2, y+4, html.EscapeString(b.text))
+	if b.href != "" {
+		buf.WriteString("</a>")
+	}
+	buf.WriteString("\n")
+}
+
+func (skip) size() (int, int, int) {
+	return 0, 0, 0
+}
+
+func (skip) draw(buf *bytes.Buffer, x, y int) {
+}
+
+func line(buf *bytes.Buffer, x1, y1, x2, y2 int) {
+	if x1 != x2 || y1 != y2 {
+		fmt.Fprintf(buf, `<path d="M%d %dL%d %d"/>`+"\n", x1, y1, x2, y2)
+	}
+}
+
+func (s sequence) size() (w, up, down int) {
+	for i, item := range s {
+		iw, iu, id := item.size()
+		if w += iw; i > 0 {
+			w += hGap
+		}
+		up, down = max(up, iu), max(down, id)
+	}
+	return
+}
+
+func (s sequence) draw(buf *bytes.Buffer, x, y int) {
+	for i, item := range s {
+		if i > 0 {
+			line(buf, x, y, x+hGap, y)
+			x += hGap
+		}
+		item.draw(buf, x, y)
+		w, _, _ := item.size()
+		x += w
+	}
+}
+
+// lines returns the offsets of the lines of the items of the choice from
+// the line of the choice, along with the space needed below it.
+func (c choice) lines() (ys []int, down int) {
+	for i, item := range c {
+		_, iu, id := item.size()
+		if i > 0 {
+			ys = append(ys, max(down+vGap+iu, 2*radius))
+		} else {
+			ys = append(ys, 0)
+		}
+		down = ys[i] + id
+	}
+	return
+}
+
+func (c choice) size() (w, up, down int) {
+	for _, item := range c {
+		iw, _, _ := item.size()
+		w = max(w, iw)
+	}
+	_, up, _ = c[0].size()
+	_, down = c.lines()
+	return w + 4*radius, up, down
+}
+
+func (c choice) draw(buf *bytes.Buffer, x, y int) {
+	w, _, _ := c.size()
+	ys, _ := c.lines()
+	for i, item := range c {
+		iw, _, _ := item.size()
+		yi := y + ys[i]
+		if i == 0 {
+			line(buf, x, y, x+2*radius, y)
+		} else {
+			fmt.Fprintf(buf, `<path d="M%d %da%d %d 0 0 1 %d %dV%da%d %d 0 0 0 %d %d"/>`+"\n",
+				x, y, radius, radius, radius, radius, yi-radius, radius, radius, radius, radius)
+			fmt.Fprintf(buf, `<path d="M%d %da%d %d 0 0 0 %d %dV%da%d %d 0 0 1 %d %d"/>`+"\n",
+				x+w-2*radius, yi, radius, radius, radius, -radius, y+radius, radius, radius, radius, -radius)
+		}
+		item.draw(buf, x+2*radius, yi)
+		line(buf, x+2*radius+iw, yi, x+w-2*radius, yi)
+		if i == 0 {
+			line(buf, x+w-2*radius, y, x+w, y)
+		}
+	}
+}
+
+func (l *loop) size() (int, int, int) {
+	w, up, down := l.item.size()
+	return w + 4*radius, up, max(down+vGap, 2*radius)
+}
+
+func (l *loop) draw(buf *bytes.Buffer, x, y int) {
+	w, _, down := l.size()
+	iw, _, _ := l.item.size()
+	line(buf, x, y, x+2*radius, y)
+	l.item.draw(buf, x+2*radius, y)
+	line(buf, x+2*radius+iw, y, x+w, y)
+	fmt.Fprintf(buf, `<path d="M%d %da%d %d 0 0 1 %d %dV%da%d %d 0 0 1 %d %dH%da%d %d 0 0 1 %d %dV%da%d %d 0 0 1 %d %d"/>`+"\n",
+		x+w-2*radius, y, radius, radius, radius, radius, y+down-radius, radius, radius, -radius, radius,
+		x+2*radius, radius, radius, -radius, -radius, y+radius, radius, radius, radius, -radius)
+}
+
+func (g *group) size() (int, int, int) {
+	w, up, down := g.item.size()
+	w = max(w, utf8.RuneCountInString(g.text)*labelWidth)
+	if g.border {
+		return w + 2*pad, up + 2*pad + boxHeight/2, down + pad
+	}
+	return w, up + boxHeight/2, down
+}
+
+func (g *group) draw(buf *bytes.Buffer, x, y int) {
+	w, up, down := g.size()
+	iw, _, _ := g.item.size()
+	ix := x + (w-iw)/2
+	if g.border {
+		fmt.Fprintf(buf, `<rect class="group" x="%d" y="%d" width="%d" height="%d"/>`+"\n", x, y-up, w, up+down)
+		fmt.Fprintf(buf, `<text class="caption" x="%d" y="%d">%s</text>`+"\n", x+pad, y-up+pad+4, html.EscapeString(g.text))
+	} else {
+		fmt.Fprintf(buf, `<text class="caption" x="%d" y="%d">%s</text>`+"\n", x, y-up+8, html.EscapeString(g.text))
+	}
+	line(buf, x, y, ix, y)
+	g.item.draw(buf, ix, y)
+	line(buf, ix+iw, y, x+w, y)
+}
+
+// railroad returns the railroad diagram of the peg.Peg expression "node",
+// in which the names in "params" are parameters rather than rules.
+func railroad(node *Node, params map[string]bool) railItem {
+	switch node.Name {
+	case "Expression":
+		var c choice
+		for _, child := range node.Children {
+			c = append(c, railroad(child, params))
+		}
+		if len(c) == 1 {
+			return c[0]
+		}
+		return c
+	case "Sequence":
+		var s sequence
+		for _, child := range node.Children {
+			if child.Name != "Action" {
+				s = append(s, railroad(child, params))
+			}
+		}
+		if len(s) == 1 {
+			return s[0]
+		}
+		return s
+	case "Prefix":
+		item := railroad(node.Children[len(node.Children)-1], params)
+		if label := PrefixLabel(node); label != "" {
+			item = &group{item: item, text: label + ":"}
+		}
+		if pred := PrefixPredicate(node); pred != nil && pred.Name == "AND" {
+			item = &group{item: item, text: "followed by", border: true}
+		} else if pred != nil {
+			item = &group{item: item, text: "not followed by", border: true}
+		}
+		return item
+	case "Suffix":
+		item := railroad(node.Children[0], params)
+		if len(node.Children) > 1 {
+			switch node.Children[len(node.Children)-1].Name {
+			case "QUESTION":
+				item = choice{skip{}, item}
+			case "STAR":
+				item = choice{skip{}, &loop{item}}
+			case "PLUS":
+				item = &loop{item}
+			}
+		}
+		return item
+	case "Primary":
+		return railroad(node.Children[0], params)
+	case "Identifier":
+		name := strings.TrimSpace(node.Data())
+		if params[name] {
+			return &box{text: name}
+		}
+		return &box{text: name, href: "rule-" + name}
+	case "Call":
+		var args []string
+		for _, arg := range node.Children[1:] {
+			args = append(args, strings.Join(strings.Fields(arg.Data()), " "))
+		}
+		name := node.Children[0].Data()
+		return &box{text: name + "(" + strings.Join(args, ", ") + ")", href: "rule-" + name}
+	case "DOT":
+		return &box{text: "any character"}
+	}
+	return &box{text: strings.TrimSpace(node.Data())}
+}
+
+// RailroadDiagram writes an SVG railroad diagram of the peg.Peg
+// Definition node "def" to "w".
+func RailroadDiagram(w io.Writer, def *Node) error {
+	var buf bytes.Buffer
+	params := make(map[string]bool)
+	for _, param := range DefinitionParameters(def) {
+		params[param] = true
+	}
+	item := railroad(DefinitionExpression(def), params)
+	iw, up, down := item.size()
+	width, height := iw+2*margin+2*hGap, up+down+2*margin
+	y := margin + up
+	fmt.Fprintf(&buf, `<svg class="railroad" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	// The start and end of the diagram are marked with bars
+	fmt.Fprintf(&buf, `<path d="M%d %dv%dM%d %dv%d"/>`+"\n", margin, y-radius, 2*radius, width-margin, y-radius, 2*radius)
+	line(&buf, margin, y, margin+hGap, y)
+	item.draw(&buf, margin+hGap, y)
+	line(&buf, margin+hGap+iw, y, width-margin, y)
+	buf.WriteString("</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Railroad writes a standalone HTML page to "w" with a railroad diagram
+// for each Definition of "grammar", which should be the root node of a
+// peg.Peg parse. The references to other rules link to their diagrams,
+// which list the rules referring to them.
+func Railroad(w io.Writer, grammar *Node) error {
+	var (
+		buf   bytes.Buffer
+		defs  []*Node
+		users = make(map[string][]string)
+	)
+	for _, node := range grammar.Children {
+		if node.Name != "Definition" {
+			continue
+		}
+		defs = append(defs, node)
+		name := node.Children[0].Data()
+		seen := make(map[string]bool)
+		var refs func(n *Node)
+		refs = func(n *Node) {
+			if n.Name == "Primary" && n.Children[0].Name == "Identifier" || n.Name == "Call" {
+				if ref := strings.TrimSpace(n.Children[0].Data()); !seen[ref] {
+					seen[ref] = true
+					users[ref] = append(users[ref], name)
+				}
+			}
+			for _, child := range n.Children {
+				refs(child)
+			}
+		}
+		refs(node)
+	}
+	buf.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Grammar</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+pre { background: #f4f4f4; padding: 8px; }
+.used { color: #666; font-size: 90%; }
+svg.railroad path { stroke: #333; stroke-width: 2; fill: none; }
+svg.railroad rect { stroke: #333; stroke-width: 2; }
+svg.railroad rect.terminal { fill: #ffd; }
+svg.railroad rect.rule { fill: #def; }
+svg.railroad rect.group { fill: none; stroke-dasharray: 4 3; stroke-width: 1; }
+svg.railroad text { font-family: monospace; font-size: 13px; text-anchor: middle; }
+svg.railroad text.caption { font-size: 10px; text-anchor: start; fill: #666; }
+svg.railroad a:hover rect { fill: #bdf; }
+</style>
+</head>
+<body>
+`)
+	for _, def := range defs {
+		name := html.EscapeString(def.Children[0].Data())
+		fmt.Fprintf(&buf, `<h2 id="rule-%s">%s</h2>`+"\n", name, name)
+		fmt.Fprintf(&buf, "<pre>%s</pre>\n", html.EscapeString(strings.TrimSpace(def.Data())))
+		if err := RailroadDiagram(&buf, def); err != nil {
+			return err
+		}
+		if u := users[def.Children[0].Data()]; len(u) > 0 {
+			buf.WriteString(`<p class="used">Used by:`)
+			for _, user := range u {
+				user = html.EscapeString(user)
+				fmt.Fprintf(&buf, ` <a href="#rule-%s">%s</a>`, user, user)
+			}
+			buf.WriteString("</p>\n")
+		}
+	}
+	buf.WriteString("</body>\n</html>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,117 @@
+package render
+
+import (
+	"bytes"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, data string) *Node {
+	var p peg.Peg
+	if !p.Parse(data) {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	return p.RootNode()
+}
+
+func TestDOT(t *testing.T) {
+	root := parse(t, "A <- \"\\\"\"\n")
+	root.Children[0].Children[0].Label = "name"
+	var buf bytes.Buffer
+	if err := DOT(&buf, root.Children[0]); err != nil {
+		t.Fatal(err)
+	}
+	if s, want := buf.String(), `digraph tree {
+	node [shape=box, fontname="monospace"];
+	n0 [label="Definition\n0-10"];
+	n1 [label="name:Identifier\n0-1\n\"A\""];
+	n0 -> n1;
+	n2 [label="Expression\n5-10"];
+	n3 [label="Sequence\n5-10"];
+	n4 [label="Prefix\n5-10"];
+	n5 [label="Suffix\n5-10"];
+	n6 [label="Primary\n5-9"];
+	n7 [label="Literal\n5-9"];
+	n8 [label="Char\n6-8\n\"\\\\\\\"\""];
+	n7 -> n8;
+	n6 -> n7;
+	n5 -> n6;
+	n4 -> n5;
+	n3 -> n4;
+	n2 -> n3;
+	n0 -> n2;
+}
+`; s != want {
+		t.Errorf("Unexpected output\n%s", s)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	data := "A <- \"a&'\" / [ä]\n"
+	root := parse(t, data)
+	var buf bytes.Buffer
+	if err := HTML(&buf, root, data); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		"<title>Peg</title>",
+		`<details open><summary data-a="0" data-b="18">Peg <span class="range">0-18</span></summary>`,
+		`<div class="leaf" data-a="0" data-b="1">Identifier <span class="range">0-1</span> <span class="data">&#34;A&#34;</span></div>`,
+		`<span data-o="6">a</span><span data-o="7">&amp;</span>`,
+		`<span data-o="14">ä</span>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected the page to contain %s", want)
+		}
+	}
+	var source string
+	for _, part := range strings.Split(s[strings.Index(s, `<pre id="source">`):strings.Index(s, "</pre>")], "</span>") {
+		if i := strings.LastIndex(part, ">"); i >= 0 {
+			source += part[i+1:]
+		}
+	}
+	if want := strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&#34;", "'", "&#39;").Replace(data); source != want {
+		t.Errorf("Expected the source %q, got %q", want, source)
+	}
+}
+
+func TestRailroad(t *testing.T) {
+	root := parse(t, `A <- x:B ('<' / [a-z])? &B !. B* B+ Twice(B)
+B <- "b" A?
+Twice(x) <- x x
+`)
+	var buf bytes.Buffer
+	if err := Railroad(&buf, root); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		`<h2 id="rule-A">A</h2>`,
+		`<h2 id="rule-B">B</h2>`,
+		`<a href="#rule-B"><rect class="rule"`,
+		`>B</text></a>`,
+		`>&#39;&lt;&#39;</text>`,
+		`>[a-z]</text>`,
+		`>&#34;b&#34;</text>`,
+		`>any character</text>`,
+		`>x:</text>`,
+		`>followed by</text>`,
+		`>not followed by</text>`,
+		`<p class="used">Used by: <a href="#rule-B">B</a></p>`,
+		`<p class="used">Used by: <a href="#rule-A">A</a></p>`,
+		`<a href="#rule-Twice"><rect class="rule" x="`,
+		`>Twice(B)</text></a>`,
+		`<rect class="terminal" x="`,
+		`>x</text>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected the page to contain %s", want)
+		}
+	}
+	if n := strings.Count(s, "<svg "); n != 3 {
+		t.Errorf("Expected 3 diagrams, got %d", n)
+	}
+}
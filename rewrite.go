@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jxo/lime/text"
+	"sort"
+)
+
+type (
+	// Rewriter records edits to the data a tree of Nodes was parsed from,
+	// and applies them leaving the data outside of the edited Ranges as
+	// it was, byte for byte.
+	Rewriter struct {
+		data  string
+		edits []rewrite
+	}
+
+	// rewrite replaces the data in "r" with "data"
+	rewrite struct {
+		r    text.Region
+		data string
+	}
+)
+
+// NewRewriter returns a Rewriter editing "data".
+func NewRewriter(data string) *Rewriter {
+	return &Rewriter{data: data}
+}
+
+// ReplaceRegion replaces the data in "r" with "data".
+func (rw *Rewriter) ReplaceRegion(r text.Region, data string) {
+	rw.edits = append(rw.edits, rewrite{text.Region{A: r.Begin(), B: r.End()}, data})
+}
+
+// Replace replaces the data in the Range of "n" with "data",
+// keeping its Leading and Trailing text.
+func (rw *Rewriter) Replace(n *Node, data string) {
+	rw.ReplaceRegion(n.Range, data)
+}
+
+// InsertBefore inserts "data" at the beginning of the Range of "n".
+// Data inserted at the same offset is kept in the order it's inserted.
+func (rw *Rewriter) InsertBefore(n *Node, data string) {
+	rw.ReplaceRegion(text.Region{A: n.Range.Begin(), B: n.Range.Begin()}, data)
+}
+
+// InsertAfter inserts "data" at the end of the Range of "n".
+func (rw *Rewriter) InsertAfter(n *Node, data string) {
+	rw.ReplaceRegion(text.Region{A: n.Range.End(), B: n.Range.End()}, data)
+}
+
+// Delete removes "n" along with its Leading and Trailing text,
+// as given by its Extent.
+func (rw *Rewriter) Delete(n *Node) {
+	rw.ReplaceRegion(n.Extent(), "")
+}
+
+// Apply returns the data with the edits applied, or an error
+// if any of the replaced Ranges overlap.
+func (rw *Rewriter) Apply() (string, error) {
+	edits := make([]rewrite, len(rw.edits))
+	copy(edits, rw.edits)
+	// Insertions go before replacements at the same offset
+	sort.SliceStable(edits, func(i, j int) bool {
+		if a, b := edits[i].r, edits[j].r; a.A != b.A {
+			return a.A < b.A
+		}
+		return edits[i].r.Empty() && !edits[j].r.Empty()
+	})
+	var (
+		buf  bytes.Buffer
+		pos  int
+		last text.Region
+	)
+	for _, e := range edits {
+		if e.r.B > len(rw.data) {
+			return "", fmt.Errorf("The edit of %d-%d is past the end of the data", e.r.A, e.r.B)
+		} else if e.r.A < pos {
+			return "", fmt.Errorf("The edit of %d-%d overlaps the edit of %d-%d", e.r.A, e.r.B, last.A, last.B)
+		}
+		buf.WriteString(rw.data[pos:e.r.A])
+		buf.WriteString(e.data)
+		pos = e.r.B
+		last = e.r
+	}
+	buf.WriteString(rw.data[pos:])
+	return buf.String(), nil
+}
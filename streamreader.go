@@ -0,0 +1,366 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// StreamReader is a Reader pulling its data from an io.Reader as
+	// parsing progresses, rather than requiring all of it up front.
+	//
+	// Only the data from the oldest offset the parser might still seek
+	// back to, as recorded in its Backtrack, is kept in its buffer. Data
+	// dropped from the buffer is still kept for the leaves of the Live
+	// Node and of the Trivia, and for the rules being matched that might
+	// turn out to be leaves, so that the Nodes parsed can return their Data.
+	//
+	// Reading data that has been dropped, or failing to read from the
+	// io.Reader, makes the StreamReader behave as if the data ended and
+	// the error is returned by Err.
+	StreamReader struct {
+		// The Node whose leaves' data is kept when it's dropped
+		// from the buffer, usually the Root of the parser
+		Live *Node
+		// The Node holding the trivia kept by the parser as its
+		// Children, whose leaves' data is kept as well, or nil
+		Trivia *Node
+		// The offsets the parser might seek back to, or nil to
+		// only keep the data from the current offset on
+		Backtrack *Backtrack
+
+		r   io.Reader
+		buf []byte
+		// The offset of the first byte in buf
+		base     int
+		pos      int
+		furthest int
+		eof      bool
+		err      error
+		index    lineIndex
+		kept     []keptData
+	}
+
+	// keptData is data dropped from the buffer of a StreamReader
+	// that's still needed by a Node.
+	keptData struct {
+		offset int
+		data   string
+	}
+
+	// Backtrack keeps track of the offsets a parser might seek back to
+	// and read the input from again, such as where the alternative it
+	// is trying started, so that a StreamReader can drop the data that
+	// precedes all of them.
+	//
+	// It also keeps track of where the rules being matched started, as
+	// their Nodes need the data from there on if they turn out to be
+	// leaves.
+	//
+	// The offsets are saved and released in a nested fashion, so the
+	// oldest one is the first saved that's still in use.
+	Backtrack struct {
+		offsets []int
+		// Whether each of the offsets is where a rule being matched
+		// started, rather than one the parser might seek back to
+		rules []bool
+	}
+)
+
+// Save records that the parser might seek back to "offset", and returns
+// the value to pass to Release once it no longer will.
+func (b *Backtrack) Save(offset int) int {
+	return b.save(offset, false)
+}
+
+// Enter records that a rule started matching at "offset", and returns
+// the value to pass to Release once its Node has been created.
+func (b *Backtrack) Enter(offset int) int {
+	return b.save(offset, true)
+}
+
+func (b *Backtrack) save(offset int, rule bool) int {
+	b.offsets = append(b.offsets, offset)
+	b.rules = append(b.rules, rule)
+	return len(b.offsets) - 1
+}
+
+// Release drops the offset recorded by the call to Save or Enter that
+// returned "saved", along with any recorded after it.
+func (b *Backtrack) Release(saved int) {
+	b.offsets = b.offsets[:saved]
+	b.rules = b.rules[:saved]
+}
+
+// Reset drops all the offsets recorded.
+func (b *Backtrack) Reset() {
+	b.offsets = b.offsets[:0]
+	b.rules = b.rules[:0]
+}
+
+// Oldest returns the oldest offset the parser might seek back to,
+// or "pos" if that's older.
+func (b *Backtrack) Oldest(pos int) int {
+	for i, offset := range b.offsets {
+		if !b.rules[i] {
+			if offset < pos {
+				return offset
+			}
+			break
+		}
+	}
+	return pos
+}
+
+// Entered returns the offset where the outermost rule being matched
+// that started at or after "offset" started, or -1 if there's none.
+func (b *Backtrack) Entered(offset int) int {
+	for i, entered := range b.offsets {
+		if b.rules[i] && entered >= offset {
+			return entered
+		}
+	}
+	return -1
+}
+
+// The number of bytes a StreamReader reads from its io.Reader at a time
+const streamChunk = 32 * 1024
+
+// NewStreamReader returns a StreamReader reading from "r".
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{r: r}
+}
+
+// Err returns the error reading from the io.Reader, or the error
+// caused by reading data no longer kept.
+func (s *StreamReader) Err() error {
+	return s.err
+}
+
+func (s *StreamReader) fail(offset int) {
+	if s.err == nil {
+		s.err = fmt.Errorf("offset %d is no longer buffered", offset)
+	}
+}
+
+// fill reads from the io.Reader until the data preceding "offset"
+// is buffered, or there's no more data.
+func (s *StreamReader) fill(offset int) {
+	for !s.eof && s.base+len(s.buf) < offset {
+		s.slide()
+		if cap(s.buf)-len(s.buf) < streamChunk/2 {
+			buf := make([]byte, len(s.buf), 2*cap(s.buf)+streamChunk)
+			copy(buf, s.buf)
+			s.buf = buf
+		}
+		n, err := s.r.Read(s.buf[len(s.buf):cap(s.buf)])
+		s.buf = s.buf[:len(s.buf)+n]
+		if err != nil {
+			s.eof = true
+			if err != io.EOF {
+				s.err = err
+			}
+		}
+		s.index.scan(string(s.buf[s.index.scanned-s.base:]), s.eof)
+	}
+}
+
+// slide drops the data no longer needed from the front of the buffer,
+// once that's at least half of it.
+func (s *StreamReader) slide() {
+	keep := s.pos
+	if s.Backtrack != nil {
+		keep = s.Backtrack.Oldest(keep)
+	}
+	if end := s.base + len(s.buf); keep > end {
+		// Seeking ahead doesn't skip the data not read yet
+		keep = end
+	}
+	drop := keep - s.base
+	if drop <= 0 || drop < len(s.buf)/2 {
+		return
+	}
+	s.retain(s.base, keep)
+	s.buf = s.buf[:copy(s.buf, s.buf[drop:])]
+	s.base = keep
+}
+
+// retain keeps the data between "start" and "end" that's covered by the
+// leaves of the Live Node and of the Trivia, or by the rule being matched
+// that hasn't created any Nodes yet, as its Node would be a leaf.
+func (s *StreamReader) retain(start, end int) {
+	var (
+		spans   [][2]int
+		collect func(children []*Node)
+	)
+	keep := func(a, b int) {
+		if a < start {
+			a = start
+		}
+		if b > end {
+			b = end
+		}
+		if a < b {
+			spans = append(spans, [2]int{a, b})
+		}
+	}
+	collect = func(children []*Node) {
+		for i := len(children) - 1; i >= 0; i-- {
+			n := children[i]
+			if n.Range.End() <= start {
+				break
+			} else if n.Range.Begin() >= end {
+				continue
+			} else if len(n.Children) > 0 {
+				collect(n.Children)
+				continue
+			}
+			keep(n.Range.Begin(), n.Range.End())
+		}
+	}
+	if s.Live != nil {
+		collect(s.Live.Children)
+		if s.Backtrack != nil {
+			// The Nodes created before a rule started end before it
+			created := 0
+			if l := len(s.Live.Children); l > 0 {
+				created = s.Live.Children[l-1].Range.End()
+			}
+			if entered := s.Backtrack.Entered(created); entered >= 0 {
+				keep(entered, end)
+			}
+		}
+	}
+	if s.Trivia != nil {
+		collect(s.Trivia.Children)
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i][0] < spans[j][0]
+	})
+	for i := 0; i < len(spans); {
+		// Merge the spans overlapping this one
+		a, b := spans[i][0], spans[i][1]
+		for i++; i < len(spans) && spans[i][0] <= b; i++ {
+			if spans[i][1] > b {
+				b = spans[i][1]
+			}
+		}
+		s.kept = append(s.kept, keptData{a, string(s.buf[a-s.base : b-s.base])})
+	}
+}
+
+// Buffered returns the number of bytes of data currently in its buffer.
+func (s *StreamReader) Buffered() int {
+	return len(s.buf)
+}
+
+func (s *StreamReader) examine() {
+	if s.pos > s.furthest {
+		s.furthest = s.pos
+	}
+}
+
+// Len returns the length of the data read from the io.Reader so far,
+// which extends past the furthest offset read unless the end of the
+// data has been reached.
+func (s *StreamReader) Len() int {
+	offset := s.pos
+	if s.furthest > offset {
+		offset = s.furthest
+	}
+	s.fill(offset + 1)
+	return s.base + len(s.buf)
+}
+
+func (s *StreamReader) Pos() int {
+	return s.pos
+}
+
+func (s *StreamReader) Read() rune {
+	if s.pos < s.base {
+		s.fail(s.pos)
+		s.pos++
+		return nilrune
+	}
+	s.fill(s.pos + utf8.UTFMax)
+	if s.pos >= s.base+len(s.buf) {
+		s.pos++
+		s.examine()
+		return nilrune
+	}
+	r, size := utf8.DecodeRune(s.buf[s.pos-s.base:])
+	s.pos += size
+	s.examine()
+	return r
+}
+
+func (s *StreamReader) UnRead() {
+	s.pos--
+	for s.pos > s.base && s.pos < s.base+len(s.buf) && !utf8.RuneStart(s.buf[s.pos-s.base]) {
+		s.pos--
+	}
+}
+
+func (s *StreamReader) Seek(offset int) {
+	s.pos = offset
+}
+
+func (s *StreamReader) LineCol(offset int) (line, column int) {
+	return s.Position(offset, ColumnRunes)
+}
+
+func (s *StreamReader) Position(offset int, unit ColumnUnit) (line, column int) {
+	s.fill(offset)
+	return s.index.lineCol(offset, unit)
+}
+
+func (s *StreamReader) Offset(line, column int, unit ColumnUnit) int {
+	// Read up to the end of the line
+	for !s.eof && len(s.index.lines) <= line {
+		s.fill(s.base + len(s.buf) + 1)
+	}
+	return s.index.offset(line, column, unit)
+}
+
+func (s *StreamReader) Substring(start, end int) string {
+	if start < 0 {
+		start = 0
+	}
+	s.fill(end)
+	if l := s.base + len(s.buf); end > l {
+		end = l
+	}
+	if start >= end {
+		return ""
+	} else if start >= s.base {
+		return string(s.buf[start-s.base : end-s.base])
+	}
+	// Piece it together from the data kept for Nodes
+	var buf strings.Builder
+	pos := start
+	i := sort.Search(len(s.kept), func(i int) bool {
+		return s.kept[i].offset+len(s.kept[i].data) > start
+	})
+	for ; pos < end && pos < s.base && i < len(s.kept); i++ {
+		k := s.kept[i]
+		if k.offset > pos {
+			break
+		}
+		stop := k.offset + len(k.data)
+		if stop > end {
+			stop = end
+		}
+		buf.WriteString(k.data[pos-k.offset : stop-k.offset])
+		pos = stop
+	}
+	if pos < end && pos < s.base {
+		s.fail(pos)
+		return ""
+	} else if pos < end {
+		buf.Write(s.buf[pos-s.base : end-s.base])
+	}
+	return buf.String()
+}
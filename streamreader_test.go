@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"github.com/jxo/lime/text"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamReader(t *testing.T) {
+	var (
+		s    ds
+		data = strings.Repeat("line ⌘ \U0001F600\nå\n", 10000)
+		root = Node{P: s}
+		r    = NewStreamReader(iotest.HalfReader(strings.NewReader(data)))
+		b    = NewReader(data)
+	)
+	r.Live = &root
+	for i := 0; r.Pos() < r.Len(); i++ {
+		start := r.Pos()
+		if c, want := r.Read(), b.Read(); c != want {
+			t.Fatalf("Expected %q at %d, got %q", want, start, c)
+		}
+		if i%100 == 0 {
+			root.Append(&Node{Range: text.Region{A: start, B: r.Pos()}, P: s})
+		}
+		if i%7 == 0 {
+			r.UnRead()
+			r.Read()
+		}
+	}
+	if r.Len() != len(data) || r.Err() != nil {
+		t.Fatalf("Expected all of the data to be read, got %d %v", r.Len(), r.Err())
+	}
+	if r.Buffered() > 2*streamChunk {
+		t.Errorf("Expected the buffer to be no larger than needed, got %d bytes", len(r.buf))
+	}
+	for _, n := range root.Children {
+		if a, b := r.Substring(n.Range.A, n.Range.B), data[n.Range.A:n.Range.B]; a != b {
+			t.Fatalf("Expected the data of %v to be kept as %q, got %q", n.Range, b, a)
+		}
+	}
+	for _, offset := range []int{0, 5, 7, 11, 12, 13, 1000, len(data) - 1, len(data)} {
+		l1, c1 := r.LineCol(offset)
+		l2, c2 := b.LineCol(offset)
+		if l1 != l2 || c1 != c2 {
+			t.Errorf("Expected %d to be at %d,%d, got %d,%d", offset, l2, c2, l1, c1)
+		}
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	if r.Substring(2, 20) != "" || r.Err() == nil {
+		t.Error("Expected reading data no longer kept to fail")
+	}
+
+	r = NewStreamReader(iotest.TimeoutReader(strings.NewReader(data)))
+	r.Seek(streamChunk + 1)
+	if r.Read() != nilrune || r.Err() != iotest.ErrTimeout {
+		t.Errorf("Expected the read error to be returned, got %v", r.Err())
+	}
+}
+
+func TestStreamReaderBacktrack(t *testing.T) {
+	var (
+		b    Backtrack
+		data = strings.Repeat("0123456789", 20000)
+		r    = NewStreamReader(strings.NewReader(data))
+	)
+	r.Backtrack = &b
+	r.Seek(1001)
+	saved := b.Save(r.Pos())
+	for r.Pos() < len(data)/2 {
+		r.Read()
+	}
+	r.Seek(1001)
+	if c := r.Read(); c != '1' || r.Err() != nil {
+		t.Fatalf("Expected the data of a saved offset to be kept, got %q %v", c, r.Err())
+	}
+	b.Release(saved)
+	for r.Pos() < r.Len() {
+		r.Read()
+	}
+	r.Seek(1001)
+	if r.Read() != nilrune || r.Err() == nil {
+		t.Error("Expected the data of a released offset to be dropped")
+	}
+}
+
+func TestStreamReaderEntered(t *testing.T) {
+	var (
+		b    Backtrack
+		live Node
+		data = strings.Repeat("0123456789", 20000)
+		r    = NewStreamReader(strings.NewReader(data))
+	)
+	r.Backtrack = &b
+	r.Live = &live
+	// A rule being matched doesn't keep its data in the buffer,
+	// but has it kept in case its Node turns out to be a leaf
+	entered := b.Enter(1001)
+	if b.Oldest(2000) != 2000 {
+		t.Error("Expected where a rule started not to be seeked back to")
+	}
+	for r.Pos() < len(data)-1 {
+		r.Read()
+	}
+	if r.Buffered() > len(data)/2 {
+		t.Errorf("Expected the buffer to stay bounded, got %d bytes", r.Buffered())
+	}
+	live.Append(&Node{Name: "Leaf", Range: text.Region{A: 1001, B: len(data) - 1}})
+	b.Release(entered)
+	if s := r.Substring(1001, len(data)-1); s != data[1001:len(data)-1] || r.Err() != nil {
+		t.Errorf("Expected the data of the rule to be kept, got %d bytes %v", len(s), r.Err())
+	}
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// TemplateError is a misuse of the parameterized definitions of a grammar,
+// as found by ExpandTemplates.
+type TemplateError struct {
+	// The node the error was found at, and the
+	// definition it was found in
+	Node        *Node
+	Rule        string
+	Description string
+}
+
+func (e *TemplateError) Error() string {
+	return e.Description
+}
+
+// ExpandTemplates returns the peg.Peg Grammar node "root" with the calls of
+// its parameterized definitions, such as "List(x, sep) <- x (sep x)*",
+// expanded.
+//
+// A Call is replaced by a group of the Expression of the definition it
+// calls, with the arguments of the call grouped in place of the parameters,
+// so that "List(Value, ',')" becomes "(Value (',' Value)*)". A call thus
+// doesn't create a Node of its own. The parameterized definitions are left
+// out of the returned grammar, and can't call themselves, directly or
+// through other parameterized definitions.
+//
+// The expanded definitions are copies, leaving "root" as it is. If "root"
+// has no parameterized definitions or calls, it's returned as it is. The
+// error returned is a *TemplateError.
+func ExpandTemplates(root *Node) (*Node, error) {
+	templates := make(map[string]*Node)
+	for _, node := range root.Children {
+		if node.Name != "Definition" || DefinitionParameters(node) == nil {
+			continue
+		}
+		name := node.Children[0].Data()
+		if DefinitionRecovery(node) != nil {
+			return nil, &TemplateError{node, name, fmt.Sprintf("%s has parameters, which definitions with a recovery expression can't have", name)}
+		}
+		if DefinitionPrecedence(node) != nil {
+			return nil, &TemplateError{node, name, fmt.Sprintf("%s has parameters, which definitions with a precedence block can't have", name)}
+		}
+		templates[name] = node
+	}
+	if len(templates) == 0 && !contains(root, "Call") {
+		return root, nil
+	}
+	ret := &Node{Name: root.Name, Range: root.Range, P: root.P}
+	for _, node := range root.Children {
+		if node.Name == "Definition" {
+			name := node.Children[0].Data()
+			if templates[name] != nil {
+				continue
+			}
+			node = node.Clone()
+			if err := expand(node, name, templates, nil, nil); err != nil {
+				return nil, err
+			}
+		}
+		ret.Append(node)
+	}
+	return ret, nil
+}
+
+// expand expands the calls in the copied expression "n" of the definition
+// "rule", replacing the parameters in "args" with copies of their arguments.
+// The parameterized definitions being expanded are in "stack".
+func expand(n *Node, rule string, templates, args map[string]*Node, stack []string) error {
+	for i, child := range n.Children {
+		switch {
+		case n.Name == "Primary" && child.Name == "Identifier":
+			name := child.Data()
+			if arg, ok := args[name]; ok {
+				n.Children[i] = arg.Clone()
+			} else if templates[name] != nil {
+				return &TemplateError{child, rule, fmt.Sprintf("%s has parameters, but is called without arguments", name)}
+			}
+		case child.Name == "Call":
+			name := child.Children[0].Data()
+			template := templates[name]
+			if template == nil {
+				return &TemplateError{child, rule, fmt.Sprintf("%s doesn't have parameters, but is called with arguments", name)}
+			}
+			params := DefinitionParameters(template)
+			if len(params) != len(child.Children)-1 {
+				return &TemplateError{child, rule, fmt.Sprintf("%s takes %d arguments, but is called with %d", name, len(params), len(child.Children)-1)}
+			}
+			for _, s := range stack {
+				if s == name {
+					return &TemplateError{child, rule, fmt.Sprintf("%s calls itself, which parameterized definitions can't", name)}
+				}
+			}
+			values := make(map[string]*Node)
+			for j, param := range params {
+				arg := child.Children[j+1].Clone()
+				if err := expand(arg, rule, templates, args, stack); err != nil {
+					return err
+				}
+				values[param] = arg
+			}
+			body := DefinitionExpression(template).Clone()
+			if err := expand(body, name, templates, values, append(stack, name)); err != nil {
+				return err
+			}
+			n.Children[i] = body
+		default:
+			if err := expand(child, rule, templates, args, stack); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
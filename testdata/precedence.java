@@ -0,0 +1,282 @@
+	private boolean precedence_Expr(int min) {
+		// Applies the operators of Expr binding at least as tightly
+		// as the level "min" to its operands.
+		boolean accept = false;
+		int start = parserData.pos;
+		int last = 0;
+		if (!accept) {
+			{
+				int pos = parserData.pos;
+				if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '-') {
+					accept = false;
+				} else {
+					parserData.pos++;
+					accept = true;
+				}
+				if (accept) {
+					Node node = Root.Cleanup(pos, parserData.pos);
+					node.Name = "Operator";
+					node.Range.Clip(ignoreRange);
+					Root.Append(node);
+				} else {
+					Root.Discard(pos);
+				}
+			}
+			if (accept) {
+				int level = 5;
+				if (level < min) {
+					level = min;
+				}
+				if ((accept = precedence_Expr(level))) {
+					Node node = Root.Cleanup(start, parserData.pos);
+					node.Name = "PrefixOp";
+					node.Range.Clip(ignoreRange);
+					Root.Append(node);
+				} else {
+					parserData.pos = start;
+					Root.Discard(start);
+				}
+			}
+		}
+		if (!accept) {
+			{
+				int save_0 = parserData.pos;
+				accept = Value();
+				if (!accept) {
+					{
+						int save_1 = parserData.pos;
+						if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '(') {
+							accept = false;
+						} else {
+							parserData.pos++;
+							accept = true;
+						}
+						if (accept) {
+							accept = Expr();
+							if (accept) {
+								if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != ')') {
+									accept = false;
+								} else {
+									parserData.pos++;
+									accept = true;
+								}
+								if (accept) {
+								}
+							}
+						}
+						if (!accept) {
+							{ /* if (LastError < parserData.pos) { LastError = parserData.pos; */ }
+							parserData.pos = save_1;
+						}
+					}
+					if (!accept) {
+					}
+				}
+				if (!accept) {
+					parserData.pos = save_0;
+				}
+			}
+			if (!accept) {
+				return false;
+			}
+		}
+		for (;;) {
+			int save = parserData.pos;
+			if (min <= 1 && last != 1) {
+				{
+					int pos = parserData.pos;
+					{
+						int save_2 = parserData.pos;
+						if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '<') {
+							accept = false;
+						} else {
+							parserData.pos++;
+							accept = true;
+						}
+						if (!accept) {
+							if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '=') {
+								accept = false;
+							} else {
+								parserData.pos++;
+								accept = true;
+							}
+							if (!accept) {
+							}
+						}
+						if (!accept) {
+							parserData.pos = save_2;
+						}
+					}
+					if (accept) {
+						Node node = Root.Cleanup(pos, parserData.pos);
+						node.Name = "Operator";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+					} else {
+						Root.Discard(pos);
+					}
+				}
+				if (accept) {
+					if ((accept = precedence_Expr(2))) {
+						Node node = Root.Cleanup(start, parserData.pos);
+						node.Name = "BinaryOp";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+						last = 1;
+						continue;
+					}
+					parserData.pos = save;
+					Root.Discard(save);
+				}
+			}
+			if (min <= 2) {
+				{
+					int pos = parserData.pos;
+					{
+						int save_3 = parserData.pos;
+						if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '+') {
+							accept = false;
+						} else {
+							parserData.pos++;
+							accept = true;
+						}
+						if (!accept) {
+							if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '-') {
+								accept = false;
+							} else {
+								parserData.pos++;
+								accept = true;
+							}
+							if (!accept) {
+							}
+						}
+						if (!accept) {
+							parserData.pos = save_3;
+						}
+					}
+					if (accept) {
+						Node node = Root.Cleanup(pos, parserData.pos);
+						node.Name = "Operator";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+					} else {
+						Root.Discard(pos);
+					}
+				}
+				if (accept) {
+					if ((accept = precedence_Expr(3))) {
+						Node node = Root.Cleanup(start, parserData.pos);
+						node.Name = "BinaryOp";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+						continue;
+					}
+					parserData.pos = save;
+					Root.Discard(save);
+				}
+			}
+			if (min <= 3) {
+				{
+					int pos = parserData.pos;
+					{
+						int save_4 = parserData.pos;
+						if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '*') {
+							accept = false;
+						} else {
+							parserData.pos++;
+							accept = true;
+						}
+						if (!accept) {
+							if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '/') {
+								accept = false;
+							} else {
+								parserData.pos++;
+								accept = true;
+							}
+							if (!accept) {
+							}
+						}
+						if (!accept) {
+							parserData.pos = save_4;
+						}
+					}
+					if (accept) {
+						Node node = Root.Cleanup(pos, parserData.pos);
+						node.Name = "Operator";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+					} else {
+						Root.Discard(pos);
+					}
+				}
+				if (accept) {
+					if ((accept = precedence_Expr(4))) {
+						Node node = Root.Cleanup(start, parserData.pos);
+						node.Name = "BinaryOp";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+						continue;
+					}
+					parserData.pos = save;
+					Root.Discard(save);
+				}
+			}
+			if (min <= 4) {
+				{
+					int pos = parserData.pos;
+					if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '^') {
+						accept = false;
+					} else {
+						parserData.pos++;
+						accept = true;
+					}
+					if (accept) {
+						Node node = Root.Cleanup(pos, parserData.pos);
+						node.Name = "Operator";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+					} else {
+						Root.Discard(pos);
+					}
+				}
+				if (accept) {
+					if ((accept = precedence_Expr(4))) {
+						Node node = Root.Cleanup(start, parserData.pos);
+						node.Name = "BinaryOp";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+						continue;
+					}
+					parserData.pos = save;
+					Root.Discard(save);
+				}
+			}
+			if (min <= 6) {
+				{
+					int pos = parserData.pos;
+					if (parserData.pos >= parserData.data.length || parserData.data[parserData.pos] != '!') {
+						accept = false;
+					} else {
+						parserData.pos++;
+						accept = true;
+					}
+					if (accept) {
+						Node node = Root.Cleanup(pos, parserData.pos);
+						node.Name = "Operator";
+						node.Range.Clip(ignoreRange);
+						Root.Append(node);
+					} else {
+						Root.Discard(pos);
+					}
+				}
+				if (accept) {
+					Node node = Root.Cleanup(start, parserData.pos);
+					node.Name = "PostfixOp";
+					node.Range.Clip(ignoreRange);
+					Root.Append(node);
+					continue;
+				}
+			}
+			return true;
+		}
+	}
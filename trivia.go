@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"github.com/jxo/lime/text"
+	"strings"
+)
+
+// edge returns the innermost Node in the tree rooted at "n" beginning at
+// "pos", or ending at it if "end" is set, or nil if there's none.
+func edge(n *Node, pos int, end bool) (ret *Node) {
+	for n != nil {
+		if end && n.Range.End() == pos || !end && n.Range.Begin() == pos {
+			ret = n
+		}
+		var next *Node
+		for _, child := range n.Children {
+			if end && child.Range.Begin() < pos && pos <= child.Range.End() ||
+				!end && child.Range.Begin() <= pos && pos < child.Range.End() {
+				next = child
+				break
+			}
+		}
+		n = next
+	}
+	return
+}
+
+// AttachTrivia attaches the ignored text in the Ranges of the Nodes
+// "trivia", such as spacing and comments, to the Nodes of the tree rooted
+// at "root" as their Leading and Trailing text.
+//
+// The ignored text between two Nodes is split after its first line break,
+// with the part up to it trailing the innermost Node ending before it, and
+// the rest leading the innermost Node beginning after it. The Leading and
+// Trailing text set by an earlier call is cleared.
+func AttachTrivia(root *Node, trivia []*Node) {
+	var clear func(n *Node)
+	clear = func(n *Node) {
+		n.Leading, n.Trailing = text.Region{}, text.Region{}
+		for _, child := range n.Children {
+			clear(child)
+		}
+	}
+	clear(root)
+
+	for i := 0; i < len(trivia); {
+		// Contiguous trivia is attached as a whole
+		r := trivia[i].Range
+		for i++; i < len(trivia) && trivia[i].Range.Begin() == r.End(); i++ {
+			r.B = trivia[i].Range.End()
+		}
+		prev, next := edge(root, r.Begin(), true), edge(root, r.End(), false)
+		split := r.End()
+		if next != nil && prev != nil {
+			if nl := strings.IndexByte(root.P.Data(r.Begin(), r.End()), '\n'); nl >= 0 {
+				split = r.Begin() + nl + 1
+			}
+		} else if prev == nil {
+			split = r.Begin()
+		}
+		if prev != nil && split > r.Begin() {
+			prev.Trailing = text.Region{A: r.Begin(), B: split}
+		}
+		if next != nil && split < r.End() {
+			next.Leading = text.Region{A: split, B: r.End()}
+		}
+	}
+}
+
+// Extent returns the Range of this Node extended over the Leading text of
+// the Nodes beginning with it, and the Trailing text of those ending with
+// it, which is what is removed along with the Node when it's deleted.
+func (n *Node) Extent() text.Region {
+	r := n.Range
+	for c := n; c != nil; {
+		if c.Leading.Size() > 0 && c.Leading.Begin() < r.A {
+			r.A = c.Leading.Begin()
+		}
+		if len(c.Children) == 0 || c.Children[0].Range.Begin() != c.Range.Begin() {
+			break
+		}
+		c = c.Children[0]
+	}
+	for c := n; c != nil; {
+		if c.Trailing.Size() > 0 && c.Trailing.End() > r.B {
+			r.B = c.Trailing.End()
+		}
+		if len(c.Children) == 0 || c.Children[len(c.Children)-1].Range.End() != c.Range.End() {
+			break
+		}
+		c = c.Children[len(c.Children)-1]
+	}
+	return r
+}
+
+// AddTrivia records the ignored text from "start" to "end", matched by
+// the definition "name", as a Node in the Children of this Node, which
+// holds the trivia of the parser whose root Node is "root". Text that Nodes
+// were created for in "root" isn't trivia, and is skipped.
+//
+// The trivia recorded after "start" before is discarded, as it was either
+// matched by alternatives that were backtracked over, or is part of the
+// text recorded now.
+func (n *Node) AddTrivia(root *Node, name string, start, end int) {
+	if c := root.Children; len(c) > 0 && c[len(c)-1].Range.End() > start {
+		return
+	}
+	n.Discard(start)
+	n.Append(&Node{Name: name, Range: text.Region{A: start, B: end}, P: n.P})
+}
@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	// RuneRange is the range of characters from "Lo" to "Hi",
+	// including both.
+	RuneRange struct {
+		Lo, Hi rune
+	}
+
+	// CharClass is the set of characters a peg.Peg Class node matches.
+	CharClass struct {
+		// The characters and ranges of characters in the class,
+		// in the order they're written
+		Ranges []RuneRange
+		// The Unicode properties written as \p{Name} in the class,
+		// which are general categories such as "L" or "Nd", or
+		// scripts such as "Greek"
+		Properties []string
+		// Whether the class is written [^...], matching any
+		// character not in it
+		Negated bool
+	}
+)
+
+// NewCharClass returns the characters matched by the peg.Peg
+// Class node "class".
+func NewCharClass(class *Node) (*CharClass, error) {
+	c := &CharClass{}
+	for _, child := range class.Children {
+		switch child.Name {
+		case "Negation":
+			c.Negated = true
+		case "Range":
+			if front := child.Children[0]; front.Name == "Property" {
+				name := front.Data()
+				name = name[3 : len(name)-1]
+				if UnicodeTable(name) == nil {
+					return nil, fmt.Errorf("%s isn't a Unicode general category or script", front.Data())
+				}
+				c.Properties = append(c.Properties, name)
+				continue
+			}
+			var bounds []rune
+			for _, char := range child.Children {
+				r, err := Unescape(char.Data())
+				if err != nil {
+					return nil, err
+				}
+				bounds = append(bounds, r...)
+			}
+			c.Ranges = append(c.Ranges, RuneRange{bounds[0], bounds[len(bounds)-1]})
+		}
+	}
+	return c, nil
+}
+
+// simple reports whether the class is neither negated nor has any Unicode
+// properties, which is what every Generator supports.
+func (c *CharClass) simple() bool {
+	return !c.Negated && len(c.Properties) == 0
+}
+
+// Contains reports whether the class matches "r".
+func (c *CharClass) Contains(r rune) bool {
+	in := false
+	for _, rr := range c.Ranges {
+		if r >= rr.Lo && r <= rr.Hi {
+			in = true
+			break
+		}
+	}
+	for i := 0; !in && i < len(c.Properties); i++ {
+		in = HasProperty(c.Properties[i], r)
+	}
+	return in != c.Negated
+}
+
+// PropertyTable returns the characters with any of the Unicode properties
+// of the class, as sorted ranges that neither overlap nor touch, for the
+// parsers of languages without Unicode tables of their own.
+func (c *CharClass) PropertyTable() []RuneRange {
+	var ranges []RuneRange
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			ranges = append(ranges, RuneRange{lo, hi})
+			return
+		}
+		for r := lo; r <= hi; r += stride {
+			ranges = append(ranges, RuneRange{r, r})
+		}
+	}
+	for _, name := range c.Properties {
+		t := UnicodeTable(name)
+		for _, r := range t.R16 {
+			add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+		for _, r := range t.R32 {
+			add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Lo < ranges[j].Lo })
+	var ret []RuneRange
+	for _, r := range ranges {
+		if n := len(ret); n > 0 && r.Lo <= ret[n-1].Hi+1 {
+			if r.Hi > ret[n-1].Hi {
+				ret[n-1].Hi = r.Hi
+			}
+		} else {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// UnicodeTable returns the table of the Unicode general category or script
+// "name", or nil if there's none.
+func UnicodeTable(name string) *unicode.RangeTable {
+	if t := unicode.Categories[name]; t != nil {
+		return t
+	}
+	return unicode.Scripts[name]
+}
+
+// HasProperty reports whether "r" has the Unicode property "name", written
+// \p{name} in a class. The generated Go parsers check properties with it.
+func HasProperty(name string, r rune) bool {
+	t := UnicodeTable(name)
+	return t != nil && unicode.Is(t, r)
+}
+
+// FoldRune returns the rune a literal matching case-insensitively compares
+// "r" as, which is the smallest of the runes equivalent to it under Unicode
+// simple case folding. The generated Go parsers compare runes with it.
+func FoldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// foldOrbit returns "r" followed by the other runes equivalent
+// to it under Unicode simple case folding.
+func foldOrbit(r rune) []rune {
+	ret := []rune{r}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+// propertyTableName returns the name of the table the generated parsers of
+// languages without Unicode tables of their own keep the Unicode properties
+// of "class" in.
+func propertyTableName(class *CharClass) string {
+	return "unicode_" + strings.Join(class.Properties, "_")
+}
+
+// formatTable returns the ranges "table" as a list of their bounds, for
+// the arrays and tuples the generated parsers keep property tables in.
+func formatTable(table []RuneRange, indent string) string {
+	var buf strings.Builder
+	for i, r := range table {
+		if i%8 == 0 {
+			buf.WriteString("\n" + indent)
+		} else {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "0x%X, 0x%X,", r.Lo, r.Hi)
+	}
+	return buf.String()
+}
+
+// cRune returns "r" as a C or Java character constant, which is written as
+// its code point unless it's printable ASCII.
+func cRune(r rune) string {
+	if r >= ' ' && r <= '~' {
+		return strconv.QuoteRune(r)
+	}
+	return fmt.Sprintf("0x%X", r)
+}
+
+// unicodeUse reports whether "node" uses negated or Unicode classes or
+// case-insensitive literals, which need a UnicodeGenerator, and returns
+// an error for a class with an unknown property.
+func unicodeUse(node *Node) (used bool, err error) {
+	switch node.Name {
+	case "Class":
+		class, err := NewCharClass(node)
+		if err != nil {
+			return false, err
+		}
+		return !class.simple(), nil
+	case "Literal":
+		return LiteralIgnoresCase(node), nil
+	}
+	for _, child := range node.Children {
+		u, err := unicodeUse(child)
+		if err != nil {
+			return false, err
+		}
+		used = used || u
+	}
+	return used, nil
+}
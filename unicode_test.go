@@ -0,0 +1,27 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+// unicodeTests are the grammars with non-ASCII characters every generator's
+// parsers are tested with, along with inputs they accept and reject.
+var unicodeTests = []generatorTest{
+	{"Set", `Word <- [äöüß]+ !.`, []string{"äöü", "ß"}, []string{"a", "äx", "ä\xc3"}},
+	{"Greek", `Greek <- [α-ωΑ-Ω]+ !.`, []string{"αβγΩ", "ω"}, []string{"aβ", "ä", "α1"}},
+	{"Literal", `Greeting <- "grüße" ' ' '→' !.`, []string{"grüße →"}, []string{"grüsse →", "grüße ->", "grüß"}},
+	{"Escape", `Escape <- 'ä' [ö-ü] "ß\U0001F600" !.`, []string{"äöß😀", "äüß😀"}, []string{"aöß😀", "äöß"}},
+	{"Any", `Pair <- . . !.`, []string{"äö", "😀x", "ab"}, []string{"ä", "äöü"}},
+	{"Astral", `Emoji <- [😀-😏]+ "!" !.`, []string{"😀😎!"}, []string{"😐!", "a!"}},
+	{"Negated", `Text <- [^äa]+ !.`, []string{"öüb", "😀"}, []string{"xä", "a", ""}},
+	{"Property", `Ident <- [\p{L}_] [\p{L}\p{Nd}_]* !.`, []string{"größe٣", "Ωmega", "_x1"}, []string{"1x", "a-b", "€"}},
+	{"Fold", `Keyword <- "straße"i ' ' "ÄRGER"i !.`, []string{"STRAßE ärger", "ſtraße Ärger"}, []string{"strasse ärger", "straße arger"}},
+}
+
+func TestUnicodeGo(t *testing.T) {
+	testGo(t, unicodeTests)
+}
+
+func TestUnicodeGenerators(t *testing.T) {
+	testGenerators(t, unicodeTests)
+}
@@ -0,0 +1,183 @@
+// Package walk traverses the trees of Nodes created by the parsers,
+// so that tools don't have to reimplement the recursion over Children.
+package walk
+
+import (
+	. "github.com/jxo/parser"
+	"sort"
+)
+
+type (
+	// Action tells Walk how to continue after a Func has been called.
+	Action int
+
+	// Func is called by Walk with a Cursor at the Node being visited.
+	Func func(c *Cursor) Action
+
+	// Cursor is the position of a Node in the tree being walked. It's
+	// only valid during the call of the Func it was passed to.
+	Cursor struct {
+		node   *Node
+		parent *Cursor
+		index  int
+		depth  int
+	}
+
+	// Visitor calls the Funcs keyed by the Name of the Nodes it visits,
+	// with the ones in Enter called before the Children of the Node
+	// are visited and the ones in Leave after. Nodes without a Func
+	// are walked through as if it returned Continue.
+	Visitor struct {
+		Enter map[string]Func
+		Leave map[string]Func
+	}
+)
+
+const (
+	// Continue with the Children of the Node, or with its next
+	// sibling when returned after the Children have been visited.
+	Continue Action = iota
+	// Skip the Children of the Node. Returning it after the Children
+	// have been visited is the same as returning Continue.
+	SkipChildren
+	// Stop the walk altogether.
+	Stop
+)
+
+// Node returns the Node the Cursor is at.
+func (c *Cursor) Node() *Node {
+	return c.node
+}
+
+// Parent returns the Cursor at the parent of the Node, or nil if
+// the Node is the root of the walk.
+func (c *Cursor) Parent() *Cursor {
+	return c.parent
+}
+
+// Index returns the index of the Node in the Children of its
+// parent, or -1 if the Node is the root of the walk.
+func (c *Cursor) Index() int {
+	return c.index
+}
+
+// Depth returns the number of ancestors the Node has below
+// the root of the walk.
+func (c *Cursor) Depth() int {
+	return c.depth
+}
+
+// Path returns the Nodes from the root of the walk down to
+// and including the Node the Cursor is at.
+func (c *Cursor) Path() []*Node {
+	ret := make([]*Node, c.depth+1)
+	for ; c != nil; c = c.parent {
+		ret[c.depth] = c.node
+	}
+	return ret
+}
+
+// Walk visits the tree rooted at "root" depth first, calling "pre" before
+// the Children of each Node are visited and "post" after, either of which
+// may be nil. It returns false if the walk was stopped.
+func Walk(root *Node, pre, post Func) bool {
+	if root == nil {
+		return true
+	}
+	return walk(&Cursor{node: root, index: -1}, pre, post)
+}
+
+func walk(c *Cursor, pre, post Func) bool {
+	action := Continue
+	if pre != nil {
+		action = pre(c)
+	}
+	switch action {
+	case Stop:
+		return false
+	case Continue:
+		for i, child := range c.node.Children {
+			if !walk(&Cursor{node: child, parent: c, index: i, depth: c.depth + 1}, pre, post) {
+				return false
+			}
+		}
+	}
+	return post == nil || post(c) != Stop
+}
+
+// Inspect visits the tree rooted at "root" in pre-order, calling "f" for
+// each Node and skipping the Children of the Nodes it returns false for.
+func Inspect(root *Node, f func(*Node) bool) {
+	Walk(root, func(c *Cursor) Action {
+		if f(c.node) {
+			return Continue
+		}
+		return SkipChildren
+	}, nil)
+}
+
+// Walk visits the tree rooted at "root" with the Funcs of the Visitor.
+// It returns false if the walk was stopped.
+func (v *Visitor) Walk(root *Node) bool {
+	return Walk(root, dispatch(v.Enter), dispatch(v.Leave))
+}
+
+func dispatch(funcs map[string]Func) Func {
+	if len(funcs) == 0 {
+		return nil
+	}
+	return func(c *Cursor) Action {
+		if f := funcs[c.node.Name]; f != nil {
+			return f(c)
+		}
+		return Continue
+	}
+}
+
+// child returns the child of "n" whose Range contains "offset",
+// relying on the Children being ordered by their Range.
+func child(n *Node, offset int) *Node {
+	i := sort.Search(len(n.Children), func(i int) bool {
+		return n.Children[i].Range.End() > offset
+	})
+	if i < len(n.Children) && n.Children[i].Range.Begin() <= offset {
+		return n.Children[i]
+	}
+	return nil
+}
+
+// PathAt returns the Nodes from "root" down to the innermost Node whose
+// Range contains "offset", with a Node containing the offsets from the
+// beginning of its Range up to but not including the end of it. It returns
+// nil if "root" doesn't contain "offset".
+func PathAt(root *Node, offset int) (ret []*Node) {
+	if root == nil || offset < root.Range.Begin() || offset >= root.Range.End() {
+		return nil
+	}
+	for n := root; n != nil; n = child(n, offset) {
+		ret = append(ret, n)
+	}
+	return
+}
+
+// At returns the innermost Node in the tree rooted at "root" whose Range
+// contains "offset", or nil if there's none. See PathAt.
+func At(root *Node, offset int) *Node {
+	if path := PathAt(root, offset); len(path) > 0 {
+		return path[len(path)-1]
+	}
+	return nil
+}
+
+// Path returns the Nodes from "root" down to and including "n",
+// or nil if "n" isn't in the tree rooted at "root".
+func Path(root, n *Node) (ret []*Node) {
+	Walk(root, func(c *Cursor) Action {
+		if c.node == n {
+			ret = c.Path()
+			return Stop
+		}
+		return Continue
+	}, nil)
+	return
+}
@@ -0,0 +1,123 @@
+package walk
+
+import (
+	"fmt"
+	. "github.com/jxo/parser"
+	"github.com/jxo/parser/peg"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, data string) *Node {
+	var p peg.Peg
+	if !p.Parse(data) {
+		t.Fatalf("Didn't parse correctly: %s", p.Error())
+	}
+	return p.RootNode()
+}
+
+func TestWalk(t *testing.T) {
+	root := parse(t, "A <- B / 'c'\nB <- [b]\n")
+	var order []string
+	Walk(root, func(c *Cursor) Action {
+		order = append(order, fmt.Sprintf("%s%s", strings.Repeat(" ", c.Depth()), c.Node().Name))
+		if c.Node().Name == "Expression" {
+			return SkipChildren
+		}
+		return Continue
+	}, func(c *Cursor) Action {
+		order = append(order, "/"+c.Node().Name)
+		return Continue
+	})
+	if s, want := strings.Join(order, ","), "Peg, Definition,  Identifier,/Identifier,  Expression,/Expression,/Definition, Definition,  Identifier,/Identifier,  Expression,/Expression,/Definition, EndOfFile,/EndOfFile,/Peg"; s != want {
+		t.Errorf("Unexpected order\n%s", s)
+	}
+
+	var n int
+	if Walk(root, nil, func(c *Cursor) Action {
+		if n++; c.Node().Name == "Identifier" {
+			return Stop
+		}
+		return Continue
+	}) {
+		t.Error("Expected the walk to be stopped")
+	} else if n != 1 {
+		t.Errorf("Expected the walk to stop at the first leaf, visited %d Nodes", n)
+	}
+
+	var names []string
+	Inspect(root, func(n *Node) bool {
+		names = append(names, n.Name)
+		return n.Name != "Definition"
+	})
+	if s := fmt.Sprint(names); s != "[Peg Definition Definition EndOfFile]" {
+		t.Errorf("Unexpected Nodes %s", s)
+	}
+}
+
+func TestVisitor(t *testing.T) {
+	root := parse(t, "A <- B 'c' / [d]\nB <- \"b\"\n")
+	var rules, literals []string
+	v := Visitor{
+		Enter: map[string]Func{
+			"Definition": func(c *Cursor) Action {
+				rules = append(rules, c.Node().Children[0].Data())
+				return Continue
+			},
+			"Class": func(c *Cursor) Action {
+				return SkipChildren
+			},
+		},
+		Leave: map[string]Func{
+			"Literal": func(c *Cursor) Action {
+				if p := c.Parent(); p.Node().Name != "Primary" || p.Node().Children[c.Index()] != c.Node() {
+					t.Errorf("Unexpected parent %s", p.Node().Name)
+				}
+				literals = append(literals, c.Node().Data())
+				return Continue
+			},
+			"Range": func(c *Cursor) Action {
+				t.Error("Expected the Children of the Class to be skipped")
+				return Continue
+			},
+		},
+	}
+	if !v.Walk(root) {
+		t.Error("Expected the walk to finish")
+	}
+	if s := fmt.Sprint(rules, literals); s != `[A B] ['c' "b"]` {
+		t.Errorf("Unexpected result %s", s)
+	}
+}
+
+func TestAt(t *testing.T) {
+	data := "A <- B 'c'\nB <- [b]\n"
+	root := parse(t, data)
+	names := func(path []*Node) string {
+		var s []string
+		for _, n := range path {
+			s = append(s, n.Name)
+		}
+		return strings.Join(s, " ")
+	}
+	offset := strings.Index(data, "'c'")
+	path := PathAt(root, offset)
+	if s, want := names(path), "Peg Definition Expression Sequence Prefix Suffix Primary Literal"; s != want {
+		t.Errorf("Expected %s, got %s", want, s)
+	}
+	if n := At(root, offset); n != path[len(path)-1] || n.Data() != "'c'" {
+		t.Errorf("Unexpected Node %v", n)
+	}
+	if s, want := names(Path(root, path[len(path)-1])), names(path); s != want {
+		t.Errorf("Expected %s, got %s", want, s)
+	}
+	if n := At(root, strings.Index(data, "B <-")); n == nil || n.Name != "Identifier" || n.Data() != "B" {
+		t.Errorf("Expected the Identifier B, got %v", n)
+	}
+	if n := At(root, len(data)); n != nil {
+		t.Errorf("Expected no Node past the end, got %v", n)
+	}
+	if p := Path(root, &Node{}); p != nil {
+		t.Errorf("Expected no path to a Node outside the tree, got %v", p)
+	}
+}